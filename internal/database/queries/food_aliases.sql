@@ -0,0 +1,7 @@
+-- name: GetFoodAliases :many
+SELECT alias, food_id FROM food_aliases;
+
+-- name: UpsertFoodAlias :exec
+INSERT INTO food_aliases (food_id, alias)
+VALUES ($1, $2)
+ON CONFLICT (alias) DO UPDATE SET food_id = EXCLUDED.food_id;
@@ -0,0 +1,29 @@
+-- name: GetFoodLabelsForFoods :many
+SELECT food_id, label FROM food_labels
+WHERE food_id = ANY(@food_ids::int[])
+ORDER BY food_id, label;
+
+-- Every recipe and ingredient link under the given foods, as deep as the other recursive food queries go
+-- name: GetRecipeDependenciesForFoods :many
+WITH RECURSIVE dependencies AS (
+    SELECT ri.recipe_id, ri.ingredient_id, 1 as depth, ARRAY[ri.recipe_id, ri.ingredient_id] as path
+    FROM recipe_ingredients ri
+    WHERE ri.recipe_id = ANY(@food_ids::int[])
+
+    UNION ALL
+
+    SELECT ri.recipe_id, ri.ingredient_id, d.depth + 1, d.path || ri.ingredient_id
+    FROM dependencies d
+    JOIN recipe_ingredients ri ON ri.recipe_id = d.ingredient_id
+    WHERE NOT ri.ingredient_id = ANY(d.path)  -- Prevent cycles
+      AND d.depth < 15                        -- Max depth limit
+)
+SELECT DISTINCT recipe_id, ingredient_id FROM dependencies;
+
+-- name: DeleteFoodLabels :exec
+DELETE FROM food_labels WHERE food_id = $1;
+
+-- name: AddFoodLabel :exec
+INSERT INTO food_labels (food_id, label)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
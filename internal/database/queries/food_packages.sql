@@ -0,0 +1,11 @@
+-- name: GetFoodPackagesForFoods :many
+SELECT id, food_id, size, unit, price FROM food_packages
+WHERE food_id = ANY(@food_ids::int[])
+ORDER BY food_id, size;
+
+-- name: DeleteFoodPackages :exec
+DELETE FROM food_packages WHERE food_id = $1;
+
+-- name: AddFoodPackage :exec
+INSERT INTO food_packages (food_id, size, unit, price)
+VALUES ($1, $2, $3, $4);
@@ -0,0 +1,27 @@
+-- name: GetFoodUnitsForFoods :many
+SELECT id, food_id, name, quantity, unit, is_purchase_unit FROM food_units
+WHERE food_id = ANY(@food_ids::int[])
+ORDER BY food_id, name;
+
+-- name: DeleteFoodUnits :exec
+DELETE FROM food_units WHERE food_id = $1;
+
+-- name: AddFoodUnit :exec
+INSERT INTO food_units (food_id, name, quantity, unit, is_purchase_unit)
+VALUES ($1, $2, $3, $4, $5);
+
+-- Which of the given custom units of a food something still measures it in. Bought shopping list items are
+-- history and don't count.
+-- name: GetFoodUnitsInUse :many
+SELECT DISTINCT uses.unit FROM (
+    SELECT ri.unit FROM recipe_ingredients ri WHERE ri.ingredient_id = @food_id::int
+    UNION ALL
+    SELECT s.unit FROM substitutions s WHERE s.food_id = @food_id::int
+    UNION ALL
+    SELECT si.unit FROM substitution_items si WHERE si.food_id = @food_id::int
+    UNION ALL
+    SELECT sli.unit FROM shopping_list_items sli
+    WHERE sli.food_id = @food_id::int AND NOT COALESCE(sli.purchased, FALSE)
+) uses
+WHERE uses.unit = ANY(@units::text[])
+ORDER BY uses.unit;
@@ -1,7 +1,7 @@
 
 -- name: CreateFood :one
-INSERT INTO foods (name, unit_type, base_unit, density, is_recipe)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO foods (name, unit_type, base_unit, density, is_recipe, is_staple, category)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetFood :one
@@ -18,26 +18,50 @@ ORDER BY name
 LIMIT $2 OFFSET $3;
 
 -- name: SearchFoodsAutocomplete :many
-SELECT id, name, unit_type, base_unit, is_recipe, density FROM foods
-WHERE name ILIKE $1 || '%'
-ORDER BY 
-    CASE WHEN name ILIKE $1 || '%' THEN 1 ELSE 2 END,
+SELECT * FROM food_stats
+WHERE name ILIKE @prefix::text || '%'
+    AND (NOT @favourites_only::boolean OR is_favourite)
+    AND (NOT @recipes_only::boolean OR is_recipe)
+    AND (@min_rating::numeric <= 0 OR average_rating >= @min_rating)
+    AND (@not_cooked_days::int <= 0 OR last_cooked_at IS NULL OR last_cooked_at < NOW() - @not_cooked_days * INTERVAL '1 day')
+ORDER BY
+    CASE WHEN @sort::text = 'rating' THEN average_rating END DESC NULLS LAST,
+    CASE WHEN @sort::text = 'times_cooked' THEN times_cooked END DESC,
+    CASE WHEN @sort::text = 'last_cooked' THEN last_cooked_at END DESC NULLS LAST,
+    CASE WHEN @sort::text = 'neglected' THEN last_cooked_at END ASC NULLS FIRST,
     LENGTH(name),
     name
-LIMIT $2;
+LIMIT @max_results::int;
 
 -- name: GetRecentFoods :many  
-SELECT id, name, unit_type, base_unit, is_recipe, density FROM foods
+SELECT id, name, unit_type, base_unit, is_recipe, density, is_staple FROM foods
 ORDER BY updated_at DESC
 LIMIT $1;
 -- name: CountSearchFoods :one
-SELECT COUNT(*) FROM foods
-WHERE 
-    CASE 
-        WHEN COALESCE(TRIM($1), '') = '' THEN TRUE
-        ELSE (name ILIKE '%' || $1 || '%') OR (CAST(id AS TEXT) LIKE '%' || $1 || '%')
-    END;
-    
+SELECT COUNT(*) FROM food_stats
+WHERE
+    (COALESCE(TRIM(@search::text), '') = '' OR name ILIKE '%' || @search || '%' OR CAST(id AS TEXT) LIKE '%' || @search || '%')
+    AND (NOT @favourites_only::boolean OR is_favourite)
+    AND (NOT @recipes_only::boolean OR is_recipe)
+    AND (@min_rating::numeric <= 0 OR average_rating >= @min_rating)
+    AND (@not_cooked_days::int <= 0 OR last_cooked_at IS NULL OR last_cooked_at < NOW() - @not_cooked_days * INTERVAL '1 day');
+
+-- name: SearchFoodsWithStats :many
+SELECT * FROM food_stats
+WHERE
+    (COALESCE(TRIM(@search::text), '') = '' OR name ILIKE '%' || @search || '%' OR CAST(id AS TEXT) LIKE '%' || @search || '%')
+    AND (NOT @favourites_only::boolean OR is_favourite)
+    AND (NOT @recipes_only::boolean OR is_recipe)
+    AND (@min_rating::numeric <= 0 OR average_rating >= @min_rating)
+    AND (@not_cooked_days::int <= 0 OR last_cooked_at IS NULL OR last_cooked_at < NOW() - @not_cooked_days * INTERVAL '1 day')
+ORDER BY
+    CASE WHEN @sort::text = 'rating' THEN average_rating END DESC NULLS LAST,
+    CASE WHEN @sort::text = 'times_cooked' THEN times_cooked END DESC,
+    CASE WHEN @sort::text = 'last_cooked' THEN last_cooked_at END DESC NULLS LAST,
+    CASE WHEN @sort::text = 'neglected' THEN last_cooked_at END ASC NULLS FIRST,
+    name
+LIMIT @page_size::int OFFSET @page_offset::int;
+
 -- name: SearchFoodsWithDependencies :many
 WITH RECURSIVE recipe_tree AS (
     -- Base case: foods matching id or name
@@ -51,7 +75,8 @@ WITH RECURSIVE recipe_tree AS (
         0 as depth,
         ARRAY[f.id] as path,
         CAST(NULL AS NUMERIC) as quantity,
-        CAST(NULL AS TEXT) as unit
+        CAST(NULL AS TEXT) as unit,
+        CAST(NULL AS BOOLEAN) as is_main
     FROM foods f
     WHERE 
         CASE 
@@ -73,7 +98,8 @@ WITH RECURSIVE recipe_tree AS (
         rt.depth + 1,
         rt.path || f.id,
         ri.quantity,
-        ri.unit
+        ri.unit,
+        ri.is_main
     FROM recipe_tree rt
     JOIN recipe_ingredients ri ON rt.id = ri.recipe_id
     JOIN foods f ON ri.ingredient_id = f.id
@@ -88,12 +114,20 @@ SELECT DISTINCT ON (rt.depth, f.id)
     f.base_unit,
     f.density,
     f.is_recipe,
+    f.is_staple,
+    f.is_favourite,
+    f.category,
     rt.depth,
     rt.quantity,
     rt.unit,
+    rt.is_main,
     r.instructions,
     r.url,
-    r.yield_quantity
+    r.yield_quantity,
+    r.prep_minutes,
+    r.cost_per_serving,
+    CAST(COALESCE((SELECT array_agg(t.tag ORDER BY t.tag) FROM recipe_tags t WHERE t.recipe_id = f.id), '{}') AS TEXT[]) as tags,
+    CAST(COALESCE((SELECT array_agg(l.label ORDER BY l.label) FROM food_labels l WHERE l.food_id = f.id), '{}') AS TEXT[]) as labels
 FROM recipe_tree rt
 JOIN foods f ON rt.id = f.id
 LEFT JOIN recipes r ON f.id = r.food_id
@@ -114,6 +148,8 @@ WITH updated_food AS (
         base_unit = $4,
         density = $5,
         is_recipe = $6,
+        is_staple = $10,
+        category = $13,
         updated_at = NOW()
     WHERE id = $1
     RETURNING *
@@ -129,6 +165,8 @@ updated_recipe AS (
         instructions = $7,
         url = $8,
         yield_quantity = $9,
+        prep_minutes = $11,
+        cost_per_serving = $12,
         updated_at = NOW()
     WHERE food_id = $1
     RETURNING *
@@ -167,12 +205,48 @@ WHERE r.food_id = $1
 GROUP BY r.food_id;
 
 -- name: CreateRecipe :one
-INSERT INTO recipes (food_id, instructions, url, yield_quantity)
-VALUES ($1, $2, $3, $4)
+INSERT INTO recipes (food_id, instructions, url, yield_quantity, prep_minutes, cost_per_serving)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: AddRecipeIngredient :exec
-INSERT INTO recipe_ingredients (recipe_id, ingredient_id, quantity, unit)
-VALUES ($1, $2, $3, $4);
+INSERT INTO recipe_ingredients (recipe_id, ingredient_id, quantity, unit, is_main)
+VALUES ($1, $2, $3, $4, $5);
 
+-- name: SetFoodFavourite :exec
+UPDATE foods
+SET is_favourite = @is_favourite::boolean, updated_at = NOW()
+WHERE id = @id::int;
+
+-- name: GetFoodHistory :one
+SELECT average_rating, last_cooked_at, times_cooked FROM food_stats
+WHERE id = @food_id::int;
+
+-- name: DeleteRecipeTags :exec
+DELETE FROM recipe_tags WHERE recipe_id = $1;
+
+-- name: AddRecipeTag :exec
+INSERT INTO recipe_tags (recipe_id, tag)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
 
+-- name: GetRecipeTags :many
+SELECT DISTINCT tag FROM recipe_tags ORDER BY tag;
+
+-- name: GetPlannerCandidates :many
+SELECT
+    f.id,
+    f.name,
+    r.prep_minutes,
+    r.cost_per_serving,
+    CAST(COALESCE((SELECT array_agg(t.tag ORDER BY t.tag) FROM recipe_tags t WHERE t.recipe_id = f.id), '{}') AS TEXT[]) as tags,
+    CAST((SELECT COUNT(*) FROM recipe_ingredients ri WHERE ri.recipe_id = f.id) AS INTEGER) as ingredient_count,
+    CAST((
+        SELECT COUNT(*) FROM recipe_ingredients ri
+        JOIN pantry_stock ps ON ps.food_id = ri.ingredient_id
+        WHERE ri.recipe_id = f.id AND ps.quantity > 0
+    ) AS INTEGER) as pantry_ingredient_count
+FROM foods f
+JOIN recipes r ON r.food_id = f.id
+WHERE f.is_recipe = true
+ORDER BY f.id;
@@ -0,0 +1,17 @@
+-- name: GetHouseholdMembers :many
+SELECT * FROM household_members
+ORDER BY name;
+
+-- name: CreateHouseholdMember :one
+INSERT INTO household_members (name, portion_factor, is_guest, diet, allergens)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: UpdateHouseholdMember :one
+UPDATE household_members
+SET name = $2, portion_factor = $3, is_guest = $4, diet = $5, allergens = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteHouseholdMember :exec
+DELETE FROM household_members WHERE id = $1;
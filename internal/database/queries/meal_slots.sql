@@ -0,0 +1,20 @@
+-- name: GetMealSlots :many
+SELECT * FROM meal_slots
+ORDER BY sort_order, default_time, name;
+
+-- name: GetMealSlotById :one
+SELECT * FROM meal_slots WHERE id = $1;
+
+-- name: CreateMealSlot :one
+INSERT INTO meal_slots (name, default_time, sort_order)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateMealSlot :one
+UPDATE meal_slots
+SET name = $2, default_time = $3, sort_order = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteMealSlot :exec
+DELETE FROM meal_slots WHERE id = $1;
@@ -0,0 +1,48 @@
+-- A staple the pantry doesn't track yet counts as empty with a par level of one base unit, so it still gets
+-- topped up, the food's package sizes round that up to something that can be bought
+-- name: GetStaplesWithStock :many
+SELECT
+    f.id,
+    f.name,
+    f.unit_type,
+    f.base_unit,
+    CAST(COALESCE(ps.quantity, 0) AS NUMERIC) as quantity,
+    CAST(COALESCE(ps.par_level, 1) AS NUMERIC) as par_level
+FROM foods f
+LEFT JOIN pantry_stock ps ON ps.food_id = f.id
+WHERE f.is_staple = true
+ORDER BY f.name;
+
+-- Untracked staples count the same way as in GetStaplesWithStock
+-- name: GetStaplesBelowPar :many
+SELECT
+    f.id,
+    f.name,
+    f.unit_type,
+    f.base_unit,
+    CAST(COALESCE(ps.quantity, 0) AS NUMERIC) as quantity,
+    CAST(COALESCE(ps.par_level, 1) AS NUMERIC) as par_level,
+    CAST(COALESCE(ps.par_level, 1) - COALESCE(ps.quantity, 0) AS NUMERIC) as shortfall
+FROM foods f
+LEFT JOIN pantry_stock ps ON ps.food_id = f.id
+WHERE f.is_staple = true
+  AND COALESCE(ps.quantity, 0) < COALESCE(ps.par_level, 1)
+ORDER BY f.name;
+
+-- name: UpsertPantryStock :one
+INSERT INTO pantry_stock (food_id, quantity, par_level)
+VALUES (@food_id::int, @quantity::numeric, @par_level::numeric)
+ON CONFLICT (food_id) DO UPDATE
+SET quantity = EXCLUDED.quantity, par_level = EXCLUDED.par_level, updated_at = NOW()
+RETURNING *;
+
+-- name: SetFoodStaple :exec
+UPDATE foods
+SET is_staple = @is_staple::boolean, updated_at = NOW()
+WHERE id = @id::int;
+
+-- Only changes foods the pantry already tracks, stock never drops below zero
+-- name: AddPantryStock :exec
+UPDATE pantry_stock
+SET quantity = GREATEST(quantity + @quantity::numeric, 0), updated_at = NOW()
+WHERE food_id = @food_id::int;
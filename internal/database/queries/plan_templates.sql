@@ -0,0 +1,32 @@
+-- name: GetPlanTemplates :many
+SELECT pt.*, COUNT(pte.id)::int as entry_count
+FROM plan_templates pt
+LEFT JOIN plan_template_entries pte ON pte.template_id = pt.id
+GROUP BY pt.id
+ORDER BY pt.name;
+
+-- name: GetPlanTemplateById :one
+SELECT * FROM plan_templates WHERE id = $1;
+
+-- name: PlanTemplateNameExists :one
+SELECT EXISTS (SELECT 1 FROM plan_templates WHERE name = @name::text);
+
+-- name: GetPlanTemplateEntries :many
+SELECT pte.*, f.name as food_name, ms.name as meal_slot_name
+FROM plan_template_entries pte
+JOIN foods f ON f.id = pte.food_id
+LEFT JOIN meal_slots ms ON ms.id = pte.meal_slot_id
+WHERE pte.template_id = $1
+ORDER BY pte.day_offset, pte.time_of_day;
+
+-- name: CreatePlanTemplate :one
+INSERT INTO plan_templates (name, days)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: CreatePlanTemplateEntry :exec
+INSERT INTO plan_template_entries (template_id, food_id, day_offset, time_of_day, servings, meal_slot_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: DeletePlanTemplate :exec
+DELETE FROM plan_templates WHERE id = $1;
@@ -0,0 +1,21 @@
+-- name: GetRecipeRatings :many
+SELECT
+    rr.member_id,
+    hm.name as member_name,
+    rr.rating,
+    rr.comment,
+    rr.updated_at
+FROM recipe_ratings rr
+JOIN household_members hm ON hm.id = rr.member_id
+WHERE rr.recipe_id = $1
+ORDER BY hm.name;
+
+-- name: UpsertRecipeRating :exec
+INSERT INTO recipe_ratings (recipe_id, member_id, rating, comment)
+VALUES (@recipe_id::int, @member_id::int, @rating::int, @comment::text)
+ON CONFLICT (recipe_id, member_id) DO UPDATE
+SET rating = EXCLUDED.rating, comment = EXCLUDED.comment, updated_at = NOW();
+
+-- name: DeleteRecipeRating :exec
+DELETE FROM recipe_ratings
+WHERE recipe_id = @recipe_id::int AND member_id = @member_id::int;
@@ -0,0 +1,44 @@
+-- Priced purchases in a date range, with where they were bought and the food's category. Items that aren't foods
+-- count too, under their own category.
+-- name: GetPricedPurchases :many
+SELECT
+    sli.id,
+    sli.food_id,
+    sli.food_name,
+    sli.actual_price,
+    CAST(sli.purchased_at AS TIMESTAMPTZ) AS purchased_at,
+    CAST(COALESCE(f.category, sli.category) AS TEXT) AS category,
+    CAST(COALESCE(s.name, '') AS TEXT) AS store_name
+FROM shopping_list_items sli
+LEFT JOIN foods f ON f.id = sli.food_id
+LEFT JOIN stores s ON s.id = sli.store_id
+WHERE sli.purchased
+    AND sli.actual_price IS NOT NULL
+    AND sli.purchased_at >= @from_time::timestamptz
+    AND sli.purchased_at < @to_time::timestamptz
+ORDER BY sli.purchased_at;
+
+-- Every priced purchase of a food, oldest first. The quantity is what was actually bought, or else what the
+-- list needed plus whatever was left over
+-- name: GetFoodPriceHistory :many
+SELECT
+    CAST(sli.purchased_at AS TIMESTAMPTZ) AS purchased_at,
+    sli.actual_price,
+    sli.unit,
+    CAST(COALESCE(
+        sli.actual_quantity,
+        (
+            SELECT SUM(slis.contributed_quantity)
+            FROM shopping_list_item_sources slis
+            WHERE slis.shopping_list_item_id = sli.id
+        ) + sli.surplus_quantity,
+        0
+    ) AS NUMERIC) AS quantity,
+    CAST(COALESCE(s.name, '') AS TEXT) AS store_name
+FROM shopping_list_items sli
+LEFT JOIN stores s ON s.id = sli.store_id
+WHERE sli.food_id = $1
+    AND sli.purchased
+    AND sli.actual_price IS NOT NULL
+    AND sli.purchased_at IS NOT NULL
+ORDER BY sli.purchased_at;
@@ -1,36 +1,62 @@
 -- name: CreateSchedule :one
 WITH inserted_schedule AS (
-  INSERT INTO schedules (food_id, scheduled_at, servings)
-  VALUES ($1, $2, $3)
+  INSERT INTO schedules (food_id, scheduled_at, servings, meal_slot_id)
+  VALUES ($1, $2, $3, $4)
   RETURNING *
 )
-SELECT s.*, f.name as food_name
+SELECT s.*, f.name as food_name, ms.name as meal_slot_name
 FROM inserted_schedule s
-JOIN foods f ON f.id = s.food_id;
+JOIN foods f ON f.id = s.food_id
+LEFT JOIN meal_slots ms ON ms.id = s.meal_slot_id;
 
 -- name: GetSchedulesInRange :many
-SELECT s.*, f.name as food_name
+SELECT s.*, f.name as food_name, ms.name as meal_slot_name
 FROM schedules s
 JOIN foods f ON s.food_id = f.id
+LEFT JOIN meal_slots ms ON ms.id = s.meal_slot_id
 WHERE scheduled_at BETWEEN $1 AND $2
 ORDER BY scheduled_at;
 
+-- name: GetSchedulesInRangeForSlots :many
+SELECT s.*, f.name as food_name, ms.name as meal_slot_name
+FROM schedules s
+JOIN foods f ON s.food_id = f.id
+LEFT JOIN meal_slots ms ON ms.id = s.meal_slot_id
+WHERE s.scheduled_at BETWEEN @range_start::timestamptz AND @range_end::timestamptz
+  AND s.meal_slot_id = ANY(@meal_slot_ids::int[])
+ORDER BY s.scheduled_at;
+
 -- name: GetScheduleById :one
-SELECT s.*, f.name as food_name
+SELECT s.*, f.name as food_name, ms.name as meal_slot_name
 FROM schedules s
 JOIN foods f ON s.food_id = f.id
+LEFT JOIN meal_slots ms ON ms.id = s.meal_slot_id
 WHERE s.id = $1;
 
+-- name: GetSchedulesByIds :many
+SELECT s.*, f.name as food_name, ms.name as meal_slot_name
+FROM schedules s
+JOIN foods f ON s.food_id = f.id
+LEFT JOIN meal_slots ms ON ms.id = s.meal_slot_id
+WHERE s.id = ANY($1::int[])
+ORDER BY s.scheduled_at;
+
+-- name: UpdateScheduleTime :exec
+UPDATE schedules
+SET scheduled_at = $2, updated_at = NOW()
+WHERE id = $1;
+
 -- name: UpdateSchedule :one
 WITH updated_schedule AS (
   UPDATE schedules 
-  SET food_id = $2, servings = $3, scheduled_at = $4, updated_at = NOW()
+  SET food_id = $2, servings = $3, scheduled_at = $4, meal_slot_id = $5, updated_at = NOW()
   WHERE schedules.id = $1
   RETURNING *
 )
-SELECT s.id, s.food_id, s.servings, s.scheduled_at, s.created_at, s.updated_at, f.name as food_name
+SELECT s.id, s.food_id, s.servings, s.scheduled_at, s.meal_slot_id, s.created_at, s.updated_at, s.servings_manual, f.name as food_name, ms.name as meal_slot_name
 FROM updated_schedule s
-JOIN foods f ON f.id = s.food_id;
+JOIN foods f ON f.id = s.food_id
+LEFT JOIN meal_slots ms ON ms.id = s.meal_slot_id;
 
 -- name: DeleteScheduleByIds :exec
 DELETE FROM schedules
@@ -40,4 +66,42 @@ RETURNING id;
 -- name: DeleteScheduleByDateRange :exec
 DELETE FROM schedules
 WHERE scheduled_at >= $1 AND scheduled_at <= $2
-RETURNING id;
\ No newline at end of file
+RETURNING id;
+
+-- Meals around a date with what the variety warnings compare: tags, main ingredients and prep time
+-- name: GetScheduleVarietyProfiles :many
+SELECT
+    s.id,
+    s.food_id,
+    s.scheduled_at,
+    f.name as food_name,
+    CAST(COALESCE(r.prep_minutes, 0) AS INTEGER) as prep_minutes,
+    CAST(COALESCE((SELECT array_agg(t.tag ORDER BY t.tag) FROM recipe_tags t WHERE t.recipe_id = s.food_id), '{}') AS TEXT[]) as tags,
+    CAST(COALESCE((
+        SELECT array_agg(i.name ORDER BY i.name)
+        FROM recipe_ingredients ri
+        JOIN foods i ON i.id = ri.ingredient_id
+        WHERE ri.recipe_id = s.food_id AND ri.is_main
+    ), '{}') AS TEXT[]) as main_ingredients
+FROM schedules s
+JOIN foods f ON f.id = s.food_id
+LEFT JOIN recipes r ON r.food_id = s.food_id
+WHERE s.scheduled_at BETWEEN @range_start::timestamptz AND @range_end::timestamptz
+  AND s.id <> @exclude_id::int
+ORDER BY s.scheduled_at;
+
+-- name: GetFoodVarietyProfile :one
+SELECT
+    f.id,
+    f.name,
+    CAST(COALESCE(r.prep_minutes, 0) AS INTEGER) as prep_minutes,
+    CAST(COALESCE((SELECT array_agg(t.tag ORDER BY t.tag) FROM recipe_tags t WHERE t.recipe_id = f.id), '{}') AS TEXT[]) as tags,
+    CAST(COALESCE((
+        SELECT array_agg(i.name ORDER BY i.name)
+        FROM recipe_ingredients ri
+        JOIN foods i ON i.id = ri.ingredient_id
+        WHERE ri.recipe_id = f.id AND ri.is_main
+    ), '{}') AS TEXT[]) as main_ingredients
+FROM foods f
+LEFT JOIN recipes r ON r.food_id = f.id
+WHERE f.id = $1;
@@ -0,0 +1,115 @@
+-- name: GetScheduleAttendeeIds :many
+SELECT member_id FROM schedule_attendees
+WHERE schedule_id = $1
+ORDER BY member_id;
+
+-- name: AddScheduleAttendee :exec
+INSERT INTO schedule_attendees (schedule_id, member_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteScheduleAttendees :exec
+DELETE FROM schedule_attendees WHERE schedule_id = $1;
+
+-- name: CopyScheduleAttendees :exec
+INSERT INTO schedule_attendees (schedule_id, member_id)
+SELECT @to_schedule_id::int, member_id
+FROM schedule_attendees
+WHERE schedule_id = @from_schedule_id::int;
+
+-- name: SetScheduleServingsManual :exec
+UPDATE schedules
+SET servings_manual = @servings_manual::boolean, updated_at = NOW()
+WHERE id = @id::int;
+
+-- Meals left without attendees keep their last servings, attendees away at the time of the meal don't count
+-- name: RecalculateScheduleServings :exec
+UPDATE schedules s
+SET servings = totals.servings, updated_at = NOW()
+FROM (
+    SELECT sa.schedule_id, SUM(m.portion_factor) as servings
+    FROM schedule_attendees sa
+    JOIN household_members m ON m.id = sa.member_id
+    JOIN schedules meal ON meal.id = sa.schedule_id
+    WHERE sa.schedule_id = ANY(@schedule_ids::int[])
+      AND NOT EXISTS (
+        SELECT 1 FROM member_away ma
+        WHERE ma.member_id = sa.member_id
+          AND ma.start_at <= meal.scheduled_at
+          AND ma.end_at > meal.scheduled_at
+      )
+    GROUP BY sa.schedule_id
+) totals
+WHERE s.id = totals.schedule_id AND NOT s.servings_manual;
+
+-- name: GetUpcomingScheduleIdsForMember :many
+SELECT sa.schedule_id
+FROM schedule_attendees sa
+JOIN schedules s ON s.id = sa.schedule_id
+WHERE sa.member_id = $1 AND s.scheduled_at >= NOW();
+
+-- name: RemoveMemberFromSchedulesInRange :many
+DELETE FROM schedule_attendees sa
+USING schedules s
+WHERE s.id = sa.schedule_id
+  AND sa.member_id = @member_id::int
+  AND s.scheduled_at >= @range_start::timestamptz
+  AND s.scheduled_at < @range_end::timestamptz
+RETURNING sa.schedule_id;
+
+-- name: CreateMemberAway :one
+INSERT INTO member_away (member_id, start_at, end_at)
+VALUES (@member_id::int, @start_at::timestamptz, @end_at::timestamptz)
+RETURNING id;
+
+-- name: AddMemberAwaySchedules :exec
+INSERT INTO member_away_schedules (member_away_id, schedule_id)
+SELECT @member_away_id::int, unnest(@schedule_ids::int[])
+ON CONFLICT DO NOTHING;
+
+-- Away periods that haven't ended yet
+-- name: GetMemberAwayPeriods :many
+SELECT * FROM member_away
+WHERE end_at > NOW()
+ORDER BY member_id, start_at;
+
+-- Meals another away period of the member also covers stay off, that period takes them over
+-- name: HandOverMemberAwaySchedules :exec
+INSERT INTO member_away_schedules (member_away_id, schedule_id)
+SELECT other.id, mas.schedule_id
+FROM member_away_schedules mas
+JOIN member_away ma ON ma.id = mas.member_away_id
+JOIN schedules s ON s.id = mas.schedule_id
+JOIN member_away other ON other.member_id = ma.member_id
+    AND other.id <> ma.id
+    AND other.start_at <= s.scheduled_at
+    AND other.end_at > s.scheduled_at
+WHERE ma.id = @id::int
+ON CONFLICT DO NOTHING;
+
+-- Puts the member back on the meals the away period took them off, except ones another period covers
+-- name: RestoreMemberAwayAttendees :many
+INSERT INTO schedule_attendees (schedule_id, member_id)
+SELECT mas.schedule_id, ma.member_id
+FROM member_away_schedules mas
+JOIN member_away ma ON ma.id = mas.member_away_id
+JOIN schedules s ON s.id = mas.schedule_id
+WHERE ma.id = @id::int
+  AND NOT EXISTS (
+      SELECT 1 FROM member_away other
+      WHERE other.member_id = ma.member_id
+        AND other.id <> ma.id
+        AND other.start_at <= s.scheduled_at
+        AND other.end_at > s.scheduled_at
+  )
+ON CONFLICT DO NOTHING
+RETURNING schedule_id;
+
+-- name: DeleteMemberAway :execrows
+DELETE FROM member_away
+WHERE id = @id::int AND member_id = @member_id::int;
+
+-- name: GetAwayMemberIds :many
+SELECT DISTINCT member_id FROM member_away
+WHERE start_at <= @at::timestamptz AND end_at > @at::timestamptz
+ORDER BY member_id;
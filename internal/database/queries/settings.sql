@@ -0,0 +1,31 @@
+-- name: GetHouseholdSettings :one
+SELECT * FROM household_settings WHERE id = 1;
+
+-- name: UpdateWeekStart :one
+UPDATE household_settings
+SET week_start = @week_start::int, updated_at = NOW()
+WHERE id = 1
+RETURNING *;
+
+-- name: UpdateCalendarFeedToken :one
+UPDATE household_settings
+SET calendar_feed_token = $1, updated_at = NOW()
+WHERE id = 1
+RETURNING *;
+
+-- name: UpdateVarietySettings :one
+UPDATE household_settings
+SET
+    variety_window_days = @variety_window_days::int,
+    variety_tags = @variety_tags::text[],
+    max_meals_per_day = @max_meals_per_day::int,
+    max_daily_prep_minutes = @max_daily_prep_minutes::int,
+    updated_at = NOW()
+WHERE id = 1
+RETURNING *;
+
+-- name: UpdateBudget :one
+UPDATE household_settings
+SET weekly_budget = $1, monthly_budget = $2, updated_at = NOW()
+WHERE id = 1
+RETURNING *;
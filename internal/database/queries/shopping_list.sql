@@ -6,6 +6,7 @@ RETURNING *;
 
 -- name: GetShoppingLists :many
 SELECT * FROM shopping_lists
+WHERE (archived_at IS NOT NULL) = @archived::bool
 ORDER BY updated_at DESC;
 
 -- name: GetShoppingListById :one
@@ -21,6 +22,14 @@ RETURNING *;
 -- name: DeleteShoppingList :exec
 DELETE FROM shopping_lists WHERE id = $1;
 
+-- Restoring a list counts as touching it, so the cleanup doesn't archive it straight back
+-- name: SetShoppingListArchived :exec
+UPDATE shopping_lists
+SET
+    archived_at = CASE WHEN @archived::bool THEN COALESCE(archived_at, NOW()) END,
+    updated_at = CASE WHEN @archived::bool THEN updated_at ELSE NOW() END
+WHERE id = @id;
+
 -- Shopping List Item Operations
 -- name: CreateShoppingListItem :one
 INSERT INTO shopping_list_items (
@@ -51,6 +60,24 @@ WHERE shopping_list_id = $1
   AND unit = $3
 LIMIT 1;
 
+-- An item that isn't a food, with its own category
+-- name: CreateFreeTextShoppingListItem :one
+INSERT INTO shopping_list_items (
+    shopping_list_id, food_name, unit, unit_type, category, notes
+)
+VALUES ($1, $2, $3, '', $4, $5)
+RETURNING *;
+
+-- Lines already bought are left alone, more of the same goes on a line of its own
+-- name: FindFreeTextShoppingListItem :one
+SELECT * FROM shopping_list_items
+WHERE shopping_list_id = @shopping_list_id
+  AND food_id IS NULL
+  AND LOWER(food_name) = LOWER(@name::text)
+  AND unit = @unit
+  AND NOT COALESCE(purchased, FALSE)
+LIMIT 1;
+
 -- name: UpdateShoppingListItemNotes :exec
 UPDATE shopping_list_items 
 SET notes = $2, updated_at = NOW()
@@ -58,32 +85,53 @@ WHERE id = $1;
 
 -- name: MarkShoppingListItemPurchased :exec
 UPDATE shopping_list_items 
-SET purchased = $2, actual_quantity = $3, actual_price = $4, updated_at = NOW()
+SET
+    purchased = $2,
+    actual_quantity = $3,
+    actual_price = $4,
+    surplus_quantity = $5,
+    store_id = $6,
+    purchased_at = CASE WHEN $2 IS TRUE THEN COALESCE(purchased_at, NOW()) END,
+    updated_at = NOW()
 WHERE id = $1;
 
+-- name: GetShoppingListItemForPurchase :one
+SELECT
+    sli.id,
+    sli.shopping_list_id,
+    sli.food_id,
+    sli.unit,
+    sli.surplus_quantity,
+    CAST(COALESCE(SUM(slis.contributed_quantity), 0) AS NUMERIC) as required_quantity
+FROM shopping_list_items sli
+LEFT JOIN shopping_list_item_sources slis ON sli.id = slis.shopping_list_item_id
+WHERE sli.id = $1
+GROUP BY sli.id;
+
 -- name: DeleteShoppingListItem :exec
 DELETE FROM shopping_list_items WHERE id = $1;
 
--- name: DeleteOrphanedShoppingListItems :exec
-DELETE FROM shopping_list_items 
-WHERE id NOT IN (
-    SELECT DISTINCT shopping_list_item_id 
-    FROM shopping_list_item_sources
-);
-
 -- Shopping List Source Operations
 -- name: CreateShoppingListSource :one
 INSERT INTO shopping_list_sources (
-    shopping_list_id, source_type, source_id, source_name, servings
+    shopping_list_id, source_type, source_id, source_name, servings, include_staples
 )
-VALUES ($1, $2, $3, $4, $5)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
+-- name: UpdateShoppingListSource :exec
+UPDATE shopping_list_sources
+SET source_name = $2, servings = $3
+WHERE id = $1;
+
 -- name: GetShoppingListSources :many
 SELECT * FROM shopping_list_sources
 WHERE shopping_list_id = $1
 ORDER BY added_at DESC;
 
+-- name: GetShoppingListSource :one
+SELECT * FROM shopping_list_sources WHERE id = $1;
+
 -- name: DeleteShoppingListSource :exec
 DELETE FROM shopping_list_sources WHERE id = $1;
 
@@ -110,6 +158,58 @@ WHERE shopping_list_item_id = $1;
 DELETE FROM shopping_list_item_sources 
 WHERE shopping_list_source_id = $1;
 
+-- What a source added to items already bought stays, it's been paid for
+-- name: DeleteUnpurchasedShoppingListItemSourcesBySource :exec
+DELETE FROM shopping_list_item_sources slis
+USING shopping_list_items sli
+WHERE sli.id = slis.shopping_list_item_id
+  AND slis.shopping_list_source_id = $1
+  AND NOT COALESCE(sli.purchased, FALSE);
+
+-- name: SetShoppingListItemSourceQuantity :execrows
+UPDATE shopping_list_item_sources
+SET contributed_quantity = $3
+WHERE shopping_list_item_id = $1 AND shopping_list_source_id = $2;
+
+-- name: DeleteShoppingListItemSource :execrows
+DELETE FROM shopping_list_item_sources
+WHERE shopping_list_item_id = $1 AND shopping_list_source_id = $2;
+
+-- name: SetShoppingListSourceOverride :exec
+INSERT INTO shopping_list_source_overrides (shopping_list_source_id, food_id, unit, quantity)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (shopping_list_source_id, food_id, unit) DO UPDATE
+SET quantity = EXCLUDED.quantity;
+
+-- name: GetShoppingListSourceOverrides :many
+SELECT o.*
+FROM shopping_list_source_overrides o
+JOIN shopping_list_sources sls ON sls.id = o.shopping_list_source_id
+WHERE sls.shopping_list_id = $1;
+
+-- What each source added to each item of a list
+-- name: GetShoppingListContributions :many
+SELECT
+    slis.shopping_list_source_id,
+    slis.contributed_quantity,
+    sli.food_id,
+    sli.food_name,
+    sli.unit,
+    COALESCE(sli.purchased, FALSE)::boolean as purchased
+FROM shopping_list_item_sources slis
+JOIN shopping_list_items sli ON sli.id = slis.shopping_list_item_id
+WHERE sli.shopping_list_id = $1;
+
+-- Items of a list left without a source, except ones already bought, which are kept for what was paid
+-- name: DeleteUnsourcedShoppingListItems :exec
+DELETE FROM shopping_list_items sli
+WHERE sli.shopping_list_id = $1
+  AND NOT COALESCE(sli.purchased, FALSE)
+  AND NOT EXISTS (
+      SELECT 1 FROM shopping_list_item_sources slis
+      WHERE slis.shopping_list_item_id = sli.id
+  );
+
 -- Advanced Queries for Item Management
 -- name: GetShoppingListWithItemCounts :many
 SELECT 
@@ -261,11 +361,22 @@ GROUP BY schedule_id, food_id, food_name, unit_type, base_unit, unit
 ORDER BY food_name;
 
 -- Statistics and reporting queries
+-- Item counts and spend for several lists at once, lists without items have no row
+-- name: GetShoppingListsStats :many
+SELECT
+    shopping_list_id,
+    COUNT(*) as total_items,
+    COUNT(CASE WHEN purchased THEN 1 END) as purchased_items,
+    CAST(COALESCE(SUM(actual_price), 0) AS NUMERIC) as total_spent
+FROM shopping_list_items
+WHERE shopping_list_id = ANY(@list_ids::int[])
+GROUP BY shopping_list_id;
+
 -- name: GetShoppingListStats :one
 SELECT 
     COUNT(*) as total_items,
     COUNT(CASE WHEN purchased THEN 1 END) as purchased_items,
-    SUM(CASE WHEN actual_price IS NOT NULL THEN actual_price ELSE 0 END) as total_spent,
+    CAST(COALESCE(SUM(actual_price), 0) AS NUMERIC) as total_spent,
     COUNT(DISTINCT 
         CASE WHEN unit_type = 'mass' THEN food_id END
     ) as mass_items,
@@ -296,20 +407,34 @@ GROUP BY sls.id, sls.source_name, sls.source_type
 ORDER BY sls.added_at;
 
 -- Cleanup and maintenance queries
--- name: DeleteEmptyShoppingLists :exec
-DELETE FROM shopping_lists 
-WHERE id NOT IN (
-    SELECT DISTINCT shopping_list_id 
-    FROM shopping_list_items
+-- Lists that never had anything added, or had everything removed, and haven't been touched since $1
+-- name: DeleteEmptyShoppingLists :execrows
+DELETE FROM shopping_lists sl
+WHERE NOT EXISTS (
+    SELECT 1 FROM shopping_list_items sli
+    WHERE sli.shopping_list_id = sl.id
 )
-AND created_at < NOW() - INTERVAL '30 days';
-
--- name: ArchiveOldShoppingLists :exec
--- This would be for a future archiving feature
-UPDATE shopping_lists 
-SET notes = COALESCE(notes || ' ', '') || '[ARCHIVED]'
-WHERE updated_at < $1 
-  AND notes NOT LIKE '%[ARCHIVED]%';
+AND sl.updated_at < $1;
+
+-- Archives lists with everything bought and nothing changed since @completed_before, and any other list
+-- left untouched since @stale_before. Empty lists are left to DeleteEmptyShoppingLists.
+-- name: ArchiveOldShoppingLists :execrows
+UPDATE shopping_lists sl
+SET archived_at = NOW()
+FROM (
+    SELECT
+        shopping_list_id,
+        BOOL_AND(COALESCE(purchased, FALSE)) AS complete,
+        MAX(updated_at) AS last_changed
+    FROM shopping_list_items
+    GROUP BY shopping_list_id
+) items
+WHERE items.shopping_list_id = sl.id
+  AND sl.archived_at IS NULL
+  AND GREATEST(sl.updated_at, items.last_changed) < CASE
+      WHEN items.complete THEN @completed_before::timestamptz
+      ELSE @stale_before::timestamptz
+  END;
 
 -- Search and filtering
 -- name: SearchShoppingLists :many
@@ -339,12 +464,16 @@ SELECT
     sli.purchased,
     sli.actual_quantity,
     sli.actual_price,
+    sli.surplus_quantity,
+    sli.store_id,
     sli.created_at,
     sli.updated_at,
     COALESCE(qty_calc.total_quantity, CAST(0 AS NUMERIC)) as calculated_quantity,
     slis.shopping_list_source_id as source_id,
-    slis.contributed_quantity
+    slis.contributed_quantity,
+    CAST(COALESCE(f.category, sli.category) AS TEXT) as category
 FROM shopping_list_items sli
+LEFT JOIN foods f ON f.id = sli.food_id
 LEFT JOIN (
     SELECT 
         shopping_list_item_id,
@@ -367,11 +496,13 @@ SELECT
     unnest(@notes::text[])
 RETURNING id, food_id, unit;
 
+-- Lines already bought are left alone, more of the same goes on a line of its own
 -- name: BatchFindCompatibleItems :many
 SELECT id, food_id, unit 
 FROM shopping_list_items 
 WHERE shopping_list_id = $1 
-  AND (food_id, unit) = ANY(SELECT unnest($2::int[]), unnest($3::text[]));
+  AND (food_id, unit) = ANY(SELECT unnest($2::int[]), unnest($3::text[]))
+  AND NOT COALESCE(purchased, FALSE);
 
 -- name: BatchCreateShoppingListItemSources :exec  
 INSERT INTO shopping_list_item_sources (shopping_list_item_id, shopping_list_source_id, contributed_quantity)
@@ -0,0 +1,72 @@
+-- name: GetStores :many
+SELECT * FROM stores ORDER BY name;
+
+-- name: CreateStore :one
+INSERT INTO stores (name)
+VALUES ($1)
+RETURNING *;
+
+-- name: DeleteStore :exec
+DELETE FROM stores WHERE id = $1;
+
+-- name: GetStoreAisles :many
+SELECT store_id, category FROM store_aisles
+ORDER BY store_id, position;
+
+-- name: DeleteStoreAisles :exec
+DELETE FROM store_aisles WHERE store_id = $1;
+
+-- name: AddStoreAisle :exec
+INSERT INTO store_aisles (store_id, category, position)
+VALUES ($1, $2, $3);
+
+-- name: SetShoppingListStore :exec
+UPDATE shopping_lists
+SET store_id = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetShoppingListSplit :exec
+UPDATE shopping_lists
+SET split_by = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetFoodStoresForFoods :many
+SELECT food_id, store_id FROM food_stores
+WHERE food_id = ANY(@food_ids::int[])
+ORDER BY food_id, store_id;
+
+-- name: DeleteFoodStores :exec
+DELETE FROM food_stores WHERE food_id = $1;
+
+-- name: AddFoodStore :exec
+INSERT INTO food_stores (food_id, store_id)
+VALUES ($1, $2);
+
+-- The latest price observed for each food at each store
+-- name: GetLatestStorePricesForFoods :many
+SELECT DISTINCT ON (sp.food_id, sp.store_id)
+    sp.food_id,
+    sp.store_id,
+    s.name AS store_name,
+    sp.price,
+    sp.quantity,
+    sp.unit,
+    sp.observed_at
+FROM store_prices sp
+JOIN stores s ON s.id = sp.store_id
+WHERE sp.food_id = ANY(@food_ids::int[])
+ORDER BY sp.food_id, sp.store_id, sp.observed_at DESC;
+
+-- Re-ticking an item replaces the price recorded from it
+-- name: RecordStorePrice :exec
+INSERT INTO store_prices (food_id, store_id, price, quantity, unit, shopping_list_item_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (shopping_list_item_id) DO UPDATE
+SET store_id = EXCLUDED.store_id,
+    price = EXCLUDED.price,
+    quantity = EXCLUDED.quantity,
+    unit = EXCLUDED.unit,
+    observed_at = NOW();
+
+-- name: DeleteStorePriceForItem :exec
+DELETE FROM store_prices WHERE shopping_list_item_id = $1;
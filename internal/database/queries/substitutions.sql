@@ -0,0 +1,45 @@
+-- name: GetSubstitutions :many
+SELECT s.id, s.food_id, f.name AS food_name, s.quantity, s.unit, s.member_id,
+       COALESCE(m.name, '')::text AS member_name
+FROM substitutions s
+JOIN foods f ON f.id = s.food_id
+LEFT JOIN household_members m ON m.id = s.member_id
+ORDER BY f.name, s.id;
+
+-- name: GetSubstitutionItems :many
+SELECT si.substitution_id, si.food_id, f.name AS food_name, f.unit_type, f.base_unit,
+       f.is_recipe, f.is_staple, si.quantity, si.unit
+FROM substitution_items si
+JOIN foods f ON f.id = si.food_id
+ORDER BY si.substitution_id, si.id;
+
+-- name: CreateSubstitution :one
+INSERT INTO substitutions (food_id, quantity, unit, member_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: AddSubstitutionItem :exec
+INSERT INTO substitution_items (substitution_id, food_id, quantity, unit)
+VALUES ($1, $2, $3, $4);
+
+-- name: DeleteSubstitution :exec
+DELETE FROM substitutions WHERE id = $1;
+
+-- name: GetScheduleSubstitutionIds :many
+SELECT substitution_id FROM schedule_substitutions
+WHERE schedule_id = $1
+ORDER BY substitution_id;
+
+-- name: AddScheduleSubstitution :exec
+INSERT INTO schedule_substitutions (schedule_id, substitution_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteScheduleSubstitutions :exec
+DELETE FROM schedule_substitutions WHERE schedule_id = $1;
+
+-- name: CopyScheduleSubstitutions :exec
+INSERT INTO schedule_substitutions (schedule_id, substitution_id)
+SELECT @to_schedule_id::int, substitution_id
+FROM schedule_substitutions
+WHERE schedule_id = @from_schedule_id::int;
@@ -15,6 +15,9 @@ import (
 
 type CalendarHandler struct {
 	scheduleService *services.ScheduleService
+	settingsService *services.SettingsService
+	mealSlotService *services.MealSlotService
+	dietService     *services.DietService
 }
 
 func (h *CalendarHandler) HandleCalendarView(c echo.Context) error {
@@ -32,29 +35,54 @@ func (h *CalendarHandler) HandleCalendarView(c echo.Context) error {
 		chosenDate = time.Now().In(userTimeZone)
 	}
 
-	start := time.Date(chosenDate.Year(), chosenDate.Month(), chosenDate.Day(), 0, 0, 0, 0, userTimeZone)
-	end := start.AddDate(0, 0, 1)
+	view := c.QueryParam("view")
+	if !utils.IsValidCalendarView(view) {
+		view = utils.CalendarViewDay
+	}
+
+	settings, err := h.settingsService.GetSettings(c.Request().Context())
+	if err != nil {
+		log.Default().Printf("Error getting settings: %s", err)
+		return err
+	}
+
+	// Load the whole visible range in one query and split it per day afterwards
+	start, end := utils.GetCalendarRange(view, chosenDate, settings.WeekStart)
 
 	schedules, err := h.scheduleService.GetSchedulesForRange(c.Request().Context(), &start, &end, userTimeZone)
 	if err != nil {
 		log.Default().Printf("Error getting schedules: %s", err)
 		return err
 	}
+	if err := h.dietService.LabelSchedules(c.Request().Context(), schedules); err != nil {
+		log.Default().Printf("Error labelling schedules: %s", err)
+		return err
+	}
+
+	mealSlots, err := h.mealSlotService.GetMealSlots(c.Request().Context())
+	if err != nil {
+		log.Default().Printf("Error getting meal slots: %s", err)
+		return err
+	}
 
-	dayData := utils.GetDayData(&chosenDate, schedules)
+	calendarData := utils.GetCalendarData(view, chosenDate, settings.WeekStart, schedules)
+	calendarData.MealSlots = mealSlots
 
 	// Check if this is an HTMX request
 	if c.Request().Header.Get("HX-Request") != "" {
 		// Return content only for HTMX
-		return pages.CalendarPage(dayData).Render(c.Request().Context(), c.Response())
+		return pages.CalendarPage(calendarData).Render(c.Request().Context(), c.Response())
 	}
 
 	// Return full page with layout for direct navigation
-	return layouts.Base([]templ.Component{pages.CalendarPage(dayData)}).Render(c.Request().Context(), c.Response().Writer)
+	return layouts.Base([]templ.Component{pages.CalendarPage(calendarData)}).Render(c.Request().Context(), c.Response().Writer)
 }
 
-func NewCalendarHandler(scheduleService *services.ScheduleService) *CalendarHandler {
+func NewCalendarHandler(scheduleService *services.ScheduleService, settingsService *services.SettingsService, mealSlotService *services.MealSlotService, dietService *services.DietService) *CalendarHandler {
 	return &CalendarHandler{
 		scheduleService: scheduleService,
+		settingsService: settingsService,
+		mealSlotService: mealSlotService,
+		dietService:     dietService,
 	}
 }
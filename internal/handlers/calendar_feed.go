@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"log"
+	"mealplanner/internal/services"
+	"mealplanner/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Window of meals published in the feed, relative to the time the feed is fetched
+const (
+	calendarFeedPastDays   = 30
+	calendarFeedFutureDays = 180
+)
+
+type CalendarFeedHandler struct {
+	scheduleService *services.ScheduleService
+	settingsService *services.SettingsService
+}
+
+func NewCalendarFeedHandler(scheduleService *services.ScheduleService, settingsService *services.SettingsService) *CalendarFeedHandler {
+	return &CalendarFeedHandler{
+		scheduleService: scheduleService,
+		settingsService: settingsService,
+	}
+}
+
+// HandleCalendarFeed serves the meal plan as an .ics feed. The token in the URL is the only
+// authentication, so unknown or revoked tokens get a plain 404.
+func (h *CalendarFeedHandler) HandleCalendarFeed(c echo.Context) error {
+	valid, err := h.settingsService.IsValidCalendarFeedToken(c.Request().Context(), c.Param("token"))
+	if err != nil {
+		log.Printf("Error checking calendar feed token: %v", err)
+		return err
+	}
+	if !valid {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	now := time.Now().UTC()
+	start := now.AddDate(0, 0, -calendarFeedPastDays)
+	end := now.AddDate(0, 0, calendarFeedFutureDays)
+	schedules, err := h.scheduleService.GetSchedulesForRange(c.Request().Context(), &start, &end, time.UTC)
+	if err != nil {
+		log.Printf("Error getting schedules for calendar feed: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("Content-Disposition", "inline; filename=meal-plan.ics")
+	c.Response().Header().Set("Cache-Control", "private, max-age=900")
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", []byte(utils.BuildICalendar("Meal Plan", schedules, now)))
+}
@@ -12,6 +12,7 @@ import (
 	"mealplanner/internal/views/pages"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/a-h/templ"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -19,7 +20,10 @@ import (
 )
 
 type FoodHandler struct {
-	service *services.FoodService
+	service       *services.FoodService
+	memberService *services.MemberService
+	dietService   *services.DietService
+	storeService  *services.StoreService
 }
 
 func (h *FoodHandler) HandleFoodsPage(c echo.Context) error {
@@ -33,13 +37,130 @@ func (h *FoodHandler) HandleFoodsPage(c echo.Context) error {
 	return layouts.Base([]templ.Component{pages.FoodsPage()}).Render(c.Request().Context(), c.Response().Writer)
 }
 func (h *FoodHandler) HandleViewFoodDetailsModal(c echo.Context) error {
-	id := c.QueryParam("id")
+	return h.renderFoodDetailsModal(c, c.QueryParam("id"))
+}
+
+// HandleSetFavourite flags or unflags a food as a favourite and re-renders its details
+func (h *FoodHandler) HandleSetFavourite(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	err = h.service.SetFavourite(c.Request().Context(), id, c.FormValue("is_favourite") == "true")
+	if err != nil {
+		log.Default().Printf("Error setting favourite: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshFoodList")
+	return h.renderFoodDetailsModal(c, c.Param("id"))
+}
+
+// HandleRateRecipe records a member's rating and comment, replacing their earlier one
+func (h *FoodHandler) HandleRateRecipe(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+	memberId, err := strconv.Atoi(c.FormValue("member_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Choose who is rating")
+	}
+	rating, err := strconv.Atoi(c.FormValue("rating"))
+	if err != nil || rating < 1 || rating > 5 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Rating must be between 1 and 5")
+	}
+
+	err = h.service.RateRecipe(c.Request().Context(), id, memberId, rating, strings.TrimSpace(c.FormValue("comment")))
+	if err != nil {
+		log.Default().Printf("Error rating recipe: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshFoodList")
+	return h.renderFoodDetailsModal(c, c.Param("id"))
+}
+
+func (h *FoodHandler) HandleDeleteRating(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+	memberId, err := strconv.Atoi(c.Param("memberId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid member ID")
+	}
+
+	err = h.service.DeleteRecipeRating(c.Request().Context(), id, memberId)
+	if err != nil {
+		log.Default().Printf("Error deleting rating: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshFoodList")
+	return h.renderFoodDetailsModal(c, c.Param("id"))
+}
+
+func (h *FoodHandler) renderFoodDetailsModal(c echo.Context, id string) error {
 	food, err := h.service.GetFoodDetails(c.Request().Context(), id, 1)
 	if err != nil {
 		log.Default().Printf("Error getting food details: %v", err)
 		return c.String(500, "Error getting food")
 	}
-	return components.ViewFoodDetailsModal(food).Render(c.Request().Context(), c.Response().Writer)
+	if err := h.service.LoadFoodHistory(c.Request().Context(), food); err != nil {
+		log.Default().Printf("Error getting food history: %v", err)
+		return c.String(500, "Error getting food")
+	}
+	if err := h.dietService.LabelFood(c.Request().Context(), food); err != nil {
+		return c.String(500, "Error getting food labels")
+	}
+
+	var ratings []*models.RecipeRating
+	var members []*models.HouseholdMember
+	if food.IsRecipe {
+		ratings, err = h.service.GetRecipeRatings(c.Request().Context(), food.ID)
+		if err != nil {
+			return c.String(500, "Error getting ratings")
+		}
+		members, err = h.memberService.GetMembers(c.Request().Context())
+		if err != nil {
+			return c.String(500, "Error getting household members")
+		}
+	}
+	return components.ViewFoodDetailsModal(food, ratings, members).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleScaleRecipe scales a recipe so it uses up the given amount of one of its ingredients
+func (h *FoodHandler) HandleScaleRecipe(c echo.Context) error {
+	recipeId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe ID")
+	}
+
+	limit, errors, err := parseIngredientLimit(c, h.service)
+	if err != nil {
+		log.Printf("Error getting ingredient to scale to: %v", err)
+		return err
+	}
+	var scaled *models.ScaledRecipe
+	if len(errors) == 0 {
+		scaled, err = h.service.ScaleToIngredient(c.Request().Context(), recipeId, limit)
+		switch err {
+		case nil:
+		case services.ErrIngredientNotInRecipe:
+			errors["limit_food_id"] = "The recipe doesn't use this ingredient"
+		case services.ErrIncompatibleUnit:
+			errors["limit_unit"] = "The recipe measures this ingredient in a different kind of unit"
+		default:
+			log.Printf("Error scaling recipe: %v", err)
+			return err
+		}
+	}
+	if len(errors) > 0 {
+		c.Response().Writer.WriteHeader(http.StatusBadRequest)
+	}
+	return components.ScaledRecipe(recipeId, scaled, errors).Render(c.Request().Context(), c.Response().Writer)
 }
 
 func (h *FoodHandler) HandleSearchFoods(c echo.Context) error {
@@ -56,7 +177,7 @@ func (h *FoodHandler) HandleSearchFoods(c echo.Context) error {
 	}
 
 	// Use paginated method for main list
-	foods, pagination, err := h.service.GetFoodsPaginated(c.Request().Context(), query, page, pageSize)
+	foods, pagination, err := h.service.GetFoodsPaginated(c.Request().Context(), query, parseFoodFilter(c), page, pageSize)
 	if err != nil {
 		return c.String(500, "Error searching foods")
 	}
@@ -77,7 +198,7 @@ func (h *FoodHandler) HandleAutocomplete(c echo.Context) error {
 		}
 	}
 	
-	foods, err := h.service.SearchFoodsAutocomplete(c.Request().Context(), query, limit)
+	foods, err := h.service.SearchFoodsAutocomplete(c.Request().Context(), query, parseFoodFilter(c), limit)
 	if err != nil {
 		log.Default().Printf("Error in autocomplete search: %v", err)
 		return c.String(500, "Error searching foods")
@@ -91,19 +212,13 @@ func (h *FoodHandler) HandleRecipeAutocomplete(c echo.Context) error {
     limit, _ := strconv.Atoi(c.QueryParam("limit"))
     if limit <= 0 { limit = 10 }
     
-    foods, err := h.service.SearchFoodsAutocomplete(c.Request().Context(), query, limit)
+    filter := parseFoodFilter(c)
+    filter.RecipesOnly = true
+    recipes, err := h.service.SearchFoodsAutocomplete(c.Request().Context(), query, filter, limit)
     if err != nil {
         return c.String(500, "Error searching recipes")
     }
     
-    // Filter to only recipes
-    recipes := make([]*models.Food, 0)
-    for _, food := range foods {
-        if food.IsRecipe {
-            recipes = append(recipes, food)
-        }
-    }
-    
     return components.AutocompleteResults(recipes).Render(c.Request().Context(), c.Response().Writer)
 }
 
@@ -155,6 +270,14 @@ func (h *FoodHandler) HandleCreateFoodModal(c echo.Context) error {
 			}
 			form.CombineDuplicateIngredients()
 		}
+		if err := form.BindUnits(c); err != nil {
+			log.Default().Printf("Error binding units form: %v", err)
+			return err
+		}
+		if err := form.BindPackages(c); err != nil {
+			log.Default().Printf("Error binding packages form: %v", err)
+			return err
+		}
 
 		// Validate form
 		if err := form.Validate(); err != nil {
@@ -174,6 +297,9 @@ func (h *FoodHandler) HandleCreateFoodModal(c echo.Context) error {
 				props.Foods = utils.ValidateAndFilterDependencies(availableFoods, 0)
 			}
 
+			if err := h.withStores(c, &props); err != nil {
+				return err
+			}
 			c.Response().Writer.WriteHeader(http.StatusBadRequest)
 			return components.CreateEditFoodModal(&props).Render(c.Request().Context(), c.Response().Writer)
 		}
@@ -184,8 +310,10 @@ func (h *FoodHandler) HandleCreateFoodModal(c echo.Context) error {
 			UnitType: form.UnitType,
 			BaseUnit: form.BaseUnit,
 			IsRecipe: form.IsRecipe,
+			IsStaple: form.IsStaple,
+			Category: form.Category,
 			//TODO: Calculate density
-		})
+		}, form.Labels, form.FoodUnits(), form.FoodPackages(), form.StoreIDs)
 		if err != nil {
 			log.Default().Printf("Error creating food: %v", err)
 			return err
@@ -203,15 +331,18 @@ func (h *FoodHandler) HandleCreateFoodModal(c echo.Context) error {
 					IngredientID: int32(ingredientID),
 					Quantity:     utils.Float64ToNumeric(ing.Quantity),
 					Unit:         ing.Unit,
+					IsMain:       ing.IsMain,
 				}
 			}
 
 			err = h.service.CreateRecipeWithIngredients(c.Request().Context(), db.CreateRecipeParams{
-				FoodID:        food.ID,
-				Url:           pgtype.Text{String: form.RecipeURL},
-				Instructions:  pgtype.Text{String: form.Instructions},
-				YieldQuantity: utils.Float64ToNumeric(form.YieldQuantity),
-			}, dbIngredients)
+				FoodID:         food.ID,
+				Url:            pgtype.Text{String: form.RecipeURL},
+				Instructions:   pgtype.Text{String: form.Instructions},
+				YieldQuantity:  utils.Float64ToNumeric(form.YieldQuantity),
+				PrepMinutes:    utils.OptionalInt4(form.PrepMinutes),
+				CostPerServing: utils.OptionalNumeric(form.CostPerServing),
+			}, dbIngredients, form.TagList())
 			if err != nil {
 				log.Default().Printf("Error adding recipe ingredient: %v", err)
 				return err
@@ -235,6 +366,9 @@ func (h *FoodHandler) HandleCreateFoodModal(c echo.Context) error {
 		Errors: make(map[string]string),
 	}
 
+	if err := h.withStores(c, &props); err != nil {
+		return err
+	}
 	return components.CreateEditFoodModal(&props).Render(c.Request().Context(), c.Response().Writer)
 }
 
@@ -261,6 +395,14 @@ func (h *FoodHandler) HandleEditFoodModal(c echo.Context) error {
 				return err
 			}
 		}
+		if err := form.BindUnits(c); err != nil {
+			log.Default().Printf("Error binding units form: %v", err)
+			return err
+		}
+		if err := form.BindPackages(c); err != nil {
+			log.Default().Printf("Error binding packages form: %v", err)
+			return err
+		}
 
 		// Validate form
 		if err := form.Validate(); err != nil {
@@ -281,19 +423,26 @@ func (h *FoodHandler) HandleEditFoodModal(c echo.Context) error {
 				props.Foods = utils.ValidateAndFilterDependencies(availableFoods, idNum)
 			}
 
+			if err := h.withStores(c, &props); err != nil {
+				return err
+			}
 			c.Response().Status = http.StatusBadRequest
 			return components.CreateEditFoodModal(&props).Render(c.Request().Context(), c.Response().Writer)
 		}
 
 		updateParams := db.UpdateFoodWithRecipeParams{
-			ID:            int32(idNum),
-			Name:          form.Name,
-			UnitType:      form.UnitType,
-			BaseUnit:      form.BaseUnit,
-			IsRecipe:      form.IsRecipe,
-			Url:           pgtype.Text{String: form.RecipeURL},
-			Instructions:  pgtype.Text{String: form.Instructions},
-			YieldQuantity: utils.Float64ToNumeric(form.YieldQuantity),
+			ID:             int32(idNum),
+			Name:           form.Name,
+			UnitType:       form.UnitType,
+			BaseUnit:       form.BaseUnit,
+			IsRecipe:       form.IsRecipe,
+			IsStaple:       form.IsStaple,
+			Url:            pgtype.Text{String: form.RecipeURL},
+			Instructions:   pgtype.Text{String: form.Instructions},
+			YieldQuantity:  utils.Float64ToNumeric(form.YieldQuantity),
+			PrepMinutes:    utils.OptionalInt4(form.PrepMinutes),
+			CostPerServing: utils.OptionalNumeric(form.CostPerServing),
+			Category:       form.Category,
 			// Calculate density
 		}
 		dbIngredients := make([]db.AddRecipeIngredientParams, len(form.Ingredients))
@@ -307,10 +456,32 @@ func (h *FoodHandler) HandleEditFoodModal(c echo.Context) error {
 				IngredientID: int32(ingredientID),
 				Quantity:     utils.Float64ToNumeric(ing.Quantity),
 				Unit:         ing.Unit,
+				IsMain:       ing.IsMain,
 			}
 		}
 
-		_, err = h.service.UpdateFood(c.Request().Context(), updateParams, dbIngredients, false)
+		_, err = h.service.UpdateFood(c.Request().Context(), updateParams, dbIngredients, form.TagList(), form.Labels, form.FoodUnits(), form.FoodPackages(), form.StoreIDs, false)
+		if errors.Is(err, services.ErrFoodUnitInUse) {
+			message := err.Error()
+			props := utils.FoodFormProps{
+				IsEdit: true,
+				Food:   form.ToModel(),
+				Errors: map[string]string{"units": strings.ToUpper(message[:1]) + message[1:]},
+			}
+			if form.IsRecipe {
+				availableFoods, err := h.service.GetFoods(c.Request().Context(), "")
+				if err != nil {
+					log.Default().Printf("Error getting foods: %v", err)
+					return err
+				}
+				props.Foods = utils.ValidateAndFilterDependencies(availableFoods, idNum)
+			}
+			if err := h.withStores(c, &props); err != nil {
+				return err
+			}
+			c.Response().Status = http.StatusBadRequest
+			return components.CreateEditFoodModal(&props).Render(c.Request().Context(), c.Response().Writer)
+		}
 		if err != nil {
 			log.Default().Printf("Error updating food: %v", err)
 			return err
@@ -341,6 +512,9 @@ func (h *FoodHandler) HandleEditFoodModal(c echo.Context) error {
 		props.Foods = utils.ValidateAndFilterDependencies(availableFoods, idNum)
 	}
 
+	if err := h.withStores(c, &props); err != nil {
+		return err
+	}
 	return components.CreateEditFoodModal(&props).Render(c.Request().Context(), c.Response().Writer)
 }
 
@@ -462,8 +636,64 @@ func (h *FoodHandler) GetFoodUnits(c echo.Context) error {
 	return components.BaseUnitsOptions(units, defaultBaseUnit).Render(
 		c.Request().Context(), c.Response().Writer)
 }
-func NewFoodHandler(service *services.FoodService) *FoodHandler {
+// parseFoodFilter reads the sort and filter query parameters shared by the food list and autocomplete
+func parseFoodFilter(c echo.Context) *models.FoodFilter {
+	filter := &models.FoodFilter{
+		Sort:           c.QueryParam("sort"),
+		FavouritesOnly: c.QueryParam("favourites") == "true",
+	}
+	if minRating, err := strconv.ParseFloat(c.QueryParam("min_rating"), 64); err == nil && minRating > 0 {
+		filter.MinRating = minRating
+	}
+	if days, err := strconv.Atoi(c.QueryParam("not_cooked_days")); err == nil && days > 0 {
+		filter.NotCookedDays = days
+	}
+	return filter
+}
+
+// parseIngredientLimit reads the ingredient and amount to scale a recipe to, returning the field errors alongside.
+// The unit can be a standard one or one of the ingredient's own.
+func parseIngredientLimit(c echo.Context, foodService *services.FoodService) (*models.RecipeItem, map[string]string, error) {
+	errors := make(map[string]string)
+	limit := &models.RecipeItem{
+		Unit: c.FormValue("limit_unit"),
+	}
+
+	var err error
+	limit.FoodID, err = strconv.Atoi(c.FormValue("limit_food_id"))
+	if err != nil {
+		errors["limit_food_id"] = "Choose an ingredient"
+	} else {
+		limit.Food, err = foodService.GetFoodDetails(c.Request().Context(), strconv.Itoa(limit.FoodID), 0)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	limit.Quantity, err = strconv.ParseFloat(c.FormValue("limit_quantity"), 64)
+	if err != nil || limit.Quantity <= 0 {
+		errors["limit_quantity"] = "Quantity must be a positive number"
+	}
+	if limit.Food.CustomUnit(limit.Unit) == nil && utils.UnitTypeOf(limit.Unit) == "" {
+		errors["limit_unit"] = "Invalid unit"
+	}
+	return limit, errors, nil
+}
+
+func NewFoodHandler(service *services.FoodService, memberService *services.MemberService, dietService *services.DietService, storeService *services.StoreService) *FoodHandler {
 	return &FoodHandler{
-		service: service,
+		service:       service,
+		memberService: memberService,
+		dietService:   dietService,
+		storeService:  storeService,
+	}
+}
+
+// withStores adds the stores a food can be bought from to the food form
+func (h *FoodHandler) withStores(c echo.Context, props *utils.FoodFormProps) error {
+	stores, err := h.storeService.GetStores(c.Request().Context())
+	if err != nil {
+		return err
 	}
+	props.Stores = stores
+	return nil
 }
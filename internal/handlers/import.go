@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mealplanner/internal/services"
+	"mealplanner/internal/utils"
+	"mealplanner/internal/views/layouts"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// Largest import file accepted, years of plans fit comfortably
+const maxImportFileSize = 5 << 20
+
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+func NewImportHandler(importService *services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+func (h *ImportHandler) HandleImportPage(c echo.Context) error {
+	// Check if this is an HTMX request
+	if c.Request().Header.Get("HX-Request") != "" {
+		// Return content only for HTMX
+		return pages.ImportPage(map[string]string{}).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	// Return full page with layout for direct navigation
+	return layouts.Base([]templ.Component{pages.ImportPage(map[string]string{})}).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleImportPreview parses the uploaded file and renders the dry-run report, nothing is created yet
+func (h *ImportHandler) HandleImportPreview(c echo.Context) error {
+	errors := make(map[string]string)
+	defaultTime := c.FormValue("default_time")
+	if _, err := time.Parse("15:04", defaultTime); err != nil {
+		errors["default_time"] = "Please choose a default time"
+	}
+
+	var content, filename string
+	file, err := c.FormFile("file")
+	if err != nil {
+		errors["file"] = "Please choose a file"
+	} else if file.Size > maxImportFileSize {
+		errors["file"] = "File is too large"
+	} else {
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		content, filename = string(data), file.Filename
+	}
+
+	if len(errors) > 0 {
+		c.Response().Writer.WriteHeader(http.StatusBadRequest)
+		return pages.ImportPage(errors).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	format := utils.DetectImportFormat(filename, content)
+	report, err := h.importService.DryRun(c.Request().Context(), format, content, defaultTime, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error reading import file: %v", err)
+		c.Response().Writer.WriteHeader(http.StatusBadRequest)
+		return pages.ImportPage(map[string]string{"file": err.Error()}).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	return pages.ImportReport(report, content, defaultTime).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleImportCommit imports the file from the dry run, with the foods picked for unmatched titles
+func (h *ImportHandler) HandleImportCommit(c echo.Context) error {
+	if err := c.Request().ParseForm(); err != nil {
+		return err
+	}
+
+	// alias_title_N / alias_food_N pairs come from the unmatched titles of the dry run
+	aliases := make(map[string]int)
+	for i := 0; ; i++ {
+		title := c.FormValue(fmt.Sprintf("alias_title_%d", i))
+		if title == "" {
+			break
+		}
+		if foodId, err := strconv.Atoi(c.FormValue(fmt.Sprintf("alias_food_%d", i))); err == nil && foodId > 0 {
+			aliases[title] = foodId
+		}
+	}
+
+	report, err := h.importService.Import(c.Request().Context(), c.FormValue("format"), c.FormValue("content"), c.FormValue("default_time"), aliases, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error importing meal plan: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshCalendar")
+	return pages.ImportReport(report, "", "").Render(c.Request().Context(), c.Response().Writer)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"log"
+	"mealplanner/internal/services"
+	"mealplanner/internal/views/layouts"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+type PantryHandler struct {
+	pantryService *services.PantryService
+}
+
+func NewPantryHandler(pantryService *services.PantryService) *PantryHandler {
+	return &PantryHandler{
+		pantryService: pantryService,
+	}
+}
+
+func (h *PantryHandler) HandlePantryPage(c echo.Context) error {
+	staples, err := h.pantryService.GetStaples(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting staples: %v", err)
+		return err
+	}
+
+	// Check if this is an HTMX request
+	if c.Request().Header.Get("HX-Request") != "" {
+		// Return content only for HTMX
+		return pages.PantryPage(staples).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	// Return full page with layout for direct navigation
+	return layouts.Base([]templ.Component{pages.PantryPage(staples)}).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *PantryHandler) HandleUpdateStock(c echo.Context) error {
+	foodId, err := strconv.Atoi(c.Param("foodId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid food ID")
+	}
+
+	var form struct {
+		Quantity float64 `form:"quantity"`
+		ParLevel float64 `form:"par_level"`
+	}
+
+	if err := c.Bind(&form); err != nil {
+		return err
+	}
+
+	if form.Quantity < 0 || form.ParLevel < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Quantity and par level cannot be negative")
+	}
+
+	err = h.pantryService.SetStock(c.Request().Context(), foodId, form.Quantity, form.ParLevel)
+	if err != nil {
+		log.Printf("Error updating pantry stock: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshPantry")
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *PantryHandler) HandleRemoveStaple(c echo.Context) error {
+	foodId, err := strconv.Atoi(c.Param("foodId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid food ID")
+	}
+
+	err = h.pantryService.RemoveStaple(c.Request().Context(), foodId)
+	if err != nil {
+		log.Printf("Error removing staple: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshPantry")
+	return c.NoContent(http.StatusNoContent)
+}
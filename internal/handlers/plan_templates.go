@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"mealplanner/internal/services"
+	"mealplanner/internal/utils"
+	"mealplanner/internal/views/layouts"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+type PlanTemplateHandler struct {
+	planTemplateService *services.PlanTemplateService
+}
+
+func NewPlanTemplateHandler(planTemplateService *services.PlanTemplateService) *PlanTemplateHandler {
+	return &PlanTemplateHandler{
+		planTemplateService: planTemplateService,
+	}
+}
+
+func (h *PlanTemplateHandler) HandleTemplatesPage(c echo.Context) error {
+	return h.renderTemplatesPage(c, map[string]string{})
+}
+
+// HandleCreateTemplate saves the meals between start_date and end_date (inclusive) as a template
+func (h *PlanTemplateHandler) HandleCreateTemplate(c echo.Context) error {
+	var form struct {
+		Name      string `form:"name"`
+		StartDate string `form:"start_date"`
+		EndDate   string `form:"end_date"`
+	}
+
+	if err := c.Bind(&form); err != nil {
+		return err
+	}
+
+	timeZone := utils.GetTimezone(c)
+	errors := make(map[string]string)
+	form.Name = strings.TrimSpace(form.Name)
+	if form.Name == "" {
+		errors["name"] = "Name is required"
+	}
+
+	startDate, err := time.ParseInLocation("2006-01-02", form.StartDate, timeZone)
+	if err != nil {
+		errors["start_date"] = "Invalid start date"
+	}
+
+	endDate, err := time.ParseInLocation("2006-01-02", form.EndDate, timeZone)
+	if err != nil {
+		errors["end_date"] = "Invalid end date"
+	} else if endDate.Before(startDate) {
+		errors["end_date"] = "End date must be after start date"
+	}
+
+	if len(errors) == 0 {
+		_, err = h.planTemplateService.CreateTemplateFromRange(c.Request().Context(), form.Name, startDate, endDate.AddDate(0, 0, 1), timeZone)
+		if err == services.ErrEmptyPlanTemplate {
+			errors["start_date"] = "No meals are scheduled in this range"
+		} else if err == services.ErrPlanTemplateNameTaken {
+			errors["name"] = "A template with this name already exists"
+		} else if err != nil {
+			log.Printf("Error creating plan template: %v", err)
+			return err
+		}
+	}
+
+	if len(errors) > 0 {
+		c.Response().Writer.WriteHeader(http.StatusBadRequest)
+		return h.renderTemplatesPage(c, errors)
+	}
+
+	return h.renderTemplatesPage(c, map[string]string{})
+}
+
+func (h *PlanTemplateHandler) HandlePreviewTemplate(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID")
+	}
+
+	startDate, scale, err := parseApplyTemplateForm(c)
+	if err != nil {
+		return err
+	}
+
+	preview, err := h.planTemplateService.PreviewTemplate(c.Request().Context(), id, startDate, scale, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error previewing plan template: %v", err)
+		return err
+	}
+
+	return pages.PlanTemplatePreview(preview).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *PlanTemplateHandler) HandleApplyTemplate(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID")
+	}
+
+	startDate, scale, err := parseApplyTemplateForm(c)
+	if err != nil {
+		return err
+	}
+	skipConflicts := c.FormValue("skip_conflicts") == "true"
+
+	created, err := h.planTemplateService.ApplyTemplate(c.Request().Context(), id, startDate, scale, skipConflicts, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error applying plan template: %v", err)
+		return err
+	}
+
+	triggerData := fmt.Sprintf(`{"refreshCalendar": {"date": "%s"}}`, startDate.Format("2006-01-02"))
+	c.Response().Header().Set("HX-Trigger", triggerData)
+	return c.String(http.StatusOK, fmt.Sprintf("Added %d meals", created))
+}
+
+func (h *PlanTemplateHandler) HandleDeleteTemplate(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID")
+	}
+
+	err = h.planTemplateService.DeleteTemplate(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error deleting plan template: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshTemplates")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *PlanTemplateHandler) renderTemplatesPage(c echo.Context, errors map[string]string) error {
+	templates, err := h.planTemplateService.GetTemplates(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting plan templates: %v", err)
+		return err
+	}
+
+	// Check if this is an HTMX request
+	if c.Request().Header.Get("HX-Request") != "" {
+		// Return content only for HTMX
+		return pages.PlanTemplatesPage(templates, errors).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	// Return full page with layout for direct navigation
+	return layouts.Base([]templ.Component{pages.PlanTemplatesPage(templates, errors)}).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// parseApplyTemplateForm reads the start date and servings scale shared by preview and apply
+func parseApplyTemplateForm(c echo.Context) (time.Time, float64, error) {
+	startDate, err := time.ParseInLocation("2006-01-02", c.FormValue("start_date"), utils.GetTimezone(c))
+	if err != nil {
+		return time.Time{}, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid start date")
+	}
+
+	scale := 1.0
+	if value := c.FormValue("scale"); value != "" {
+		scale, err = strconv.ParseFloat(value, 64)
+		if err != nil || scale <= 0 {
+			return time.Time{}, 0, echo.NewHTTPError(http.StatusBadRequest, "Servings scale must be a positive number")
+		}
+	}
+	return startDate, scale, nil
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"mealplanner/internal/models"
+	"mealplanner/internal/services"
+	"mealplanner/internal/utils"
+	"mealplanner/internal/views/layouts"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// Longest range the generator fills in one go
+const maxPlannerDays = 31
+
+type PlannerHandler struct {
+	planGeneratorService *services.PlanGeneratorService
+	mealSlotService      *services.MealSlotService
+}
+
+func NewPlannerHandler(planGeneratorService *services.PlanGeneratorService, mealSlotService *services.MealSlotService) *PlannerHandler {
+	return &PlannerHandler{
+		planGeneratorService: planGeneratorService,
+		mealSlotService:      mealSlotService,
+	}
+}
+
+// HandlePlannerPage shows the constraint form for the coming week with a fresh seed
+func (h *PlannerHandler) HandlePlannerPage(c echo.Context) error {
+	now := time.Now().In(utils.GetTimezone(c))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	constraints := &models.PlanConstraints{
+		StartDate:    today,
+		EndDate:      today.AddDate(0, 0, 6),
+		Servings:     1,
+		NoRepeatDays: 7,
+		Seed:         rand.Int63n(1000000),
+	}
+	return h.renderPlannerPage(c, constraints, nil, map[string]string{})
+}
+
+// HandleGeneratePlan renders a proposal for review, nothing is scheduled yet
+func (h *PlannerHandler) HandleGeneratePlan(c echo.Context) error {
+	constraints, errors := parsePlanConstraints(c)
+	if len(errors) > 0 {
+		c.Response().Writer.WriteHeader(http.StatusBadRequest)
+		return h.renderPlannerPage(c, constraints, nil, errors)
+	}
+
+	proposal, err := h.planGeneratorService.Generate(c.Request().Context(), constraints, utils.GetTimezone(c))
+	switch err {
+	case nil:
+	case services.ErrNoMealSlots:
+		errors["meal_slot_ids"] = "Choose at least one meal slot"
+	case services.ErrNoPlanCandidates:
+		errors["required_tags"] = "No recipes have these tags"
+	default:
+		log.Printf("Error generating plan: %v", err)
+		return err
+	}
+	if len(errors) > 0 {
+		c.Response().Writer.WriteHeader(http.StatusBadRequest)
+		return h.renderPlannerPage(c, constraints, nil, errors)
+	}
+
+	return h.renderPlannerPage(c, constraints, proposal, errors)
+}
+
+// HandleApplyPlan schedules the meals that were left checked in the proposal
+func (h *PlannerHandler) HandleApplyPlan(c echo.Context) error {
+	form, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+
+	var meals []*models.ProposedMeal
+	for _, index := range form["include"] {
+		foodId, err := strconv.Atoi(c.FormValue("food_id_" + index))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid food ID")
+		}
+		scheduledAt, err := time.Parse(time.RFC3339, c.FormValue("scheduled_at_"+index))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid scheduled time")
+		}
+		servings, err := strconv.ParseFloat(c.FormValue("servings_"+index), 64)
+		if err != nil || servings <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid servings")
+		}
+		mealSlotId, _ := strconv.Atoi(c.FormValue("meal_slot_id_" + index))
+		meals = append(meals, &models.ProposedMeal{
+			FoodID:      foodId,
+			ScheduledAt: scheduledAt,
+			Servings:    servings,
+			MealSlotID:  mealSlotId,
+		})
+	}
+
+	created, err := h.planGeneratorService.ApplyProposal(c.Request().Context(), meals, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error applying generated plan: %v", err)
+		return err
+	}
+
+	if len(meals) > 0 {
+		triggerData := fmt.Sprintf(`{"refreshCalendar": {"date": "%s"}}`, meals[0].ScheduledAt.In(utils.GetTimezone(c)).Format("2006-01-02"))
+		c.Response().Header().Set("HX-Trigger", triggerData)
+	}
+	return c.String(http.StatusOK, fmt.Sprintf("Added %d meals", created))
+}
+
+func (h *PlannerHandler) renderPlannerPage(c echo.Context, constraints *models.PlanConstraints, proposal *models.PlanProposal, errors map[string]string) error {
+	mealSlots, err := h.mealSlotService.GetMealSlots(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting meal slots: %v", err)
+		return err
+	}
+	tags, err := h.planGeneratorService.GetRecipeTags(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting recipe tags: %v", err)
+		return err
+	}
+
+	page := pages.PlannerPage(constraints, mealSlots, tags, proposal, errors)
+	// Check if this is an HTMX request
+	if c.Request().Header.Get("HX-Request") != "" {
+		// Return content only for HTMX
+		return page.Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	// Return full page with layout for direct navigation
+	return layouts.Base([]templ.Component{page}).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// parsePlanConstraints reads the generator form, returning the field errors alongside what could be parsed
+func parsePlanConstraints(c echo.Context) (*models.PlanConstraints, map[string]string) {
+	timeZone := utils.GetTimezone(c)
+	errors := make(map[string]string)
+	constraints := &models.PlanConstraints{
+		PreferPantry: c.FormValue("prefer_pantry") == "true",
+		RequiredTags: utils.ParseTags(c.FormValue("required_tags")),
+		ExcludedTags: utils.ParseTags(c.FormValue("excluded_tags")),
+	}
+
+	var err error
+	constraints.StartDate, err = time.ParseInLocation("2006-01-02", c.FormValue("start_date"), timeZone)
+	if err != nil {
+		errors["start_date"] = "Invalid start date"
+	}
+	constraints.EndDate, err = time.ParseInLocation("2006-01-02", c.FormValue("end_date"), timeZone)
+	if err != nil {
+		errors["end_date"] = "Invalid end date"
+	} else if constraints.EndDate.Before(constraints.StartDate) {
+		errors["end_date"] = "End date must be after start date"
+	} else if daysInRange := int(constraints.EndDate.Sub(constraints.StartDate).Hours()/24) + 1; daysInRange > maxPlannerDays {
+		errors["end_date"] = fmt.Sprintf("Plan at most %d days at a time", maxPlannerDays)
+	}
+
+	form, _ := c.FormParams()
+	for _, value := range form["meal_slot_ids"] {
+		if id, err := strconv.Atoi(value); err == nil {
+			constraints.MealSlotIDs = append(constraints.MealSlotIDs, id)
+		}
+	}
+	if len(constraints.MealSlotIDs) == 0 {
+		errors["meal_slot_ids"] = "Choose at least one meal slot"
+	}
+
+	constraints.Servings, err = strconv.ParseFloat(c.FormValue("servings"), 64)
+	if err != nil || constraints.Servings <= 0 {
+		errors["servings"] = "Servings must be a positive number"
+	}
+	if value := c.FormValue("no_repeat_days"); value != "" {
+		constraints.NoRepeatDays, err = strconv.Atoi(value)
+		if err != nil || constraints.NoRepeatDays < 0 {
+			errors["no_repeat_days"] = "Must be a whole number of days"
+		}
+	}
+	if value := c.FormValue("max_weekday_prep_minutes"); value != "" {
+		constraints.MaxWeekdayPrepMinutes, err = strconv.Atoi(value)
+		if err != nil || constraints.MaxWeekdayPrepMinutes < 0 {
+			errors["max_weekday_prep_minutes"] = "Must be a whole number of minutes"
+		}
+	}
+	if value := c.FormValue("budget"); value != "" {
+		constraints.Budget, err = strconv.ParseFloat(value, 64)
+		if err != nil || constraints.Budget < 0 {
+			errors["budget"] = "Budget can't be negative"
+		}
+	}
+	constraints.Seed, err = strconv.ParseInt(c.FormValue("seed"), 10, 64)
+	if err != nil {
+		errors["seed"] = "Seed must be a whole number"
+	}
+	return constraints, errors
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"mealplanner/internal/models"
+	"mealplanner/internal/services"
+	"mealplanner/internal/utils"
+	"mealplanner/internal/views/layouts"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+type ReportHandler struct {
+	reportService *services.ReportService
+	foodService   *services.FoodService
+}
+
+func NewReportHandler(reportService *services.ReportService, foodService *services.FoodService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		foodService:   foodService,
+	}
+}
+
+// HandleReportsPage shows grocery spending by week or month, category and store, with price trends per food
+func (h *ReportHandler) HandleReportsPage(c echo.Context) error {
+	period, from, to, err := parseReportRange(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.reportService.GetSpendingReport(c.Request().Context(), period, from, to)
+	if err != nil {
+		log.Printf("Error getting spending report: %v", err)
+		return err
+	}
+	foods, err := h.foodService.GetFoods(c.Request().Context(), "")
+	if err != nil {
+		log.Printf("Error getting foods: %v", err)
+		return err
+	}
+
+	page := pages.ReportsPage(report, foods)
+	// Check if this is an HTMX request
+	if c.Request().Header.Get("HX-Request") != "" {
+		// Return content only for HTMX
+		return page.Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	// Return full page with layout for direct navigation
+	return layouts.Base([]templ.Component{page}).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *ReportHandler) HandleExportSpending(c echo.Context) error {
+	period, from, to, err := parseReportRange(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.reportService.GetSpendingReport(c.Request().Context(), period, from, to)
+	if err != nil {
+		log.Printf("Error getting spending report: %v", err)
+		return err
+	}
+	text, err := utils.BuildSpendingCSV(report)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("spending-%s-%s.csv", report.From.Format(time.DateOnly), report.To.AddDate(0, 0, -1).Format(time.DateOnly))
+	c.Response().Header().Set("Content-Disposition", "attachment; filename="+filename)
+	c.Response().Header().Set("Content-Type", "text/csv")
+	return c.String(http.StatusOK, text)
+}
+
+// HandlePriceTrend shows every price paid for a food, loaded into the reports page
+func (h *ReportHandler) HandlePriceTrend(c echo.Context) error {
+	if c.QueryParam("food_id") == "" {
+		return c.HTML(http.StatusOK, "")
+	}
+	foodId, err := strconv.Atoi(c.QueryParam("food_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid food ID")
+	}
+
+	trend, err := h.reportService.GetPriceTrend(c.Request().Context(), foodId)
+	if err != nil {
+		log.Printf("Error getting price trend: %v", err)
+		return err
+	}
+	return pages.PriceTrend(trend).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *ReportHandler) HandleExportPriceTrend(c echo.Context) error {
+	foodId, err := strconv.Atoi(c.Param("foodId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid food ID")
+	}
+
+	trend, err := h.reportService.GetPriceTrend(c.Request().Context(), foodId)
+	if err != nil {
+		log.Printf("Error getting price trend: %v", err)
+		return err
+	}
+	text, err := utils.BuildPriceTrendCSV(trend)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=prices-%d.csv", foodId))
+	c.Response().Header().Set("Content-Type", "text/csv")
+	return c.String(http.StatusOK, text)
+}
+
+// parseReportRange reads the period and date range of a report, by default the last three months or the
+// last eight weeks up to today
+func parseReportRange(c echo.Context) (string, time.Time, time.Time, error) {
+	period := c.QueryParam("period")
+	if period == "" {
+		period = models.ReportMonthly
+	}
+	if period != models.ReportWeekly && period != models.ReportMonthly {
+		return "", time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid period")
+	}
+
+	timeZone := utils.GetTimezone(c)
+	now := time.Now().In(timeZone)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, timeZone)
+	if value := c.QueryParam("to"); value != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, value, timeZone)
+		if err != nil {
+			return "", time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid end date")
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, -2, 0)
+	if period == models.ReportWeekly {
+		from = to.AddDate(0, 0, -7*7)
+	}
+	if value := c.QueryParam("from"); value != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, value, timeZone)
+		if err != nil {
+			return "", time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid start date")
+		}
+		from = parsed
+	}
+	if to.Before(from) {
+		return "", time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "The end date must be after the start date")
+	}
+	if to.After(from.AddDate(3, 0, 0)) {
+		return "", time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Reports cover at most three years")
+	}
+	return period, from, to, nil
+}
@@ -9,6 +9,7 @@ import (
 	"mealplanner/internal/utils"
 	"mealplanner/internal/views/components"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -19,6 +20,10 @@ import (
 type SchedulesHandler struct {
 	scheduleService *services.ScheduleService
 	foodService     *services.FoodService
+	mealSlotService *services.MealSlotService
+	varietyService  *services.VarietyService
+	memberService   *services.MemberService
+	dietService     *services.DietService
 }
 
 func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
@@ -27,6 +32,7 @@ func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
 		Time     string `form:"time"`
 		FoodID   string `form:"food_id"`
 		Servings string `form:"servings"`
+		MealSlot string `form:"meal_slot_id"`
 	}
 
 	if err := c.Bind(&input); err != nil {
@@ -45,6 +51,13 @@ func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
 	} else {
 		selectedFoodId = foodId
 	}
+	mealSlotId, mealSlot, err := h.parseMealSlot(c, input.MealSlot)
+	if err != nil {
+		errors["meal_slot"] = "Please select a valid meal slot"
+	}
+	if input.Time == "" && mealSlot != nil {
+		input.Time = mealSlot.DefaultTime
+	}
 	var dateOfSchedule time.Time
 	var timeOfSchedule time.Time
 	if input.Time == "" {
@@ -60,7 +73,16 @@ func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
 			return err
 		}
 	}
+	members, err := h.memberService.GetMembers(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	attendeeIds := parseAttendeeIds(c)
+	// Without servings typed in they're counted from who is eating, households without members default to 1
 	var servings float64 = 1.0
+	if len(members) > 0 {
+		servings = 0
+	}
 	if input.Servings != "" {
 		parsedServings, err := strconv.ParseFloat(input.Servings, 64)
 		if err != nil || parsedServings <= 0 {
@@ -68,6 +90,12 @@ func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
 		} else {
 			servings = parsedServings
 		}
+	} else if servings == 0 && len(attendeeIds) == 0 {
+		errors["servings"] = "Set the servings or choose who is eating"
+	}
+	dietWarning, err := h.checkDiets(c, foodId, attendeeIds, errors)
+	if err != nil {
+		return err
 	}
 
 	if len(errors) > 0 {
@@ -75,6 +103,7 @@ func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
 		date, _ := time.Parse("2006-01-02", input.Date)
 		// TODO: Get foods
 		// foods, _ := h.foodService.ListFoods()
+		mealSlots, _ := h.mealSlotService.GetMealSlots(c.Request().Context())
 
 		props := &utils.ModalProps{
 			Date:   date,
@@ -83,8 +112,13 @@ func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
 			FoodChosen: models.Food{
 				ID: selectedFoodId,
 			},
-			Servings:   servings,
-			TimeChosen: timeOfSchedule,
+			Servings:       servings,
+			ServingsManual: servings > 0,
+			TimeChosen:     timeOfSchedule,
+			MealSlots:      mealSlots,
+			MealSlotID:     mealSlotId,
+			Members:        members,
+			AttendeeIDs:    attendeeIds,
 		}
 
 		c.Response().Writer.WriteHeader(http.StatusBadRequest)
@@ -96,7 +130,31 @@ func (h *SchedulesHandler) HandleAddSchedule(c echo.Context) error {
 	// store the time in UTC
 	scheduleAt = scheduleAt.UTC()
 
-	_, err = h.scheduleService.CreateSchedule(c.Request().Context(), foodId, servings, scheduleAt, userTimeZone)
+	if warnings := h.scheduleWarnings(c, foodId, scheduleAt, 0, dietWarning); len(warnings) > 0 {
+		// Show the warnings, submitting again adds the meal anyway
+		food, err := h.foodService.GetFoodDetails(c.Request().Context(), strconv.Itoa(foodId), 0)
+		if err != nil {
+			return c.String(500, "Error getting food")
+		}
+		mealSlots, _ := h.mealSlotService.GetMealSlots(c.Request().Context())
+		props := &utils.ModalProps{
+			Date:           dateOfSchedule,
+			Foods:          []*models.Food{},
+			Errors:         map[string]string{},
+			Warnings:       warnings,
+			FoodChosen:     *food,
+			Servings:       servings,
+			ServingsManual: servings > 0,
+			TimeChosen:     timeOfSchedule,
+			MealSlots:      mealSlots,
+			MealSlotID:     mealSlotId,
+			Members:        members,
+			AttendeeIDs:    attendeeIds,
+		}
+		return components.CreateScheduleModal(props).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	_, err = h.scheduleService.CreateSchedule(c.Request().Context(), foodId, servings, scheduleAt, mealSlotId, attendeeIds, userTimeZone)
 	if err != nil {
 		log.Default().Printf("Error creating schedule: %s", err)
 		return err
@@ -123,6 +181,7 @@ func (h *SchedulesHandler) HandleEditScheduleModal(c echo.Context) error {
 			Time     string `form:"time"`
 			FoodID   string `form:"food_id"`
 			Servings string `form:"servings"`
+			MealSlot string `form:"meal_slot_id"`
 		}
 
 		if err := c.Bind(&input); err != nil {
@@ -141,6 +200,14 @@ func (h *SchedulesHandler) HandleEditScheduleModal(c echo.Context) error {
 			selectedFoodId = foodId
 		}
 
+		mealSlotId, mealSlot, err := h.parseMealSlot(c, input.MealSlot)
+		if err != nil {
+			errors["meal_slot"] = "Please select a valid meal slot"
+		}
+		if input.Time == "" && mealSlot != nil {
+			input.Time = mealSlot.DefaultTime
+		}
+
 		var dateOfSchedule time.Time
 		var timeOfSchedule time.Time
 		if input.Time == "" {
@@ -157,7 +224,16 @@ func (h *SchedulesHandler) HandleEditScheduleModal(c echo.Context) error {
 			}
 		}
 
+		members, err := h.memberService.GetMembers(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		attendeeIds := parseAttendeeIds(c)
+		// Without servings typed in they're counted from who is eating, households without members default to 1
 		var servings float64 = 1.0
+		if len(members) > 0 {
+			servings = 0
+		}
 		if input.Servings != "" {
 			parsedServings, err := strconv.ParseFloat(input.Servings, 64)
 			if err != nil || parsedServings <= 0 {
@@ -165,22 +241,34 @@ func (h *SchedulesHandler) HandleEditScheduleModal(c echo.Context) error {
 			} else {
 				servings = parsedServings
 			}
+		} else if servings == 0 && len(attendeeIds) == 0 {
+			errors["servings"] = "Set the servings or choose who is eating"
+		}
+		dietWarning, err := h.checkDiets(c, foodId, attendeeIds, errors)
+		if err != nil {
+			return err
 		}
 
 		if len(errors) > 0 {
 			// Re-render form with errors
 			date, _ := time.Parse("2006-01-02", input.Date)
 			foods, _ := h.foodService.GetFoods(c.Request().Context(), "")
+			mealSlots, _ := h.mealSlotService.GetMealSlots(c.Request().Context())
 
 			props := &utils.ModalProps{
-				Date:       date,
-				Foods:      foods,
-				Errors:     errors,
-				FoodChosen: models.Food{ID: selectedFoodId},
-				Servings:   servings,
-				TimeChosen: timeOfSchedule,
-				IsEdit:     true,
-				ScheduleID: idNum,
+				Date:           date,
+				Foods:          foods,
+				Errors:         errors,
+				FoodChosen:     models.Food{ID: selectedFoodId},
+				Servings:       servings,
+				ServingsManual: servings > 0,
+				TimeChosen:     timeOfSchedule,
+				IsEdit:         true,
+				ScheduleID:     idNum,
+				MealSlots:      mealSlots,
+				MealSlotID:     mealSlotId,
+				Members:        members,
+				AttendeeIDs:    attendeeIds,
 			}
 
 			c.Response().Writer.WriteHeader(http.StatusBadRequest)
@@ -192,7 +280,34 @@ func (h *SchedulesHandler) HandleEditScheduleModal(c echo.Context) error {
 		// Store the time in UTC
 		scheduleAt = scheduleAt.UTC()
 
-		_, err = h.scheduleService.UpdateSchedule(c.Request().Context(), idNum, foodId, servings, scheduleAt, userTimeZone)
+		if warnings := h.scheduleWarnings(c, foodId, scheduleAt, idNum, dietWarning); len(warnings) > 0 {
+			// Show the warnings, submitting again saves the change anyway
+			food, err := h.foodService.GetFoodDetails(c.Request().Context(), strconv.Itoa(foodId), 0)
+			if err != nil {
+				return c.String(500, "Error getting food")
+			}
+			foods, _ := h.foodService.GetFoods(c.Request().Context(), "")
+			mealSlots, _ := h.mealSlotService.GetMealSlots(c.Request().Context())
+			props := &utils.ModalProps{
+				Date:           dateOfSchedule,
+				Foods:          foods,
+				Errors:         map[string]string{},
+				Warnings:       warnings,
+				FoodChosen:     *food,
+				Servings:       servings,
+				ServingsManual: servings > 0,
+				TimeChosen:     timeOfSchedule,
+				IsEdit:         true,
+				ScheduleID:     idNum,
+				MealSlots:      mealSlots,
+				MealSlotID:     mealSlotId,
+				Members:        members,
+				AttendeeIDs:    attendeeIds,
+			}
+			return components.CreateScheduleModal(props).Render(c.Request().Context(), c.Response().Writer)
+		}
+
+		_, err = h.scheduleService.UpdateSchedule(c.Request().Context(), idNum, foodId, servings, scheduleAt, mealSlotId, attendeeIds, userTimeZone)
 		if err != nil {
 			log.Default().Printf("Error updating schedule: %s", err)
 			return err
@@ -216,19 +331,34 @@ func (h *SchedulesHandler) HandleEditScheduleModal(c echo.Context) error {
 		return c.String(500, "Error searching foods")
 	}
 
+	mealSlots, err := h.mealSlotService.GetMealSlots(c.Request().Context())
+	if err != nil {
+		return c.String(500, "Error getting meal slots")
+	}
+
+	members, err := h.memberService.GetMembers(c.Request().Context())
+	if err != nil {
+		return c.String(500, "Error getting household members")
+	}
+
 	userTimeZone := utils.GetTimezone(c)
 	localTime := schedule.ScheduledAt.In(userTimeZone)
 
 	props := &utils.ModalProps{
-		Date:       localTime,
-		TimeChosen: localTime,
-		FoodChosen: models.Food{ID: schedule.FoodID},
-		Foods:      foods,
-		Servings:   schedule.Servings,
-		Errors:     map[string]string{},
-		IsEdit:     true,
-		ScheduleID: idNum,
-		Schedule:   schedule,
+		Date:           localTime,
+		TimeChosen:     localTime,
+		FoodChosen:     models.Food{ID: schedule.FoodID},
+		Foods:          foods,
+		Servings:       schedule.Servings,
+		ServingsManual: schedule.ServingsManual,
+		Errors:         map[string]string{},
+		IsEdit:         true,
+		ScheduleID:     idNum,
+		Schedule:       schedule,
+		MealSlots:      mealSlots,
+		MealSlotID:     schedule.MealSlotID,
+		Members:        members,
+		AttendeeIDs:    schedule.AttendeeIDs,
 	}
 
 	return components.CreateScheduleModal(props).Render(c.Request().Context(), c.Response())
@@ -274,6 +404,106 @@ func (h *SchedulesHandler) HandleDeleteScheduleByDateRange(c echo.Context) error
 	return c.NoContent(http.StatusNoContent)
 }
 
+func (h *SchedulesHandler) HandleMoveSchedules(c echo.Context) error {
+	ids, date, timeOfDay, err := parseBulkScheduleTarget(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.scheduleService.MoveSchedules(c.Request().Context(), ids, date, timeOfDay, utils.GetTimezone(c))
+	if err != nil {
+		log.Default().Printf("Error moving schedules: %s", err)
+		return err
+	}
+
+	triggerData := fmt.Sprintf(`{"refreshCalendar": {"date": "%s"}, "closeModal": {}}`, date.Format("2006-01-02"))
+	c.Response().Header().Set("HX-Trigger", triggerData)
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *SchedulesHandler) HandleCopySchedules(c echo.Context) error {
+	ids, date, timeOfDay, err := parseBulkScheduleTarget(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.scheduleService.CopySchedules(c.Request().Context(), ids, date, timeOfDay, utils.GetTimezone(c))
+	if err != nil {
+		log.Default().Printf("Error copying schedules: %s", err)
+		return err
+	}
+
+	triggerData := fmt.Sprintf(`{"refreshCalendar": {"date": "%s"}, "closeModal": {}}`, date.Format("2006-01-02"))
+	c.Response().Header().Set("HX-Trigger", triggerData)
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleShiftSchedules moves every meal between start and end (inclusive dates) by a number of days
+func (h *SchedulesHandler) HandleShiftSchedules(c echo.Context) error {
+	timeZone := utils.GetTimezone(c)
+	start, err := time.ParseInLocation("2006-01-02", c.FormValue("start"), timeZone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid start date")
+	}
+	end, err := time.ParseInLocation("2006-01-02", c.FormValue("end"), timeZone)
+	if err != nil || end.Before(start) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid end date")
+	}
+	days, err := strconv.Atoi(c.FormValue("days"))
+	if err != nil || days == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Days must be a non-zero whole number")
+	}
+
+	_, err = h.scheduleService.ShiftSchedulesInRange(c.Request().Context(), start, end.AddDate(0, 0, 1), days, timeZone)
+	if err != nil {
+		log.Default().Printf("Error shifting schedules: %s", err)
+		return err
+	}
+
+	triggerData := fmt.Sprintf(`{"refreshCalendar": {"date": "%s"}, "closeModal": {}}`, start.AddDate(0, 0, days).Format("2006-01-02"))
+	c.Response().Header().Set("HX-Trigger", triggerData)
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *SchedulesHandler) HandleSwapDays(c echo.Context) error {
+	timeZone := utils.GetTimezone(c)
+	dayA, err := time.ParseInLocation("2006-01-02", c.FormValue("date"), timeZone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date")
+	}
+	dayB, err := time.ParseInLocation("2006-01-02", c.FormValue("swap_date"), timeZone)
+	if err != nil || dayB.Equal(dayA) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Please choose a different day to swap with")
+	}
+
+	err = h.scheduleService.SwapDays(c.Request().Context(), dayA, dayB, timeZone)
+	if err != nil {
+		log.Default().Printf("Error swapping days: %s", err)
+		return err
+	}
+
+	triggerData := fmt.Sprintf(`{"refreshCalendar": {"date": "%s"}, "closeModal": {}}`, dayA.Format("2006-01-02"))
+	c.Response().Header().Set("HX-Trigger", triggerData)
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleBulkScheduleModal shows the move, copy, shift and swap actions for a day
+func (h *SchedulesHandler) HandleBulkScheduleModal(c echo.Context) error {
+	timeZone := utils.GetTimezone(c)
+	date, err := time.ParseInLocation("2006-01-02", c.QueryParam("date"), timeZone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date")
+	}
+
+	end := date.AddDate(0, 0, 1)
+	schedules, err := h.scheduleService.GetSchedulesForRange(c.Request().Context(), &date, &end, timeZone)
+	if err != nil {
+		return err
+	}
+
+	return components.BulkScheduleModal(date, utils.FilterSchedulesForDay(&date, schedules)).Render(c.Request().Context(), c.Response())
+}
+
 func (h *SchedulesHandler) HandleScheduleModal(c echo.Context) error {
 	dateStr := c.QueryParam("date")
 	date, err := time.Parse("2006-01-02", dateStr)
@@ -286,16 +516,185 @@ func (h *SchedulesHandler) HandleScheduleModal(c echo.Context) error {
 	if err != nil {
 		return c.String(500, "Error searching foods")
 	}
-	return components.CreateScheduleModal(&utils.ModalProps{
-		Date:   date,
-		Foods:  foods,
-		Errors: map[string]string{},
-	}).Render(c.Request().Context(), c.Response())
+
+	mealSlots, err := h.mealSlotService.GetMealSlots(c.Request().Context())
+	if err != nil {
+		return c.String(500, "Error getting meal slots")
+	}
+
+	members, err := h.memberService.GetMembers(c.Request().Context())
+	if err != nil {
+		return c.String(500, "Error getting household members")
+	}
+	// Preselect the slot when the modal is opened from a slot row in the day view
+	mealSlotId, _ := strconv.Atoi(c.QueryParam("meal_slot_id"))
+	var slotTime time.Time
+	for _, slot := range mealSlots {
+		if slot.ID == mealSlotId {
+			slotTime = slot.DefaultTimeOn(date)
+		}
+	}
+
+	// Everyone but guests and who is away at the meal eats by default. Without a slot the time isn't known yet,
+	// so only who is away all day is left out, saving the meal leaves out anyone away at the time chosen.
+	timeZone := utils.GetTimezone(c)
+	var awayIds []int
+	if !slotTime.IsZero() {
+		at := time.Date(slotTime.Year(), slotTime.Month(), slotTime.Day(), slotTime.Hour(), slotTime.Minute(), 0, 0, timeZone)
+		awayIds, err = h.memberService.GetAwayMemberIds(c.Request().Context(), at)
+	} else {
+		dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, timeZone)
+		awayIds, err = h.memberService.GetAwayMemberIds(c.Request().Context(), dayStart)
+		if err == nil && len(awayIds) > 0 {
+			var awayAtEnd []int
+			awayAtEnd, err = h.memberService.GetAwayMemberIds(c.Request().Context(), dayStart.AddDate(0, 0, 1).Add(-time.Minute))
+			awayIds = slices.DeleteFunc(awayIds, func(id int) bool {
+				return !slices.Contains(awayAtEnd, id)
+			})
+		}
+	}
+	if err != nil {
+		return c.String(500, "Error getting household members")
+	}
+	attendeeIds := []int{}
+	for _, member := range members {
+		if !member.IsGuest && !slices.Contains(awayIds, member.ID) {
+			attendeeIds = append(attendeeIds, member.ID)
+		}
+	}
+
+	props := &utils.ModalProps{
+		Date:           date,
+		Foods:          foods,
+		Errors:         map[string]string{},
+		MealSlots:      mealSlots,
+		MealSlotID:     mealSlotId,
+		Members:        members,
+		AttendeeIDs:    attendeeIds,
+		ServingsManual: len(members) == 0,
+	}
+	props.TimeChosen = slotTime
+	// A recipe scaled to an ingredient comes with its food and servings
+	if foodId := c.QueryParam("food_id"); foodId != "" {
+		food, err := h.foodService.GetFoodDetails(c.Request().Context(), foodId, 1)
+		if err != nil {
+			return c.String(500, "Error getting food")
+		}
+		props.FoodChosen = *food
+		if servings, err := strconv.ParseFloat(c.QueryParam("servings"), 64); err == nil && servings > 0 {
+			props.Servings = servings
+			props.ServingsManual = true
+		}
+	}
+	return components.CreateScheduleModal(props).Render(c.Request().Context(), c.Response())
+}
+
+// parseBulkScheduleTarget reads the selected schedule ids, the target date and the optional target time
+func parseBulkScheduleTarget(c echo.Context) ([]int, time.Time, string, error) {
+	if err := c.Request().ParseForm(); err != nil {
+		return nil, time.Time{}, "", err
+	}
+
+	idStrs := c.Request().Form["schedule_ids"]
+	if len(idStrs) == 0 {
+		return nil, time.Time{}, "", echo.NewHTTPError(http.StatusBadRequest, "Please select at least one meal")
+	}
+	ids := make([]int, 0, len(idStrs))
+	for _, idStr := range idStrs {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil, time.Time{}, "", echo.NewHTTPError(http.StatusBadRequest, "One or more of the provided ids are not valid")
+		}
+		ids = append(ids, id)
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", c.FormValue("target_date"), utils.GetTimezone(c))
+	if err != nil {
+		return nil, time.Time{}, "", echo.NewHTTPError(http.StatusBadRequest, "Invalid target date")
+	}
+
+	timeOfDay := c.FormValue("target_time")
+	if timeOfDay != "" {
+		if _, err := time.Parse("15:04", timeOfDay); err != nil {
+			return nil, time.Time{}, "", echo.NewHTTPError(http.StatusBadRequest, "Invalid target time")
+		}
+	}
+	return ids, date, timeOfDay, nil
+}
+
+// parseMealSlot resolves the submitted meal slot id, an empty value means the schedule has no slot
+func (h *SchedulesHandler) parseMealSlot(c echo.Context, value string) (int, *models.MealSlot, error) {
+	if value == "" {
+		return 0, nil, nil
+	}
+	mealSlotId, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, nil, err
+	}
+	mealSlot, err := h.mealSlotService.GetMealSlotById(c.Request().Context(), mealSlotId)
+	if err != nil {
+		return 0, nil, err
+	}
+	return mealSlotId, mealSlot, nil
+}
+
+// parseAttendeeIds reads the checked attendees, ignoring anything that isn't an id
+func parseAttendeeIds(c echo.Context) []int {
+	form, _ := c.FormParams()
+	attendeeIds := []int{}
+	for _, value := range form["attendee_ids"] {
+		if id, err := strconv.Atoi(value); err == nil {
+			attendeeIds = append(attendeeIds, id)
+		}
+	}
+	return attendeeIds
+}
+
+// checkDiets compares the food with the restrictions of who is eating. An allergy is added to the form
+// errors so the meal can't be scheduled, a broken diet is returned to be shown with the other warnings.
+func (h *SchedulesHandler) checkDiets(c echo.Context, foodId int, attendeeIds []int, errors map[string]string) (string, error) {
+	if errors["food"] != "" {
+		return "", nil
+	}
+	allergies, diets, err := h.dietService.CheckAttendees(c.Request().Context(), foodId, attendeeIds)
+	if err != nil {
+		log.Default().Printf("Error checking diets: %s", err)
+		return "", err
+	}
+	if allergies != "" {
+		errors["food"] = allergies
+	}
+	return diets, nil
+}
+
+// scheduleWarnings runs the variety checks unless the form confirms the warnings were already seen.
+// The warnings are advisory, so a failed check is logged and treated as having none.
+// dietWarning comes from checkDiets and is shown first under the food.
+func (h *SchedulesHandler) scheduleWarnings(c echo.Context, foodId int, scheduledAt time.Time, excludeScheduleId int, dietWarning string) map[string]string {
+	if c.FormValue("confirm_warnings") == "true" {
+		return nil
+	}
+	warnings, err := h.varietyService.CheckSchedule(c.Request().Context(), foodId, scheduledAt, excludeScheduleId, utils.GetTimezone(c))
+	if err != nil {
+		log.Default().Printf("Error checking schedule variety: %s", err)
+		warnings = make(map[string]string)
+	}
+	if dietWarning != "" {
+		if warnings["food"] != "" {
+			dietWarning += ". " + warnings["food"]
+		}
+		warnings["food"] = dietWarning
+	}
+	return warnings
 }
 
-func NewSchedulesHandler(scheduleService *services.ScheduleService, foodService *services.FoodService) *SchedulesHandler {
+func NewSchedulesHandler(scheduleService *services.ScheduleService, foodService *services.FoodService, mealSlotService *services.MealSlotService, varietyService *services.VarietyService, memberService *services.MemberService, dietService *services.DietService) *SchedulesHandler {
 	return &SchedulesHandler{
 		scheduleService: scheduleService,
 		foodService:     foodService,
+		mealSlotService: mealSlotService,
+		varietyService:  varietyService,
+		memberService:   memberService,
+		dietService:     dietService,
 	}
 }
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"mealplanner/internal/models"
+	"mealplanner/internal/services"
+	"mealplanner/internal/utils"
+	"mealplanner/internal/views/layouts"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+type SettingsHandler struct {
+	settingsService *services.SettingsService
+	mealSlotService *services.MealSlotService
+	memberService   *services.MemberService
+}
+
+func NewSettingsHandler(settingsService *services.SettingsService, mealSlotService *services.MealSlotService, memberService *services.MemberService) *SettingsHandler {
+	return &SettingsHandler{
+		settingsService: settingsService,
+		mealSlotService: mealSlotService,
+		memberService:   memberService,
+	}
+}
+
+func (h *SettingsHandler) HandleSettingsPage(c echo.Context) error {
+	settings, err := h.settingsService.GetSettings(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting settings: %v", err)
+		return err
+	}
+
+	mealSlots, err := h.mealSlotService.GetMealSlots(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting meal slots: %v", err)
+		return err
+	}
+
+	members, err := h.memberService.GetMembers(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting household members: %v", err)
+		return err
+	}
+
+	away, err := h.memberService.GetAwayPeriods(c.Request().Context(), utils.GetTimezone(c))
+	if err != nil {
+		return err
+	}
+
+	page := pages.SettingsPage(settings, mealSlots, members, away, calendarFeedURL(c, settings))
+	// Check if this is an HTMX request
+	if c.Request().Header.Get("HX-Request") != "" {
+		// Return content only for HTMX
+		return page.Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	// Return full page with layout for direct navigation
+	return layouts.Base([]templ.Component{page}).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *SettingsHandler) HandleUpdateWeekStart(c echo.Context) error {
+	weekStart, err := strconv.Atoi(c.FormValue("week_start"))
+	if err != nil || weekStart < int(time.Sunday) || weekStart > int(time.Saturday) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid week start")
+	}
+
+	_, err = h.settingsService.UpdateWeekStart(c.Request().Context(), time.Weekday(weekStart))
+	if err != nil {
+		log.Printf("Error updating week start: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings,refreshCalendar")
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *SettingsHandler) HandleUpdateVarietySettings(c echo.Context) error {
+	limits := map[string]int{"variety_window_days": 0, "max_meals_per_day": 0, "max_daily_prep_minutes": 0}
+	for name := range limits {
+		value := c.FormValue(name)
+		if value == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Limits must be whole numbers, 0 turns a check off")
+		}
+		limits[name] = limit
+	}
+
+	_, err := h.settingsService.UpdateVarietySettings(
+		c.Request().Context(),
+		limits["variety_window_days"],
+		utils.ParseTags(c.FormValue("variety_tags")),
+		limits["max_meals_per_day"],
+		limits["max_daily_prep_minutes"],
+	)
+	if err != nil {
+		log.Printf("Error updating variety settings: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleUpdateBudget sets the weekly and monthly grocery budgets, empty or 0 for none
+func (h *SettingsHandler) HandleUpdateBudget(c echo.Context) error {
+	budgets := map[string]float64{"weekly_budget": 0, "monthly_budget": 0}
+	for name := range budgets {
+		value := c.FormValue(name)
+		if value == "" {
+			continue
+		}
+		budget, err := strconv.ParseFloat(value, 64)
+		if err != nil || budget < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Budgets must be positive amounts")
+		}
+		budgets[name] = budget
+	}
+
+	_, err := h.settingsService.UpdateBudget(c.Request().Context(), budgets["weekly_budget"], budgets["monthly_budget"])
+	if err != nil {
+		log.Printf("Error updating budget: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings")
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *SettingsHandler) HandleCreateMealSlot(c echo.Context) error {
+	name, defaultTime, sortOrder, err := parseMealSlotForm(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.mealSlotService.CreateMealSlot(c.Request().Context(), name, defaultTime, sortOrder)
+	if err != nil {
+		log.Printf("Error creating meal slot: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings,refreshCalendar")
+	return c.NoContent(http.StatusCreated)
+}
+
+func (h *SettingsHandler) HandleUpdateMealSlot(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid meal slot ID")
+	}
+
+	name, defaultTime, sortOrder, err := parseMealSlotForm(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.mealSlotService.UpdateMealSlot(c.Request().Context(), id, name, defaultTime, sortOrder)
+	if err != nil {
+		log.Printf("Error updating meal slot: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings,refreshCalendar")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleDeleteMealSlot removes a slot, schedules assigned to it keep their time but lose the slot
+func (h *SettingsHandler) HandleDeleteMealSlot(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid meal slot ID")
+	}
+
+	err = h.mealSlotService.DeleteMealSlot(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error deleting meal slot: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings,refreshCalendar")
+	return c.NoContent(http.StatusOK)
+}
+
+func parseMealSlotForm(c echo.Context) (string, time.Time, int, error) {
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return "", time.Time{}, 0, echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+
+	defaultTime, err := time.Parse("15:04", c.FormValue("default_time"))
+	if err != nil {
+		return "", time.Time{}, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid default time")
+	}
+
+	sortOrder := 0
+	if value := c.FormValue("sort_order"); value != "" {
+		sortOrder, err = strconv.Atoi(value)
+		if err != nil {
+			return "", time.Time{}, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid sort order")
+		}
+	}
+	return name, defaultTime, sortOrder, nil
+}
+
+func (h *SettingsHandler) HandleCreateMember(c echo.Context) error {
+	member, err := parseMemberForm(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.memberService.CreateMember(c.Request().Context(), member)
+	if err != nil {
+		log.Printf("Error creating household member: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings")
+	return c.NoContent(http.StatusCreated)
+}
+
+// HandleUpdateMember saves a member, upcoming meals they eat are recalculated with the new portion
+func (h *SettingsHandler) HandleUpdateMember(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid member ID")
+	}
+
+	member, err := parseMemberForm(c)
+	if err != nil {
+		return err
+	}
+	member.ID = id
+
+	_, err = h.memberService.UpdateMember(c.Request().Context(), member)
+	if err != nil {
+		log.Printf("Error updating household member: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings,refreshCalendar")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleMarkMemberAway marks a member away between two dates, both inclusive, and takes them off the meals in them
+func (h *SettingsHandler) HandleMarkMemberAway(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid member ID")
+	}
+
+	timeZone := utils.GetTimezone(c)
+	start, err := time.ParseInLocation("2006-01-02", c.FormValue("start"), timeZone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid start date")
+	}
+	end, err := time.ParseInLocation("2006-01-02", c.FormValue("end"), timeZone)
+	if err != nil || end.Before(start) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid end date")
+	}
+
+	changed, err := h.memberService.MarkAway(c.Request().Context(), id, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings,refreshCalendar")
+	return c.String(http.StatusOK, fmt.Sprintf("Removed from %d meals", changed))
+}
+
+// HandleCancelMemberAway removes an away period, the member is put back on the meals it took them off
+func (h *SettingsHandler) HandleCancelMemberAway(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid member ID")
+	}
+	awayId, err := strconv.Atoi(c.Param("awayId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid away period ID")
+	}
+
+	_, err = h.memberService.CancelAway(c.Request().Context(), id, awayId)
+	if err == services.ErrMemberAwayNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Away period not found")
+	}
+	if err != nil {
+		log.Printf("Error cancelling away period: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings,refreshCalendar")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleDeleteMember removes a member and the ratings they gave
+func (h *SettingsHandler) HandleDeleteMember(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid member ID")
+	}
+
+	err = h.memberService.DeleteMember(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error deleting household member: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings")
+	return c.NoContent(http.StatusOK)
+}
+
+func parseMemberForm(c echo.Context) (*models.HouseholdMember, error) {
+	member := &models.HouseholdMember{
+		Name:          strings.TrimSpace(c.FormValue("name")),
+		PortionFactor: 1,
+		IsGuest:       c.FormValue("is_guest") == "true",
+		Diet:          c.FormValue("diet"),
+		Allergens:     []string{},
+	}
+	if member.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+
+	if value := c.FormValue("portion_factor"); value != "" {
+		var err error
+		member.PortionFactor, err = strconv.ParseFloat(value, 64)
+		if err != nil || member.PortionFactor <= 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Portion must be a positive number")
+		}
+	}
+	if member.Diet != "" && !slices.Contains(models.Diets, member.Diet) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unknown diet")
+	}
+
+	form, _ := c.FormParams()
+	for _, allergen := range form["allergens"] {
+		if !slices.Contains(models.FoodLabels, allergen) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Unknown allergen")
+		}
+		member.Allergens = append(member.Allergens, allergen)
+	}
+	return member, nil
+}
+
+func (h *SettingsHandler) HandleRotateCalendarFeed(c echo.Context) error {
+	_, err := h.settingsService.RotateCalendarFeedToken(c.Request().Context())
+	if err != nil {
+		log.Printf("Error rotating calendar feed token: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings")
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *SettingsHandler) HandleRevokeCalendarFeed(c echo.Context) error {
+	_, err := h.settingsService.RevokeCalendarFeedToken(c.Request().Context())
+	if err != nil {
+		log.Printf("Error revoking calendar feed token: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSettings")
+	return c.NoContent(http.StatusOK)
+}
+
+// calendarFeedURL builds the absolute subscription URL, empty when the feed is disabled
+func calendarFeedURL(c echo.Context, settings *models.HouseholdSettings) string {
+	if !settings.CalendarFeedEnabled() {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s/feeds/%s/meals.ics", c.Scheme(), c.Request().Host, settings.CalendarFeedToken)
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"mealplanner/internal/models"
 	"mealplanner/internal/services"
@@ -9,7 +11,9 @@ import (
 	"mealplanner/internal/views/layouts"
 	"mealplanner/internal/views/pages"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/a-h/templ"
@@ -20,23 +24,30 @@ type ShoppingListHandler struct {
 	shoppingService *services.ShoppingService
 	scheduleService *services.ScheduleService
 	foodService     *services.FoodService
+	mealSlotService *services.MealSlotService
+	storeService    *services.StoreService
 }
 
 func NewShoppingListHandler(
 	shoppingService *services.ShoppingService,
 	scheduleService *services.ScheduleService,
 	foodService *services.FoodService,
+	mealSlotService *services.MealSlotService,
+	storeService *services.StoreService,
 ) *ShoppingListHandler {
 	return &ShoppingListHandler{
 		shoppingService: shoppingService,
 		scheduleService: scheduleService,
 		foodService:     foodService,
+		mealSlotService: mealSlotService,
+		storeService:    storeService,
 	}
 }
 
 // Page handlers
 func (h *ShoppingListHandler) HandleShoppingListsPage(c echo.Context) error {
-	lists, err := h.shoppingService.GetShoppingLists(c.Request().Context())
+	archived := c.QueryParam("archived") == "true"
+	lists, err := h.shoppingService.GetShoppingLists(c.Request().Context(), archived)
 	if err != nil {
 		log.Printf("Error getting shopping lists: %v", err)
 		return err
@@ -45,11 +56,11 @@ func (h *ShoppingListHandler) HandleShoppingListsPage(c echo.Context) error {
 	// Check if this is an HTMX request
 	if c.Request().Header.Get("HX-Request") != "" {
 		// Return content only for HTMX
-		return pages.ShoppingListsPage(lists).Render(c.Request().Context(), c.Response().Writer)
+		return pages.ShoppingListsPage(lists, archived).Render(c.Request().Context(), c.Response().Writer)
 	}
 
 	// Return full page with layout for direct navigation
-	return layouts.Base([]templ.Component{pages.ShoppingListsPage(lists)}).Render(c.Request().Context(), c.Response().Writer)
+	return layouts.Base([]templ.Component{pages.ShoppingListsPage(lists, archived)}).Render(c.Request().Context(), c.Response().Writer)
 }
 
 func (h *ShoppingListHandler) HandleViewShoppingList(c echo.Context) error {
@@ -63,15 +74,109 @@ func (h *ShoppingListHandler) HandleViewShoppingList(c echo.Context) error {
 		log.Printf("Error getting shopping list: %v", err)
 		return err
 	}
+	stores, err := h.storeService.GetStores(c.Request().Context())
+	if err != nil {
+		return err
+	}
 
 	// Check if this is an HTMX request
 	if c.Request().Header.Get("HX-Request") != "" {
 		// Return partial for HTMX
-		return pages.ShoppingListDetailPage(list).Render(c.Request().Context(), c.Response().Writer)
+		return pages.ShoppingListDetailPage(list, stores).Render(c.Request().Context(), c.Response().Writer)
 	}
 
 	// Return full page for direct navigation
-	return layouts.Base([]templ.Component{pages.ShoppingListDetailPage(list)}).Render(c.Request().Context(), c.Response().Writer)
+	return layouts.Base([]templ.Component{pages.ShoppingListDetailPage(list, stores)}).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleShoppingListProgress renders how far through the list the shopping is, refreshed as items are ticked off
+func (h *ShoppingListHandler) HandleShoppingListProgress(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	list, err := h.shoppingService.GetShoppingListById(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error getting shopping list: %v", err)
+		return err
+	}
+	return components.ShoppingListProgress(list.Progress).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleShoppingListDrift shows the meals and recipes on a list that have changed since they were added
+func (h *ShoppingListHandler) HandleShoppingListDrift(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	drifts, err := h.shoppingService.GetShoppingListDrift(c.Request().Context(), id, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error checking shopping list for changes: %v", err)
+		return err
+	}
+	return components.ShoppingListDrift(id, drifts).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleReconcileShoppingList updates the list for the meals and recipes that have changed
+func (h *ShoppingListHandler) HandleReconcileShoppingList(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	err = h.shoppingService.ReconcileShoppingList(c.Request().Context(), id, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error reconciling shopping list: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshShoppingListDetail")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleSetStore orders the list by the chosen store's layout
+func (h *ShoppingListHandler) HandleSetStore(c echo.Context) error {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid list ID")
+	}
+	storeId := 0
+	if value := c.FormValue("store_id"); value != "" {
+		storeId, err = strconv.Atoi(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid store")
+		}
+	}
+
+	err = h.storeService.SetShoppingListStore(c.Request().Context(), listId, storeId)
+	if err != nil {
+		log.Printf("Error setting shopping list store: %v", err)
+		return err
+	}
+
+	return h.returnUpdatedItems(c, listId)
+}
+
+// HandleSetSplit splits the list into a section per store, by preferred or cheapest store, or joins it back up
+func (h *ShoppingListHandler) HandleSetSplit(c echo.Context) error {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid list ID")
+	}
+	splitBy := c.FormValue("split_by")
+	if !slices.Contains([]string{models.SplitNone, models.SplitPreferred, models.SplitCheapest}, splitBy) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid split")
+	}
+
+	err = h.storeService.SetShoppingListSplit(c.Request().Context(), listId, splitBy)
+	if err != nil {
+		log.Printf("Error splitting shopping list: %v", err)
+		return err
+	}
+
+	return h.returnUpdatedItems(c, listId)
 }
 
 // Shopping list CRUD
@@ -89,8 +194,9 @@ func (h *ShoppingListHandler) HandleCreateShoppingListModal(c echo.Context) erro
 
 func (h *ShoppingListHandler) handleCreateShoppingList(c echo.Context) error {
 	var form struct {
-		Name  string `form:"name"`
-		Notes string `form:"notes"`
+		Name       string `form:"name"`
+		Notes      string `form:"notes"`
+		AddStaples bool   `form:"add_staples"`
 	}
 
 	if err := c.Bind(&form); err != nil {
@@ -114,16 +220,191 @@ func (h *ShoppingListHandler) handleCreateShoppingList(c echo.Context) error {
 	}
 
 	// Create list
-	_, err := h.shoppingService.CreateShoppingList(c.Request().Context(), form.Name, form.Notes)
+	list, err := h.shoppingService.CreateShoppingList(c.Request().Context(), form.Name, form.Notes)
 	if err != nil {
 		log.Printf("Error creating shopping list: %v", err)
 		return err
 	}
+
+	if form.AddStaples {
+		err = h.shoppingService.AddStaplesBelowPar(c.Request().Context(), list.ID)
+		if err != nil {
+			log.Printf("Error adding staples below par: %v", err)
+			return err
+		}
+	}
 	
 	c.Response().Header().Set("HX-Trigger", "refreshShoppingList,closeModal")
 	return c.NoContent(http.StatusOK)
 }
 
+// HandleEditShoppingListModal renames a list and edits its notes
+func (h *ShoppingListHandler) HandleEditShoppingListModal(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	if c.Request().Method == "PUT" {
+		var form struct {
+			Name  string `form:"name"`
+			Notes string `form:"notes"`
+		}
+		if err := c.Bind(&form); err != nil {
+			return err
+		}
+		form.Name = strings.TrimSpace(form.Name)
+		form.Notes = strings.TrimSpace(form.Notes)
+
+		if form.Name == "" {
+			props := &utils.ShoppingListFormProps{
+				ID:     id,
+				IsEdit: true,
+				Name:   form.Name,
+				Notes:  form.Notes,
+				Errors: map[string]string{"name": "Name is required"},
+			}
+			c.Response().WriteHeader(http.StatusBadRequest)
+			return components.CreateShoppingListModal(props).Render(c.Request().Context(), c.Response().Writer)
+		}
+
+		err = h.shoppingService.UpdateShoppingList(c.Request().Context(), id, form.Name, form.Notes)
+		if err != nil {
+			log.Printf("Error updating shopping list: %v", err)
+			return err
+		}
+
+		c.Response().Header().Set("HX-Trigger", "refreshShoppingListDetail,closeModal")
+		return c.NoContent(http.StatusOK)
+	}
+
+	// GET - show the modal filled in with the list
+	list, err := h.shoppingService.GetShoppingListById(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error getting shopping list: %v", err)
+		return err
+	}
+	props := &utils.ShoppingListFormProps{
+		ID:     list.ID,
+		IsEdit: true,
+		Name:   list.Name,
+		Notes:  list.Notes,
+		Errors: make(map[string]string),
+	}
+	return components.CreateShoppingListModal(props).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleDuplicateShoppingList copies a list's items into a new list and shows it
+func (h *ShoppingListHandler) HandleDuplicateShoppingList(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	list, err := h.shoppingService.DuplicateShoppingList(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error duplicating shopping list: %v", err)
+		return err
+	}
+	stores, err := h.storeService.GetStores(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("HX-Push-Url", fmt.Sprintf("/shopping-lists/%d", list.ID))
+	return pages.ShoppingListDetailPage(list, stores).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleArchiveShoppingList archives a list, or restores it when archived is false
+func (h *ShoppingListHandler) HandleArchiveShoppingList(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+	archived := c.FormValue("archived") != "false"
+
+	err = h.shoppingService.SetShoppingListArchived(c.Request().Context(), id, archived)
+	if err != nil {
+		log.Printf("Error archiving shopping list: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshShoppingList,refreshShoppingListDetail")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleCloseShoppingList ends a trip: anything not bought, or bought short, carries over to another active list
+// or a new one, and the list is archived
+func (h *ShoppingListHandler) HandleCloseShoppingList(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+	list, err := h.shoppingService.GetShoppingListById(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error getting shopping list: %v", err)
+		return err
+	}
+	if list.Archived() {
+		return echo.NewHTTPError(http.StatusBadRequest, "List is already closed")
+	}
+	active, err := h.shoppingService.GetShoppingLists(c.Request().Context(), false)
+	if err != nil {
+		log.Printf("Error getting shopping lists: %v", err)
+		return err
+	}
+	props := &utils.CloseShoppingListProps{
+		List:      list,
+		CarryOver: list.CarryOver(),
+		Errors:    make(map[string]string),
+	}
+	for _, target := range active {
+		if target.ID != id {
+			props.Targets = append(props.Targets, target)
+		}
+	}
+
+	if c.Request().Method != "POST" {
+		return components.CloseShoppingListModal(props).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	targetId := 0
+	if carryTo := c.FormValue("carry_to"); carryTo != "" {
+		targetId, err = strconv.Atoi(carryTo)
+		if err == nil && !slices.ContainsFunc(props.Targets, func(l *models.ShoppingList) bool { return l.ID == targetId }) {
+			err = fmt.Errorf("list %d is not active", targetId)
+		}
+		if err != nil {
+			props.Errors["carry_to"] = "Choose an active list, or a new one"
+			c.Response().WriteHeader(http.StatusBadRequest)
+			return components.CloseShoppingListModal(props).Render(c.Request().Context(), c.Response().Writer)
+		}
+	}
+
+	target, err := h.shoppingService.CloseShoppingList(c.Request().Context(), id, targetId)
+	if err != nil {
+		log.Printf("Error closing shopping list: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "closeModal")
+	if target == nil {
+		lists, err := h.shoppingService.GetShoppingLists(c.Request().Context(), false)
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set("HX-Push-Url", "/shopping-lists")
+		return pages.ShoppingListsPage(lists, false).Render(c.Request().Context(), c.Response().Writer)
+	}
+
+	stores, err := h.storeService.GetStores(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set("HX-Push-Url", fmt.Sprintf("/shopping-lists/%d", target.ID))
+	return pages.ShoppingListDetailPage(target, stores).Render(c.Request().Context(), c.Response().Writer)
+}
+
 func (h *ShoppingListHandler) HandleDeleteShoppingList(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -164,10 +445,17 @@ func (h *ShoppingListHandler) HandleAddItemsModal(c echo.Context) error {
 		schedules = []*models.Schedule{} // Continue with empty schedules
 	}
 
+	mealSlots, err := h.mealSlotService.GetMealSlots(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting meal slots: %v", err)
+		mealSlots = []*models.MealSlot{}
+	}
+
 	props := &utils.AddItemsModalProps{
 		ListID:    listId,
 		Foods:     foods,
 		Schedules: schedules,
+		MealSlots: mealSlots,
 		Errors:    make(map[string]string),
 	}
 
@@ -233,6 +521,61 @@ func (h *ShoppingListHandler) HandleAddManualItem(c echo.Context) error {
 }
 
 // Recipe addition
+// HandleAddFreeTextItem adds something that isn't a food, like paper towels, with an optional quantity and unit
+func (h *ShoppingListHandler) HandleAddFreeTextItem(c echo.Context) error {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid list ID")
+	}
+
+	var form struct {
+		Name     string  `form:"name"`
+		Quantity float64 `form:"quantity"`
+		Unit     string  `form:"unit"`
+		Category string  `form:"category"`
+		Notes    string  `form:"notes"`
+	}
+
+	if err := c.Bind(&form); err != nil {
+		return err
+	}
+	form.Name = strings.TrimSpace(form.Name)
+	form.Unit = strings.TrimSpace(form.Unit)
+
+	// Validate
+	errors := make(map[string]string)
+	if form.Name == "" {
+		errors["free_text_name"] = "Name is required"
+	}
+	if form.Quantity < 0 {
+		errors["free_text_quantity"] = "Quantity can't be negative"
+	}
+	if form.Category != "" && !slices.Contains(models.FoodCategories, form.Category) {
+		errors["free_text_category"] = "Unknown category"
+	}
+
+	if len(errors) > 0 {
+		return h.returnAddItemsModalWithErrors(c, listId, errors)
+	}
+
+	req := &models.AddFreeTextItemRequest{
+		Name:     form.Name,
+		Quantity: form.Quantity,
+		Unit:     form.Unit,
+		Category: form.Category,
+		Notes:    form.Notes,
+	}
+
+	err = h.shoppingService.AddFreeTextItem(c.Request().Context(), listId, req)
+	if err != nil {
+		log.Printf("Error adding free text item: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshShoppingListDetail,closeModal")
+	return c.NoContent(http.StatusOK)
+}
+
 func (h *ShoppingListHandler) HandleAddRecipe(c echo.Context) error {
 	listId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -240,8 +583,9 @@ func (h *ShoppingListHandler) HandleAddRecipe(c echo.Context) error {
 	}
 
 	var form struct {
-		RecipeID string  `form:"recipe_id"`
-		Servings float64 `form:"servings"`
+		RecipeID       string  `form:"recipe_id"`
+		Servings       float64 `form:"servings"`
+		IncludeStaples bool    `form:"include_staples"`
 	}
 
 	if err := c.Bind(&form); err != nil {
@@ -253,7 +597,9 @@ func (h *ShoppingListHandler) HandleAddRecipe(c echo.Context) error {
 	if form.RecipeID == "" {
 		errors["recipe_id"] = "Please select a recipe"
 	}
-	if form.Servings <= 0 {
+	// scaling to an ingredient derives the servings instead
+	scaleToIngredient := c.FormValue("limit_food_id") != ""
+	if form.Servings <= 0 && !scaleToIngredient {
 		errors["servings"] = "Servings must be greater than 0"
 	}
 
@@ -267,10 +613,37 @@ func (h *ShoppingListHandler) HandleAddRecipe(c echo.Context) error {
 		return h.returnAddItemsModalWithErrors(c, listId, errors)
 	}
 
+	if scaleToIngredient {
+		limit, limitErrors, err := parseIngredientLimit(c, h.foodService)
+		if err != nil {
+			log.Printf("Error getting ingredient to scale to: %v", err)
+			return err
+		}
+		if len(limitErrors) > 0 {
+			return h.returnAddItemsModalWithErrors(c, listId, limitErrors)
+		}
+		scaled, err := h.foodService.ScaleToIngredient(c.Request().Context(), recipeId, limit)
+		switch err {
+		case nil:
+			form.Servings = scaled.Servings
+		case services.ErrIngredientNotInRecipe:
+			errors["limit_food_id"] = "The recipe doesn't use this ingredient"
+		case services.ErrIncompatibleUnit:
+			errors["limit_unit"] = "The recipe measures this ingredient in a different kind of unit"
+		default:
+			log.Printf("Error scaling recipe: %v", err)
+			return err
+		}
+		if len(errors) > 0 {
+			return h.returnAddItemsModalWithErrors(c, listId, errors)
+		}
+	}
+
 	// Add recipe
 	req := &models.AddRecipeRequest{
-		RecipeID: recipeId,
-		Servings: form.Servings,
+		RecipeID:       recipeId,
+		Servings:       form.Servings,
+		IncludeStaples: form.IncludeStaples,
 	}
 
 	err = h.shoppingService.AddRecipe(c.Request().Context(), listId, req)
@@ -317,10 +690,15 @@ func (h *ShoppingListHandler) HandleAddSchedules(c echo.Context) error {
 
 	// Add schedules
 	req := &models.AddSchedulesRequest{
-		ScheduleIDs: scheduleIDs,
+		ScheduleIDs:    scheduleIDs,
+		IncludeStaples: c.FormValue("include_staples") == "true",
 	}
 	timeZone := utils.GetTimezone(c)
 	err = h.shoppingService.AddSchedules(c.Request().Context(), listId, req, timeZone)
+	if isSubstitutionUnitError(err) {
+		errors := map[string]string{"schedule_ids": substitutionUnitMessage}
+		return h.returnAddItemsModalWithErrors(c, listId, errors)
+	}
 	if err != nil {
 		log.Printf("Error adding schedules: %v", err)
 		return err
@@ -339,8 +717,10 @@ func (h *ShoppingListHandler) HandleAddDateRange(c echo.Context) error {
 	}
 
 	var form struct {
-		StartDate string `form:"start_date"`
-		EndDate   string `form:"end_date"`
+		StartDate      string `form:"start_date"`
+		EndDate        string `form:"end_date"`
+		IncludeStaples bool   `form:"include_staples"`
+		MealSlotIDs    []int  `form:"meal_slot_ids"`
 	}
 
 	if err := c.Bind(&form); err != nil {
@@ -372,12 +752,18 @@ func (h *ShoppingListHandler) HandleAddDateRange(c echo.Context) error {
 
 	// Add date range
 	req := &models.AddDateRangeRequest{
-		StartDate: startDate,
-		EndDate:   endDate,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		IncludeStaples: form.IncludeStaples,
+		MealSlotIDs:    form.MealSlotIDs,
 	}
 
 	timeZone := utils.GetTimezone(c)
 	err = h.shoppingService.AddDateRange(c.Request().Context(), listId, req, timeZone)
+	if isSubstitutionUnitError(err) {
+		errors["start_date"] = substitutionUnitMessage
+		return h.returnAddItemsModalWithErrors(c, listId, errors)
+	}
 	if err != nil {
 		log.Printf("Error adding date range: %v", err)
 		return err
@@ -388,6 +774,23 @@ func (h *ShoppingListHandler) HandleAddDateRange(c echo.Context) error {
 
 }
 
+// Staples top-up
+func (h *ShoppingListHandler) HandleAddStaples(c echo.Context) error {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid list ID")
+	}
+
+	err = h.shoppingService.AddStaplesBelowPar(c.Request().Context(), listId)
+	if err != nil {
+		log.Printf("Error adding staples below par: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshShoppingListDetail,closeModal")
+	return c.NoContent(http.StatusOK)
+}
+
 func (h *ShoppingListHandler) HandleUpdateItem(c echo.Context) error {
 	listId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -432,13 +835,14 @@ func (h *ShoppingListHandler) HandleMarkItemPurchased(c echo.Context) error {
 		Purchased      bool    `form:"purchased"`
 		ActualQuantity float64 `form:"actual_quantity"`
 		ActualPrice    float64 `form:"actual_price"`
+		StoreID        int     `form:"store_id"`
 	}
 
 	if err := c.Bind(&form); err != nil {
 		return err
 	}
 
-	err = h.shoppingService.MarkItemPurchased(c.Request().Context(), itemId, form.Purchased, form.ActualQuantity, form.ActualPrice)
+	err = h.shoppingService.MarkItemPurchased(c.Request().Context(), itemId, form.Purchased, form.ActualQuantity, form.ActualPrice, form.StoreID)
 	if err != nil {
 		log.Printf("Error marking item purchased: %v", err)
 		return err
@@ -467,6 +871,63 @@ func (h *ShoppingListHandler) HandleDeleteItem(c echo.Context) error {
 	return h.returnUpdatedItems(c, listId)
 }
 
+// HandleShoppingListItem shows how much each source adds to an item
+func (h *ShoppingListHandler) HandleShoppingListItem(c echo.Context) error {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid list ID")
+	}
+	itemId, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid item ID")
+	}
+
+	return h.returnItemModal(c, listId, itemId)
+}
+
+// HandleSetItemContribution changes how much one source adds to an item, leaving the rest of the source alone
+func (h *ShoppingListHandler) HandleSetItemContribution(c echo.Context) error {
+	listId, itemId, sourceId, err := parseItemContribution(c)
+	if err != nil {
+		return err
+	}
+	quantity, err := strconv.ParseFloat(c.FormValue("quantity"), 64)
+	if err != nil || quantity < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid quantity")
+	}
+
+	err = h.shoppingService.SetItemContribution(c.Request().Context(), listId, itemId, sourceId, quantity)
+	if err == services.ErrContributionNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Contribution not found")
+	}
+	if err != nil {
+		log.Printf("Error changing item contribution: %v", err)
+		return err
+	}
+
+	return h.returnItemModal(c, listId, itemId)
+}
+
+// HandleDeleteItemContribution takes one source off an item, unlike HandleDeleteItemsBySource which removes
+// everything the source added
+func (h *ShoppingListHandler) HandleDeleteItemContribution(c echo.Context) error {
+	listId, itemId, sourceId, err := parseItemContribution(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.shoppingService.SetItemContribution(c.Request().Context(), listId, itemId, sourceId, 0)
+	if err == services.ErrContributionNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, "Contribution not found")
+	}
+	if err != nil {
+		log.Printf("Error removing item contribution: %v", err)
+		return err
+	}
+
+	return h.returnItemModal(c, listId, itemId)
+}
+
 func (h *ShoppingListHandler) HandleDeleteItemsBySource(c echo.Context) error {
 	listId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -489,8 +950,12 @@ func (h *ShoppingListHandler) HandleDeleteItemsBySource(c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	stores, err := h.storeService.GetStores(c.Request().Context())
+	if err != nil {
+		return err
+	}
 
-	return pages.ShoppingListDetailPage(list).Render(c.Request().Context(), c.Response().Writer)
+	return pages.ShoppingListDetailPage(list, stores).Render(c.Request().Context(), c.Response().Writer)
 }
 
 // Export
@@ -512,6 +977,14 @@ func (h *ShoppingListHandler) HandleExportShoppingList(c echo.Context) error {
 }
 
 // Helper methods
+const substitutionUnitMessage = "A substitution on one of these meals can't be converted from the recipe's unit"
+
+// isSubstitutionUnitError reports whether adding meals failed on a substitution that doesn't fit the recipe,
+// which the user can fix, rather than on the database.
+func isSubstitutionUnitError(err error) bool {
+	return errors.Is(err, services.ErrSubstitutionUnit)
+}
+
 func (h *ShoppingListHandler) returnAddItemsModalWithErrors(c echo.Context, listId int, errors map[string]string) error {
 	// Re-fetch data for modal
 	foods, err := h.foodService.GetFoods(c.Request().Context(), "")
@@ -528,10 +1001,16 @@ func (h *ShoppingListHandler) returnAddItemsModalWithErrors(c echo.Context, list
 		schedules = []*models.Schedule{}
 	}
 
+	mealSlots, err := h.mealSlotService.GetMealSlots(c.Request().Context())
+	if err != nil {
+		mealSlots = []*models.MealSlot{}
+	}
+
 	props := &utils.AddItemsModalProps{
 		ListID:    listId,
 		Foods:     foods,
 		Schedules: schedules,
+		MealSlots: mealSlots,
 		Errors:    errors,
 	}
 
@@ -539,11 +1018,51 @@ func (h *ShoppingListHandler) returnAddItemsModalWithErrors(c echo.Context, list
 	return components.AddItemsModal(props).Render(c.Request().Context(), c.Response().Writer)
 }
 
+// returnItemModal renders an item's breakdown by source and refreshes the list behind it. An item that's no
+// longer on the list closes the modal.
+func (h *ShoppingListHandler) returnItemModal(c echo.Context, listId, itemId int) error {
+	item, err := h.shoppingService.GetShoppingListItem(c.Request().Context(), listId, itemId)
+	if err != nil {
+		log.Printf("Error getting shopping list item: %v", err)
+		return err
+	}
+
+	if c.Request().Method != "GET" {
+		if item == nil {
+			c.Response().Header().Set("HX-Trigger", "refreshShoppingListDetail,closeModal")
+			return c.NoContent(http.StatusOK)
+		}
+		c.Response().Header().Set("HX-Trigger", "refreshShoppingListDetail")
+	}
+	if item == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Item not found")
+	}
+	return components.ShoppingListItemModal(item).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// parseItemContribution reads the list, item and source of a contribution from the path
+func parseItemContribution(c echo.Context) (int, int, int, error) {
+	listId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid list ID")
+	}
+	itemId, err := strconv.Atoi(c.Param("itemId"))
+	if err != nil {
+		return 0, 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid item ID")
+	}
+	sourceId, err := strconv.Atoi(c.Param("sourceId"))
+	if err != nil {
+		return 0, 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid source ID")
+	}
+	return listId, itemId, sourceId, nil
+}
+
 func (h *ShoppingListHandler) returnUpdatedItems(c echo.Context, listId int) error {
 	list, err := h.shoppingService.GetShoppingListById(c.Request().Context(), listId)
 	if err != nil {
 		return err
 	}
 
-	return components.ShoppingListItems(list.Items).Render(c.Request().Context(), c.Response().Writer)
+	c.Response().Header().Set("HX-Trigger", "refreshShoppingListProgress")
+	return components.ShoppingListItems(list).Render(c.Request().Context(), c.Response().Writer)
 }
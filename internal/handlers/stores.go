@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"mealplanner/internal/models"
+	"mealplanner/internal/services"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type StoreHandler struct {
+	storeService *services.StoreService
+}
+
+func NewStoreHandler(storeService *services.StoreService) *StoreHandler {
+	return &StoreHandler{storeService: storeService}
+}
+
+// HandleStoreSettings renders the stores section of the settings page
+func (h *StoreHandler) HandleStoreSettings(c echo.Context) error {
+	stores, err := h.storeService.GetStores(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return pages.StoreSettings(stores).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *StoreHandler) HandleCreateStore(c echo.Context) error {
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+	aisles, err := parseAisles(c.FormValue("aisles"))
+	if err != nil {
+		return err
+	}
+
+	stores, err := h.storeService.GetStores(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	for _, store := range stores {
+		if strings.EqualFold(store.Name, name) {
+			return echo.NewHTTPError(http.StatusBadRequest, "A store with this name already exists")
+		}
+	}
+
+	err = h.storeService.CreateStore(c.Request().Context(), name, aisles)
+	if err != nil {
+		log.Printf("Error creating store: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshStores")
+	return c.NoContent(http.StatusCreated)
+}
+
+func (h *StoreHandler) HandleUpdateStore(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid store ID")
+	}
+	aisles, err := parseAisles(c.FormValue("aisles"))
+	if err != nil {
+		return err
+	}
+
+	err = h.storeService.SetAisles(c.Request().Context(), id, aisles)
+	if err != nil {
+		log.Printf("Error updating store aisles: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshStores")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleDeleteStore removes a store, lists ordered by it go back to the default order
+func (h *StoreHandler) HandleDeleteStore(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid store ID")
+	}
+
+	err = h.storeService.DeleteStore(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error deleting store: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshStores")
+	return c.NoContent(http.StatusOK)
+}
+
+// parseAisles reads a comma separated list of food categories in walking order
+func parseAisles(value string) ([]string, error) {
+	var aisles []string
+	for _, category := range strings.Split(value, ",") {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == "" {
+			continue
+		}
+		if !slices.Contains(models.FoodCategories, category) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Unknown aisle "+category+", use "+strings.Join(models.FoodCategories, ", "))
+		}
+		if slices.Contains(aisles, category) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Aisle "+category+" is listed twice")
+		}
+		aisles = append(aisles, category)
+	}
+	return aisles, nil
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"mealplanner/internal/models"
+	"mealplanner/internal/services"
+	"mealplanner/internal/utils"
+	"mealplanner/internal/views/components"
+	"mealplanner/internal/views/pages"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SubstitutionHandler struct {
+	substitutionService *services.SubstitutionService
+	foodService         *services.FoodService
+	memberService       *services.MemberService
+}
+
+func NewSubstitutionHandler(substitutionService *services.SubstitutionService, foodService *services.FoodService, memberService *services.MemberService) *SubstitutionHandler {
+	return &SubstitutionHandler{
+		substitutionService: substitutionService,
+		foodService:         foodService,
+		memberService:       memberService,
+	}
+}
+
+// HandleSubstitutionSettings renders the substitution rules section of the settings page
+func (h *SubstitutionHandler) HandleSubstitutionSettings(c echo.Context) error {
+	substitutions, err := h.substitutionService.GetSubstitutions(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	foods, err := h.foodService.GetFoods(c.Request().Context(), "")
+	if err != nil {
+		log.Printf("Error getting foods: %v", err)
+		return err
+	}
+	members, err := h.memberService.GetMembers(c.Request().Context())
+	if err != nil {
+		log.Printf("Error getting household members: %v", err)
+		return err
+	}
+	return pages.SubstitutionSettings(substitutions, foods, members).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *SubstitutionHandler) HandleCreateSubstitution(c echo.Context) error {
+	substitution, err := parseSubstitutionForm(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.substitutionService.CreateSubstitution(c.Request().Context(), substitution)
+	if err != nil {
+		log.Printf("Error creating substitution: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSubstitutions")
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *SubstitutionHandler) HandleDeleteSubstitution(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid substitution ID")
+	}
+
+	err = h.substitutionService.DeleteSubstitution(c.Request().Context(), id)
+	if err != nil {
+		log.Printf("Error deleting substitution: %v", err)
+		return err
+	}
+
+	c.Response().Header().Set("HX-Trigger", "refreshSubstitutions")
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleCookView shows a scheduled meal's ingredients scaled to its servings, with the substitutions swapped in
+func (h *SubstitutionHandler) HandleCookView(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid schedule ID")
+	}
+
+	view, err := h.substitutionService.GetCookView(c.Request().Context(), id, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error getting cook view: %v", err)
+		return c.String(500, "Error getting schedule")
+	}
+	return components.CookModal(view, "").Render(c.Request().Context(), c.Response().Writer)
+}
+
+// HandleApplySubstitutions saves the substitutions checked in the cook view and shows the result
+func (h *SubstitutionHandler) HandleApplySubstitutions(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid schedule ID")
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return err
+	}
+	var substitutionIds []int
+	for _, value := range form["substitution_ids"] {
+		if substitutionId, err := strconv.Atoi(value); err == nil {
+			substitutionIds = append(substitutionIds, substitutionId)
+		}
+	}
+
+	applyError := ""
+	err = h.substitutionService.SetScheduleSubstitutions(c.Request().Context(), id, substitutionIds)
+	switch err {
+	case nil:
+	case services.ErrConflictingSubstitutions:
+		applyError = "Pick one substitution per ingredient"
+	default:
+		log.Printf("Error applying substitutions: %v", err)
+		return err
+	}
+
+	view, err := h.substitutionService.GetCookView(c.Request().Context(), id, utils.GetTimezone(c))
+	if err != nil {
+		log.Printf("Error getting cook view: %v", err)
+		return err
+	}
+	if applyError != "" {
+		c.Response().Writer.WriteHeader(http.StatusBadRequest)
+	}
+	return components.CookModal(view, applyError).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// parseSubstitutionForm reads the replaced food and the substitute rows, rows without a food are skipped
+func parseSubstitutionForm(c echo.Context) (*models.Substitution, error) {
+	substitution := &models.Substitution{
+		Unit: c.FormValue("unit"),
+	}
+
+	var err error
+	substitution.FoodID, err = strconv.Atoi(c.FormValue("food_id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Choose the food to replace")
+	}
+	substitution.Quantity, err = strconv.ParseFloat(c.FormValue("quantity"), 64)
+	if err != nil || substitution.Quantity <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Quantity must be a positive number")
+	}
+	if utils.UnitTypeOf(substitution.Unit) == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid unit")
+	}
+	if value := c.FormValue("member_id"); value != "" {
+		substitution.MemberID, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid member")
+		}
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; form.Has(fmt.Sprintf("items[%d].food_id", i)); i++ {
+		foodValue := form.Get(fmt.Sprintf("items[%d].food_id", i))
+		if foodValue == "" {
+			continue
+		}
+		foodId, err := strconv.Atoi(foodValue)
+		if err != nil || foodId == substitution.FoodID {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Choose a different food to substitute with")
+		}
+		quantity, err := strconv.ParseFloat(form.Get(fmt.Sprintf("items[%d].quantity", i)), 64)
+		if err != nil || quantity <= 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Substitute quantities must be positive numbers")
+		}
+		unit := form.Get(fmt.Sprintf("items[%d].unit", i))
+		if utils.UnitTypeOf(unit) == "" {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid substitute unit")
+		}
+		substitution.Items = append(substitution.Items, &models.RecipeItem{
+			FoodID:   foodId,
+			Quantity: quantity,
+			Unit:     unit,
+		})
+	}
+	if len(substitution.Items) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Add at least one substitute")
+	}
+	return substitution, nil
+}
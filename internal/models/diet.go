@@ -0,0 +1,56 @@
+package models
+
+import "slices"
+
+// FoodLabels are what a food can be marked as containing, recipes carry the labels of their ingredients too
+var FoodLabels = []string{"meat", "fish", "shellfish", "dairy", "egg", "gluten", "nuts", "peanuts", "soy", "sesame", "honey"}
+
+// Diets lists the supported diets in display order
+var Diets = []string{"vegetarian", "pescatarian", "vegan"}
+
+// dietExclusions maps each diet to the labels it rules out
+var dietExclusions = map[string][]string{
+	"vegetarian":  {"meat", "fish", "shellfish"},
+	"pescatarian": {"meat"},
+	"vegan":       {"meat", "fish", "shellfish", "dairy", "egg", "honey"},
+}
+
+// SuitableDiets lists the diets a food with these labels fits
+func SuitableDiets(labels []string) []string {
+	suitable := []string{}
+	for _, diet := range Diets {
+		if len(conflictingLabels(labels, dietExclusions[diet])) == 0 {
+			suitable = append(suitable, diet)
+		}
+	}
+	return suitable
+}
+
+// DietConflict is why a meal doesn't suit one of the people eating it
+type DietConflict struct {
+	MemberName string
+	Labels     []string
+	Allergy    bool // false when it only breaks the member's diet
+}
+
+// Conflicts checks a food's labels against the member's allergens first, then their diet
+func (m *HouseholdMember) Conflicts(labels []string) []*DietConflict {
+	var conflicts []*DietConflict
+	if allergens := conflictingLabels(labels, m.Allergens); len(allergens) > 0 {
+		conflicts = append(conflicts, &DietConflict{MemberName: m.Name, Labels: allergens, Allergy: true})
+	}
+	if excluded := conflictingLabels(labels, dietExclusions[m.Diet]); len(excluded) > 0 {
+		conflicts = append(conflicts, &DietConflict{MemberName: m.Name, Labels: excluded})
+	}
+	return conflicts
+}
+
+func conflictingLabels(labels, excluded []string) []string {
+	var conflicting []string
+	for _, label := range labels {
+		if slices.Contains(excluded, label) {
+			conflicting = append(conflicting, label)
+		}
+	}
+	return conflicting
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
 type Food struct {
 	ID       int     `json:"id"`
 	Name     string  `json:"name"`
@@ -7,14 +13,138 @@ type Food struct {
 	BaseUnit string  `json:"baseUnit"`
 	Density  float64 `json:"density,omitempty"`
 	IsRecipe bool    `json:"isRecipe"`
+	IsStaple bool    `json:"isStaple"`
+	Category string  `json:"category,omitempty"` // one of FoodCategories, empty when uncategorised
 	Recipe   *Recipe `json:"recipe,omitempty"`
+
+	IsFavourite   bool      `json:"isFavourite"`
+	AverageRating float64   `json:"averageRating,omitempty"` // 0 when unrated
+	RatingCount   int       `json:"ratingCount,omitempty"`
+	LastCookedAt  time.Time `json:"lastCookedAt,omitempty"` // zero when never cooked
+	TimesCooked   int       `json:"timesCooked,omitempty"`
+
+	Labels          []string `json:"labels,omitempty"`          // set on the food itself
+	EffectiveLabels []string `json:"effectiveLabels,omitempty"` // including everything it's made from
+
+	Units    []*FoodUnit    `json:"units,omitempty"`    // units only this food is measured in
+	Packages []*FoodPackage `json:"packages,omitempty"` // sizes it's sold in
+
+	StoreIDs []int         `json:"storeIds,omitempty"` // stores it's usually bought from
+	Prices   []*StorePrice `json:"prices,omitempty"`   // the latest price paid at each store
+}
+
+// FoodUnit is a unit only one food is measured in, e.g. 1 can = 400 grams of chopped tomatoes
+type FoodUnit struct {
+	ID             int     `json:"id"`
+	FoodID         int     `json:"foodId"`
+	Name           string  `json:"name"`
+	Quantity       float64 `json:"quantity"` // how much of Unit one Name is
+	Unit           string  `json:"unit"`     // a standard unit of the food's type
+	IsPurchaseUnit bool    `json:"isPurchaseUnit,omitempty"`
+}
+
+// FoodPackage is a size a food is sold in, e.g. a 250 gram block of butter
+type FoodPackage struct {
+	ID     int     `json:"id"`
+	FoodID int     `json:"foodId"`
+	Size   float64 `json:"size"`
+	Unit   string  `json:"unit"`
+	Price  float64 `json:"price,omitempty"` // 0 when unknown
+}
+
+func (p *FoodPackage) String() string {
+	return fmt.Sprintf("%s %s", strconv.FormatFloat(p.Size, 'f', -1, 64), p.Unit)
+}
+
+// PackagePlan is how many of one package to buy to cover a shopping list item
+type PackagePlan struct {
+	Package *FoodPackage `json:"package"`
+	Count   int          `json:"count"`
+	Surplus float64      `json:"surplus"` // left over once the item is covered, in the item's unit
+}
+
+func (p *PackagePlan) String() string {
+	return fmt.Sprintf("%d × %s", p.Count, p.Package)
+}
+
+// Cost is what the packages come to, 0 when the package price is unknown
+func (p *PackagePlan) Cost() float64 {
+	return float64(p.Count) * p.Package.Price
+}
+
+// CustomUnit finds one of the food's own units by name, nil for standard units
+func (f *Food) CustomUnit(name string) *FoodUnit {
+	if f == nil {
+		return nil
+	}
+	for _, unit := range f.Units {
+		if unit.Name == name {
+			return unit
+		}
+	}
+	return nil
+}
+
+// PurchaseUnit is the unit shopping lists also show the food in, nil when it has none
+func (f *Food) PurchaseUnit() *FoodUnit {
+	if f == nil {
+		return nil
+	}
+	for _, unit := range f.Units {
+		if unit.IsPurchaseUnit {
+			return unit
+		}
+	}
+	return nil
+}
+
+// Sort options for food searches, anything else sorts by name
+const (
+	FoodSortName        = "name"
+	FoodSortRating      = "rating"
+	FoodSortTimesCooked = "times_cooked"
+	FoodSortLastCooked  = "last_cooked"
+	FoodSortNeglected   = "neglected" // longest since last cooked, never cooked first
+)
+
+// FoodFilter narrows and orders food searches, the zero value matches everything sorted by name
+type FoodFilter struct {
+	Sort           string
+	FavouritesOnly bool
+	RecipesOnly    bool
+	MinRating      float64
+	NotCookedDays  int // only foods not cooked in this many days, 0 for any
+}
+
+func (f *FoodFilter) IsZero() bool {
+	return f == nil || *f == FoodFilter{}
+}
+
+// RecipeRating is one member's rating of a recipe
+type RecipeRating struct {
+	MemberID   int       `json:"memberId"`
+	MemberName string    `json:"memberName"`
+	Rating     int       `json:"rating"` // 1 to 5
+	Comment    string    `json:"comment,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
 }
 
 type Recipe struct {
-	Instructions  string        `json:"instructions,omitempty"`
-	URL           string        `json:"url,omitempty"`
-	YieldQuantity float64       `json:"yieldQuantity"`
-	Ingredients   []*RecipeItem `json:"ingredients"`
+	Instructions   string        `json:"instructions,omitempty"`
+	URL            string        `json:"url,omitempty"`
+	YieldQuantity  float64       `json:"yieldQuantity"`
+	PrepMinutes    int           `json:"prepMinutes,omitempty"`    // 0 when unknown
+	CostPerServing float64       `json:"costPerServing,omitempty"` // 0 when unknown
+	Tags           []string      `json:"tags,omitempty"`
+	Ingredients    []*RecipeItem `json:"ingredients"`
+}
+
+// ScaledRecipe is a recipe scaled so one of its ingredients, possibly in a nested recipe, uses up Limit
+type ScaledRecipe struct {
+	Food     *Food
+	Servings float64 // in the recipe's base unit, like its yield
+	Limit    *RecipeItem
+	Lines    []*CookLine
 }
 
 type RecipeItem struct {
@@ -22,4 +152,5 @@ type RecipeItem struct {
 	Food     *Food   `json:"food"`
 	Quantity float64 `json:"quantity"`
 	Unit     string  `json:"unit"`
+	IsMain   bool    `json:"isMain,omitempty"` // compared by the variety warnings
 }
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type HouseholdMember struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	PortionFactor float64 `json:"portionFactor"` // share of a serving, 0.5 for a child
+	IsGuest       bool    `json:"isGuest"`       // guests don't attend meals by default
+
+	Diet      string   `json:"diet,omitempty"` // one of Diets, empty for none
+	Allergens []string `json:"allergens,omitempty"`
+}
+
+// MemberAway is a period a member is away, from Start up to End
+type MemberAway struct {
+	ID       int       `json:"id"`
+	MemberID int       `json:"memberId"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// LastDay is the last day the member is away, End being the midnight after it
+func (a *MemberAway) LastDay() time.Time {
+	return a.End.AddDate(0, 0, -1)
+}
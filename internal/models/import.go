@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// How an imported title was matched to a food
+const (
+	ImportMatchExact = "exact"
+	ImportMatchAlias = "alias"
+	ImportMatchFuzzy = "fuzzy"
+)
+
+// ImportRow is one event or CSV row of an imported meal plan
+type ImportRow struct {
+	Line        int       `json:"line"` // line of the CSV row, or of the event's BEGIN:VEVENT
+	Title       string    `json:"title"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	Servings    float64   `json:"servings"`
+	FoodID      int       `json:"foodId,omitempty"`
+	FoodName    string    `json:"foodName,omitempty"`
+	MatchType   string    `json:"matchType,omitempty"`
+	Duplicate   bool      `json:"duplicate,omitempty"` // the same food is already scheduled at that time
+	Error       string    `json:"error,omitempty"`
+	Warning     string    `json:"warning,omitempty"` // imported, but not quite as in the file
+}
+
+func (r *ImportRow) IsMatched() bool {
+	return r.Error == "" && r.FoodID > 0
+}
+
+// IsImportable reports whether the row will create a schedule
+func (r *ImportRow) IsImportable() bool {
+	return r.IsMatched() && !r.Duplicate
+}
+
+type ImportReport struct {
+	Format  string       `json:"format"` // "ics" or "csv"
+	DryRun  bool         `json:"dryRun"`
+	Rows    []*ImportRow `json:"rows"`
+	Created int          `json:"created"`
+}
+
+func (r *ImportReport) ImportableCount() int {
+	count := 0
+	for _, row := range r.Rows {
+		if row.IsImportable() {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *ImportReport) DuplicateCount() int {
+	count := 0
+	for _, row := range r.Rows {
+		if row.IsMatched() && row.Duplicate {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *ImportReport) ErrorCount() int {
+	count := 0
+	for _, row := range r.Rows {
+		if row.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// UnmatchedTitles returns each distinct title that parsed fine but matched no food
+func (r *ImportReport) UnmatchedTitles() []string {
+	seen := make(map[string]bool)
+	titles := []string{}
+	for _, row := range r.Rows {
+		if row.Error == "" && row.FoodID == 0 && !seen[row.Title] {
+			seen[row.Title] = true
+			titles = append(titles, row.Title)
+		}
+	}
+	return titles
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type MealSlot struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	DefaultTime string `json:"defaultTime"` // "15:04"
+	SortOrder   int    `json:"sortOrder"`
+}
+
+// DefaultTimeOn returns the slot's default time on the given day, in that day's location
+func (m *MealSlot) DefaultTimeOn(day time.Time) time.Time {
+	t, err := time.Parse("15:04", m.DefaultTime)
+	if err != nil {
+		return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+}
@@ -0,0 +1,24 @@
+package models
+
+// PantryStaple is a staple food with its current stock and par level,
+// both expressed in the food's base unit.
+type PantryStaple struct {
+	FoodID   int     `json:"foodId"`
+	FoodName string  `json:"foodName"`
+	UnitType string  `json:"unitType"`
+	BaseUnit string  `json:"baseUnit"`
+	Quantity float64 `json:"quantity"`
+	ParLevel float64 `json:"parLevel"`
+}
+
+// Shortfall returns how much needs buying to bring the staple back up to par
+func (p *PantryStaple) Shortfall() float64 {
+	if p.Quantity >= p.ParLevel {
+		return 0
+	}
+	return p.ParLevel - p.Quantity
+}
+
+func (p *PantryStaple) IsBelowPar() bool {
+	return p.Quantity < p.ParLevel
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// PlanConstraints controls how the generator fills empty meal slots
+type PlanConstraints struct {
+	StartDate             time.Time `json:"startDate"`
+	EndDate               time.Time `json:"endDate"`     // inclusive
+	MealSlotIDs           []int     `json:"mealSlotIds"` // empty fills every slot
+	Servings              float64   `json:"servings"`
+	NoRepeatDays          int       `json:"noRepeatDays"`          // 0 allows repeats
+	MaxWeekdayPrepMinutes int       `json:"maxWeekdayPrepMinutes"` // 0 means no limit
+	Budget                float64   `json:"budget"`                // ceiling for the whole proposal, 0 means no limit
+	PreferPantry          bool      `json:"preferPantry"`
+	RequiredTags          []string  `json:"requiredTags"` // a recipe needs every one of these
+	ExcludedTags          []string  `json:"excludedTags"`
+	Seed                  int64     `json:"seed"`
+}
+
+// PlanProposal is the generated plan, shown for review before anything is scheduled
+type PlanProposal struct {
+	Constraints *PlanConstraints `json:"constraints"`
+	Meals       []*ProposedMeal  `json:"meals"`
+	Unfilled    []*UnfilledSlot  `json:"unfilled,omitempty"`
+}
+
+type ProposedMeal struct {
+	FoodID            int       `json:"foodId"`
+	FoodName          string    `json:"foodName"`
+	ScheduledAt       time.Time `json:"scheduledAt"`
+	Servings          float64   `json:"servings"`
+	MealSlotID        int       `json:"mealSlotId"`
+	MealSlotName      string    `json:"mealSlotName"`
+	PrepMinutes       int       `json:"prepMinutes,omitempty"`
+	Cost              float64   `json:"cost,omitempty"`
+	PantryIngredients int       `json:"pantryIngredients,omitempty"`
+}
+
+// UnfilledSlot is an empty slot the generator couldn't fill, with the constraint that ruled everything out
+type UnfilledSlot struct {
+	Date         time.Time `json:"date"`
+	MealSlotName string    `json:"mealSlotName"`
+	Reason       string    `json:"reason"`
+}
+
+func (p *PlanProposal) TotalCost() float64 {
+	total := 0.0
+	for _, meal := range p.Meals {
+		total += meal.Cost
+	}
+	return total
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// PlanTemplate is a saved plan whose entries are stored relative to the first day of the template
+type PlanTemplate struct {
+	ID         int                  `json:"id"`
+	Name       string               `json:"name"`
+	Days       int                  `json:"days"`
+	EntryCount int                  `json:"entryCount"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	Entries    []*PlanTemplateEntry `json:"entries,omitempty"`
+}
+
+type PlanTemplateEntry struct {
+	ID           int     `json:"id"`
+	FoodID       int     `json:"foodId"`
+	FoodName     string  `json:"foodName"`
+	DayOffset    int     `json:"dayOffset"`
+	TimeOfDay    string  `json:"timeOfDay"` // "15:04"
+	Servings     float64 `json:"servings"`
+	MealSlotID   int     `json:"mealSlotId,omitempty"`
+	MealSlotName string  `json:"mealSlotName,omitempty"`
+}
+
+// PlanTemplatePreview is a template resolved onto a concrete start date, before anything is created
+type PlanTemplatePreview struct {
+	Template  *PlanTemplate              `json:"template"`
+	StartDate time.Time                  `json:"startDate"`
+	Scale     float64                    `json:"scale"`
+	Items     []*PlanTemplatePreviewItem `json:"items"`
+}
+
+type PlanTemplatePreviewItem struct {
+	Entry       *PlanTemplateEntry `json:"entry"`
+	ScheduledAt time.Time          `json:"scheduledAt"`
+	Servings    float64            `json:"servings"`
+	Conflicts   []*Schedule        `json:"conflicts,omitempty"`
+}
+
+func (i *PlanTemplatePreviewItem) HasConflict() bool {
+	return len(i.Conflicts) > 0
+}
+
+func (p *PlanTemplatePreview) ConflictCount() int {
+	count := 0
+	for _, item := range p.Items {
+		if item.HasConflict() {
+			count++
+		}
+	}
+	return count
+}
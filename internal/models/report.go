@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Periods spending is reported by
+const (
+	ReportWeekly  = "week"
+	ReportMonthly = "month"
+)
+
+// SpendingReport is what was spent on groceries between two dates, by period, category and store
+type SpendingReport struct {
+	Period     string            `json:"period"` // ReportWeekly or ReportMonthly
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"` // exclusive
+	Total      float64           `json:"total"`
+	Periods    []*SpendingPeriod `json:"periods"`
+	Categories []*SpendingTotal  `json:"categories"`
+	Stores     []*SpendingTotal  `json:"stores"`
+}
+
+// SpendingPeriod is what was spent in one week or month against the budget for it
+type SpendingPeriod struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"` // exclusive
+	Total  float64   `json:"total"`
+	Budget float64   `json:"budget,omitempty"` // 0 without a budget
+}
+
+// Label names the period, e.g. "Week of Mar 2" or "March 2026"
+func (p *SpendingPeriod) Label(period string) string {
+	if period == ReportMonthly {
+		return p.Start.Format("January 2006")
+	}
+	return "Week of " + p.Start.Format("Jan 2")
+}
+
+// OverBudget reports whether the period went over a budget set for it
+func (p *SpendingPeriod) OverBudget() bool {
+	return p.Budget > 0 && p.Total > p.Budget
+}
+
+// BudgetStatus describes how far over or under budget the period is, empty without a budget
+func (p *SpendingPeriod) BudgetStatus() string {
+	switch {
+	case p.Budget <= 0:
+		return ""
+	case p.OverBudget():
+		return fmt.Sprintf("$%.2f over", p.Total-p.Budget)
+	default:
+		return fmt.Sprintf("$%.2f under", p.Budget-p.Total)
+	}
+}
+
+// SpendingTotal is what was spent in one category or at one store
+type SpendingTotal struct {
+	Name  string  `json:"name"`
+	Total float64 `json:"total"`
+}
+
+// Share is the percentage of the report total spent here
+func (t *SpendingTotal) Share(total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return t.Total / total * 100
+}
+
+// PriceTrend is every price paid for a food, compared per PriceUnit
+type PriceTrend struct {
+	Food      *Food         `json:"food"`
+	PriceUnit string        `json:"priceUnit"`
+	Points    []*PricePoint `json:"points"`
+}
+
+// PricePoint is one purchase of a food
+type PricePoint struct {
+	PurchasedAt time.Time `json:"purchasedAt"`
+	StoreName   string    `json:"storeName,omitempty"`
+	Price       float64   `json:"price"`
+	Quantity    float64   `json:"quantity"`
+	Unit        string    `json:"unit"`
+	UnitPrice   float64   `json:"unitPrice,omitempty"` // per PriceUnit, 0 when the unit doesn't convert
+}
+
+// Change is the percentage the unit price moved from the first purchase to the latest, 0 with fewer than two
+func (t *PriceTrend) Change() float64 {
+	var first, last float64
+	for _, point := range t.Points {
+		if point.UnitPrice <= 0 {
+			continue
+		}
+		if first == 0 {
+			first = point.UnitPrice
+		}
+		last = point.UnitPrice
+	}
+	if first == 0 {
+		return 0
+	}
+	return (last - first) / first * 100
+}
+
+// MaxUnitPrice is the highest unit price paid, for scaling the trend's bars
+func (t *PriceTrend) MaxUnitPrice() float64 {
+	highest := 0.0
+	for _, point := range t.Points {
+		highest = max(highest, point.UnitPrice)
+	}
+	return highest
+}
@@ -6,11 +6,16 @@ import (
 )
 
 type Schedule struct {
-	ID          int       `json:"id"`
-	FoodID      int       `json:"foodId"`
-	FoodName    string    `json:"foodName"`
-	Servings    float64   `json:"servings"` 
-	ScheduledAt time.Time `json:"scheduledAt"`
+	ID             int       `json:"id"`
+	FoodID         int       `json:"foodId"`
+	FoodName       string    `json:"foodName"`
+	Servings       float64   `json:"servings"`
+	ServingsManual bool      `json:"servingsManual"` // false when servings follow the attendees
+	ScheduledAt    time.Time `json:"scheduledAt"`
+	MealSlotID     int       `json:"mealSlotId,omitempty"`
+	MealSlotName   string    `json:"mealSlotName,omitempty"`
+	AttendeeIDs    []int     `json:"attendeeIds,omitempty"` // only loaded for a single schedule
+	Labels         []string  `json:"labels,omitempty"`      // effective labels of the food
 }
 
 func ToScheduleModelFromGetSchedulesInRangeRow(schedule *db.GetSchedulesInRangeRow, timeZone *time.Location) *Schedule {
@@ -18,13 +23,16 @@ func ToScheduleModelFromGetSchedulesInRangeRow(schedule *db.GetSchedulesInRangeR
 	if err != nil {
 		return nil
 	}
-	
+
 	return &Schedule{
-		ID:          int(schedule.ID),
-		FoodID:      int(schedule.FoodID.Int32),
-		FoodName:    schedule.FoodName,
-		Servings: servings.Float64,
-		ScheduledAt: schedule.ScheduledAt.Time.In(timeZone),
+		ID:             int(schedule.ID),
+		FoodID:         int(schedule.FoodID.Int32),
+		FoodName:       schedule.FoodName,
+		Servings:       servings.Float64,
+		ServingsManual: schedule.ServingsManual,
+		ScheduledAt:    schedule.ScheduledAt.Time.In(timeZone),
+		MealSlotID:     int(schedule.MealSlotID.Int32),
+		MealSlotName:   schedule.MealSlotName.String,
 	}
 }
 
@@ -41,13 +49,16 @@ func ToScheduleModelFromCreateScheduleRow(schedule *db.CreateScheduleRow, timeZo
 	if err != nil {
 		return nil
 	}
-	
+
 	return &Schedule{
-		ID:          int(schedule.ID),
-		FoodID:      int(schedule.FoodID.Int32),
-		FoodName:    schedule.FoodName,
-		Servings: servings.Float64,
-		ScheduledAt: schedule.ScheduledAt.Time.In(timeZone),
+		ID:             int(schedule.ID),
+		FoodID:         int(schedule.FoodID.Int32),
+		FoodName:       schedule.FoodName,
+		Servings:       servings.Float64,
+		ServingsManual: schedule.ServingsManual,
+		ScheduledAt:    schedule.ScheduledAt.Time.In(timeZone),
+		MealSlotID:     int(schedule.MealSlotID.Int32),
+		MealSlotName:   schedule.MealSlotName.String,
 	}
 }
 
@@ -56,13 +67,16 @@ func ToScheduleModelFromUpdateScheduleRow(schedule *db.UpdateScheduleRow, timeZo
 	if err != nil {
 		return nil
 	}
-	
+
 	return &Schedule{
-		ID:          int(schedule.ID),
-		FoodID:      int(schedule.FoodID.Int32),
-		FoodName:    schedule.FoodName,
-		Servings:    servings.Float64,
-		ScheduledAt: schedule.ScheduledAt.Time.In(timeZone),
+		ID:             int(schedule.ID),
+		FoodID:         int(schedule.FoodID.Int32),
+		FoodName:       schedule.FoodName,
+		Servings:       servings.Float64,
+		ServingsManual: schedule.ServingsManual,
+		ScheduledAt:    schedule.ScheduledAt.Time.In(timeZone),
+		MealSlotID:     int(schedule.MealSlotID.Int32),
+		MealSlotName:   schedule.MealSlotName.String,
 	}
 }
 
@@ -71,12 +85,67 @@ func ToScheduleModelFromGetScheduleByIdRow(schedule *db.GetScheduleByIdRow, time
 	if err != nil {
 		return nil
 	}
-	
+
+	return &Schedule{
+		ID:             int(schedule.ID),
+		FoodID:         int(schedule.FoodID.Int32),
+		FoodName:       schedule.FoodName,
+		Servings:       servings.Float64,
+		ServingsManual: schedule.ServingsManual,
+		ScheduledAt:    schedule.ScheduledAt.Time.In(timeZone),
+		MealSlotID:     int(schedule.MealSlotID.Int32),
+		MealSlotName:   schedule.MealSlotName.String,
+	}
+}
+
+func ToScheduleModelFromGetSchedulesInRangeForSlotsRow(schedule *db.GetSchedulesInRangeForSlotsRow, timeZone *time.Location) *Schedule {
+	servings, err := schedule.Servings.Float64Value()
+	if err != nil {
+		return nil
+	}
+
 	return &Schedule{
-		ID:          int(schedule.ID),
-		FoodID:      int(schedule.FoodID.Int32),
-		FoodName:    schedule.FoodName,
-		Servings:    servings.Float64,
-		ScheduledAt: schedule.ScheduledAt.Time.In(timeZone),
+		ID:             int(schedule.ID),
+		FoodID:         int(schedule.FoodID.Int32),
+		FoodName:       schedule.FoodName,
+		Servings:       servings.Float64,
+		ServingsManual: schedule.ServingsManual,
+		ScheduledAt:    schedule.ScheduledAt.Time.In(timeZone),
+		MealSlotID:     int(schedule.MealSlotID.Int32),
+		MealSlotName:   schedule.MealSlotName.String,
+	}
+}
+
+func ToSchedulesModelFromGetSchedulesInRangeForSlotsRow(schedules []*db.GetSchedulesInRangeForSlotsRow, timeZone *time.Location) []*Schedule {
+	var result []*Schedule
+	for _, schedule := range schedules {
+		result = append(result, ToScheduleModelFromGetSchedulesInRangeForSlotsRow(schedule, timeZone))
 	}
-}
\ No newline at end of file
+	return result
+}
+
+func ToScheduleModelFromGetSchedulesByIdsRow(schedule *db.GetSchedulesByIdsRow, timeZone *time.Location) *Schedule {
+	servings, err := schedule.Servings.Float64Value()
+	if err != nil {
+		return nil
+	}
+
+	return &Schedule{
+		ID:             int(schedule.ID),
+		FoodID:         int(schedule.FoodID.Int32),
+		FoodName:       schedule.FoodName,
+		Servings:       servings.Float64,
+		ServingsManual: schedule.ServingsManual,
+		ScheduledAt:    schedule.ScheduledAt.Time.In(timeZone),
+		MealSlotID:     int(schedule.MealSlotID.Int32),
+		MealSlotName:   schedule.MealSlotName.String,
+	}
+}
+
+func ToSchedulesModelFromGetSchedulesByIdsRow(schedules []*db.GetSchedulesByIdsRow, timeZone *time.Location) []*Schedule {
+	var result []*Schedule
+	for _, schedule := range schedules {
+		result = append(result, ToScheduleModelFromGetSchedulesByIdsRow(schedule, timeZone))
+	}
+	return result
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type HouseholdSettings struct {
+	WeekStart         time.Weekday `json:"weekStart"`
+	CalendarFeedToken string       `json:"-"` // empty when the feed is disabled
+
+	// Variety warnings shown when scheduling, 0 turns a check off
+	VarietyWindowDays   int      `json:"varietyWindowDays"`
+	VarietyTags         []string `json:"varietyTags"` // recipes sharing one of these tags count as repeats
+	MaxMealsPerDay      int      `json:"maxMealsPerDay"`
+	MaxDailyPrepMinutes int      `json:"maxDailyPrepMinutes"`
+
+	// Grocery budget targets, 0 for no budget
+	WeeklyBudget  float64 `json:"weeklyBudget"`
+	MonthlyBudget float64 `json:"monthlyBudget"`
+}
+
+func (s *HouseholdSettings) CalendarFeedEnabled() bool {
+	return s.CalendarFeedToken != ""
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+    "fmt"
+    "math"
+    "strconv"
     "time"
 )
 
@@ -12,6 +15,38 @@ type ShoppingList struct {
     UpdatedAt time.Time            `json:"updatedAt"`
     Items     []*ShoppingListItem  `json:"items,omitempty"`
     Sources   []*ShoppingListSource `json:"sources,omitempty"`
+    StoreID   int                    `json:"storeId,omitempty"`  // whose layout orders the items, 0 for none
+    SplitBy   string                 `json:"splitBy,omitempty"`  // one of the Split modes, empty when not split
+    Sections  []*ShoppingListSection `json:"sections,omitempty"` // the items by store, then by aisle in walking order
+    Progress  *ShoppingListProgress  `json:"progress,omitempty"`
+    ArchivedAt time.Time             `json:"archivedAt,omitempty"` // zero while the list is active
+}
+
+// Archived reports whether the list has been put away, by hand or by the cleanup
+func (l *ShoppingList) Archived() bool {
+    return !l.ArchivedAt.IsZero()
+}
+
+// CarryOver is what's still to buy when a trip is closed: copies of the items not bought, and of the items bought
+// short for just the shortfall
+func (l *ShoppingList) CarryOver() []*ShoppingListItem {
+    var items []*ShoppingListItem
+    for _, item := range l.Items {
+        if !item.Purchased {
+            carried := *item
+            items = append(items, &carried)
+        } else if shortfall := item.Shortfall(); shortfall > 0 {
+            carried := *item
+            carried.Quantity = shortfall
+            items = append(items, &carried)
+        }
+    }
+    return items
+}
+
+// Split reports whether the list is split into a section per store
+func (l *ShoppingList) Split() bool {
+    return l.SplitBy != SplitNone
 }
 
 type ShoppingListItem struct {
@@ -19,6 +54,7 @@ type ShoppingListItem struct {
     ShoppingListID   int                         `json:"shoppingListId"`
     FoodID           int                         `json:"foodId"`
     FoodName         string                      `json:"foodName"`
+    Category         string                      `json:"category,omitempty"`
     Quantity         float64                     `json:"quantity"`
     Unit             string                      `json:"unit"`
     UnitType         string                      `json:"unitType"`
@@ -27,22 +63,152 @@ type ShoppingListItem struct {
     ActualQuantity   float64                     `json:"actualQuantity,omitempty"`
     ActualPrice      float64                     `json:"actualPrice,omitempty"`
     Sources          []*ShoppingListItemSource   `json:"sources,omitempty"`
+    PurchaseQuantity float64                     `json:"purchaseQuantity,omitempty"` // Quantity in PurchaseUnit
+    PurchaseUnit     string                      `json:"purchaseUnit,omitempty"`     // the food's unit to buy in, if any
+    Packages         *PackagePlan                `json:"packages,omitempty"`         // whole packages to buy, nil without sizes
+    SurplusQuantity  float64                     `json:"surplusQuantity,omitempty"`  // bought beyond Quantity, in Unit
+    StoreID          int                         `json:"storeId,omitempty"`          // where it's bought, or was once purchased
+    EstimatedPrice   float64                     `json:"estimatedPrice,omitempty"`   // from the latest price at StoreID, 0 when unknown
+}
+
+// Shortfall is how much less than needed was bought, in Unit. Only an actual quantity entered when the item
+// was purchased can show one.
+func (i *ShoppingListItem) Shortfall() float64 {
+    if !i.Purchased || i.ActualQuantity <= 0 || i.Quantity-i.ActualQuantity < 1e-9 {
+        return 0
+    }
+    return i.Quantity - i.ActualQuantity
+}
+
+// FreeText reports whether the item is something other than a food, like dish soap, with no food behind it
+func (i *ShoppingListItem) FreeText() bool {
+    return i.FoodID == 0
+}
+
+// Estimate is what the item is expected to cost, from its package prices or else the latest price at its store
+func (i *ShoppingListItem) Estimate() float64 {
+    if i.Packages != nil && i.Packages.Cost() > 0 {
+        return i.Packages.Cost()
+    }
+    return i.EstimatedPrice
+}
+
+// PurchaseAmount describes the item in the unit it's bought in, e.g. " (2 cans)", empty without one
+func (i *ShoppingListItem) PurchaseAmount() string {
+    if i.PurchaseUnit == "" {
+        return ""
+    }
+    return fmt.Sprintf(" (%s %s)", roundedQuantity(i.PurchaseQuantity), i.PurchaseUnit)
+}
+
+func roundedQuantity(quantity float64) string {
+    return strconv.FormatFloat(math.Round(quantity*100)/100, 'f', -1, 64)
+}
+
+// ShoppingListProgress is how far through a list the shopping is, overall and for each source
+type ShoppingListProgress struct {
+    ListID            int               `json:"listId"`
+    TotalItems        int               `json:"totalItems"`
+    PurchasedItems    int               `json:"purchasedItems"`
+    Spent             float64           `json:"spent"`                       // the actual prices entered so far
+    Estimated         float64           `json:"estimated,omitempty"`         // what the whole list should cost, 0 when unknown
+    RemainingEstimate float64           `json:"remainingEstimate,omitempty"` // what the items still to buy should cost
+    ShortItems        int               `json:"shortItems,omitempty"`        // purchased, but less than needed
+    Sources           []*SourceProgress `json:"sources,omitempty"`
+}
+
+func (p *ShoppingListProgress) RemainingItems() int {
+    return p.TotalItems - p.PurchasedItems
+}
+
+// Percentage is the share of items purchased, 0 for an empty list
+func (p *ShoppingListProgress) Percentage() float64 {
+    if p.TotalItems == 0 {
+        return 0
+    }
+    return float64(p.PurchasedItems) / float64(p.TotalItems) * 100
+}
+
+func (p *ShoppingListProgress) Complete() bool {
+    return p.TotalItems > 0 && p.PurchasedItems == p.TotalItems
+}
+
+// SourceProgress is how many of the items a source added have been purchased
+type SourceProgress struct {
+    SourceName     string `json:"sourceName"`
+    SourceType     string `json:"sourceType"`
+    TotalItems     int    `json:"totalItems"`
+    PurchasedItems int    `json:"purchasedItems"`
+}
+
+func (p *SourceProgress) Percentage() float64 {
+    if p.TotalItems == 0 {
+        return 0
+    }
+    return float64(p.PurchasedItems) / float64(p.TotalItems) * 100
 }
 
 type ShoppingListSource struct {
     ID             int       `json:"id"`
     ShoppingListID int       `json:"shoppingListId"`
-    SourceType     string    `json:"sourceType"` // 'schedule', 'recipe', 'manual', 'copy'
+    SourceType     string    `json:"sourceType"` // 'schedule', 'recipe', 'manual', 'copy', 'staples'
     SourceID       int       `json:"sourceId,omitempty"`
     SourceName     string    `json:"sourceName"`
     Servings       float64   `json:"servings,omitempty"`
+    IncludeStaples bool      `json:"includeStaples,omitempty"`
     AddedAt        time.Time `json:"addedAt"`
 }
 
+// Synced reports whether the source follows a schedule or recipe that can change after it's added
+func (s *ShoppingListSource) Synced() bool {
+    return s.SourceType == "schedule" || s.SourceType == "recipe"
+}
+
+// SourceDrift is how a schedule or recipe has changed since it was added to a list
+type SourceDrift struct {
+    Source   *ShoppingListSource   `json:"source"`
+    Removed  bool                  `json:"removed,omitempty"`  // the meal or recipe has been deleted
+    Servings float64               `json:"servings,omitempty"` // the servings now
+    Changes  []*ContributionChange `json:"changes,omitempty"`
+}
+
+// Summary says what changed in a few words
+func (d *SourceDrift) Summary() string {
+    switch {
+    case d.Removed && d.Source.SourceType == "schedule":
+        return "Meal deleted"
+    case d.Removed:
+        return "Recipe deleted"
+    case d.Servings != d.Source.Servings:
+        return fmt.Sprintf("Servings changed from %s to %s", roundedQuantity(d.Source.Servings), roundedQuantity(d.Servings))
+    }
+    return "Ingredients changed"
+}
+
+// ContributionChange is how much of a food a source needs now against what it added to the list
+type ContributionChange struct {
+    FoodName string  `json:"foodName"`
+    Unit     string  `json:"unit"`
+    Before   float64 `json:"before"` // 0 when newly needed
+    After    float64 `json:"after"`  // 0 when no longer needed
+}
+
+func (c *ContributionChange) String() string {
+    switch {
+    case c.Before == 0:
+        return fmt.Sprintf("%s: add %s %s", c.FoodName, roundedQuantity(c.After), c.Unit)
+    case c.After == 0:
+        return fmt.Sprintf("%s: no longer needed", c.FoodName)
+    }
+    return fmt.Sprintf("%s: %s → %s %s", c.FoodName, roundedQuantity(c.Before), roundedQuantity(c.After), c.Unit)
+}
+
 type ShoppingListItemSource struct {
-    ItemID              int     `json:"itemId"`
-    SourceID            int     `json:"sourceId"`
-    ContributedQuantity float64 `json:"contributedQuantity"`
+    ItemID              int                 `json:"itemId"`
+    SourceID            int                 `json:"sourceId"`
+    ContributedQuantity float64             `json:"contributedQuantity"`
+    Source              *ShoppingListSource `json:"source,omitempty"` // filled in for the item's breakdown
+    Adjusted            bool                `json:"adjusted,omitempty"` // changed by hand from what the source works out to
 }
 
 // Request types for different add operations
@@ -53,16 +219,29 @@ type AddManualItemRequest struct {
     Notes    string  `json:"notes,omitempty"`
 }
 
+// AddFreeTextItemRequest adds something that isn't a food, the quantity and unit are optional
+type AddFreeTextItemRequest struct {
+    Name     string  `json:"name"`
+    Quantity float64 `json:"quantity,omitempty"`
+    Unit     string  `json:"unit,omitempty"`
+    Category string  `json:"category,omitempty"`
+    Notes    string  `json:"notes,omitempty"`
+}
+
 type AddRecipeRequest struct {
-    RecipeID int     `json:"recipeId"`
-    Servings float64 `json:"servings"`
+    RecipeID       int     `json:"recipeId"`
+    Servings       float64 `json:"servings"`
+    IncludeStaples bool    `json:"includeStaples"`
 }
 
 type AddSchedulesRequest struct {
-    ScheduleIDs []int `json:"scheduleIds"`
+    ScheduleIDs    []int `json:"scheduleIds"`
+    IncludeStaples bool  `json:"includeStaples"`
 }
 
 type AddDateRangeRequest struct {
-    StartDate time.Time `json:"startDate"`
-    EndDate   time.Time `json:"endDate"`
+    StartDate      time.Time `json:"startDate"`
+    EndDate        time.Time `json:"endDate"`
+    IncludeStaples bool      `json:"includeStaples"`
+    MealSlotIDs    []int     `json:"mealSlotIds"` // empty means every slot
 }
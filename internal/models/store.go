@@ -0,0 +1,150 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FoodCategories are the store sections a food can be filed under, in the order used without a store
+var FoodCategories = []string{"produce", "bakery", "meat", "fish", "dairy", "frozen", "pantry", "spices", "drinks", "household"}
+
+// Store is a shop with the order its sections are walked in
+type Store struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Aisles []string `json:"aisles,omitempty"` // categories in walking order
+}
+
+// How a shopping list is split into per-store sections
+const (
+	SplitNone      = ""
+	SplitPreferred = "preferred" // each food's preferred store, the cheapest of them when there are several
+	SplitCheapest  = "cheapest"  // the store with the lowest latest price
+)
+
+// StorePrice is what was paid for a quantity of a food at a store
+type StorePrice struct {
+	FoodID     int       `json:"foodId"`
+	StoreID    int       `json:"storeId"`
+	StoreName  string    `json:"storeName"`
+	Price      float64   `json:"price"`
+	Quantity   float64   `json:"quantity"`
+	Unit       string    `json:"unit"`
+	ObservedAt time.Time `json:"observedAt"`
+}
+
+func (p *StorePrice) String() string {
+	return fmt.Sprintf("$%.2f for %s %s at %s", p.Price, strconv.FormatFloat(p.Quantity, 'f', -1, 64), p.Unit, p.StoreName)
+}
+
+// ShoppingListSection is the items of a list bought at one store
+type ShoppingListSection struct {
+	Store  *Store               `json:"store,omitempty"` // nil for items without a store
+	Groups []*ShoppingListGroup `json:"groups"`
+}
+
+// Title names the section's store, "Any store" for items without one
+func (s *ShoppingListSection) Title() string {
+	if s.Store == nil {
+		return "Any store"
+	}
+	return s.Store.Name
+}
+
+// EstimatedTotal adds up the estimated prices of the items still to buy, 0 when none are known
+func (s *ShoppingListSection) EstimatedTotal() float64 {
+	total := 0.0
+	for _, group := range s.Groups {
+		for _, item := range group.Items {
+			if !item.Purchased {
+				total += item.EstimatedPrice
+			}
+		}
+	}
+	return total
+}
+
+// SplitByStore puts items into a section per store by their StoreID, in the order of stores, each grouped by
+// the store's aisles. Items without a known store come last, grouped by the aisles of fallback, which may be nil.
+func SplitByStore(items []*ShoppingListItem, stores []*Store, fallback *Store) []*ShoppingListSection {
+	var sections []*ShoppingListSection
+	assigned := make(map[int]bool)
+	for _, store := range stores {
+		var storeItems []*ShoppingListItem
+		for _, item := range items {
+			if item.StoreID == store.ID {
+				storeItems = append(storeItems, item)
+				assigned[item.ID] = true
+			}
+		}
+		if len(storeItems) > 0 {
+			sections = append(sections, &ShoppingListSection{Store: store, Groups: GroupByAisle(storeItems, store.Aisles)})
+		}
+	}
+
+	var rest []*ShoppingListItem
+	for _, item := range items {
+		if !assigned[item.ID] {
+			rest = append(rest, item)
+		}
+	}
+	if len(rest) > 0 {
+		var aisles []string
+		if fallback != nil {
+			aisles = fallback.Aisles
+		}
+		sections = append(sections, &ShoppingListSection{Groups: GroupByAisle(rest, aisles)})
+	}
+	return sections
+}
+
+// ShoppingListGroup is the items of a list found in one section of the store
+type ShoppingListGroup struct {
+	Category string              `json:"category"` // empty for uncategorised items
+	Items    []*ShoppingListItem `json:"items"`
+}
+
+// Title names the group's section, "Other" for uncategorised items
+func (g *ShoppingListGroup) Title() string {
+	if g.Category == "" {
+		return "Other"
+	}
+	return strings.ToUpper(g.Category[:1]) + g.Category[1:]
+}
+
+// GroupByAisle groups items by category in the order the aisles are walked, categories missing from aisles
+// follow in the default order and uncategorised items come last. Items are sorted by name within a group.
+func GroupByAisle(items []*ShoppingListItem, aisles []string) []*ShoppingListGroup {
+	order := slices.Clone(aisles)
+	for _, category := range FoodCategories {
+		if !slices.Contains(order, category) {
+			order = append(order, category)
+		}
+	}
+	position := func(category string) int {
+		if index := slices.Index(order, category); index >= 0 {
+			return index
+		}
+		return len(order)
+	}
+
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b *ShoppingListItem) int {
+		if diff := position(a.Category) - position(b.Category); diff != 0 {
+			return diff
+		}
+		return strings.Compare(strings.ToLower(a.FoodName), strings.ToLower(b.FoodName))
+	})
+
+	var groups []*ShoppingListGroup
+	for _, item := range sorted {
+		if len(groups) == 0 || groups[len(groups)-1].Category != item.Category {
+			groups = append(groups, &ShoppingListGroup{Category: item.Category})
+		}
+		groups[len(groups)-1].Items = append(groups[len(groups)-1].Items, item)
+	}
+	return groups
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Substitution swaps a quantity of one food for one or more others, e.g. 1 cup buttermilk for
+// 1 cup milk and 1 tablespoon lemon juice. Recipe lines are scaled against Quantity.
+type Substitution struct {
+	ID         int           `json:"id"`
+	FoodID     int           `json:"foodId"`
+	FoodName   string        `json:"foodName"`
+	Quantity   float64       `json:"quantity"`
+	Unit       string        `json:"unit"`
+	MemberID   int           `json:"memberId,omitempty"` // 0 when it's for everyone
+	MemberName string        `json:"memberName,omitempty"`
+	Items      []*RecipeItem `json:"items"`
+}
+
+func (s *Substitution) String() string {
+	items := make([]string, len(s.Items))
+	for i, item := range s.Items {
+		items[i] = fmt.Sprintf("%g %s %s", item.Quantity, item.Unit, item.Food.Name)
+	}
+	return fmt.Sprintf("%g %s %s = %s", s.Quantity, s.Unit, s.FoodName, strings.Join(items, " + "))
+}
+
+// CookLine is a recipe line scaled for a meal. When a substitution applies, Substitutes are cooked instead
+type CookLine struct {
+	Item         *RecipeItem
+	Substitution *Substitution
+	Substitutes  []*RecipeItem
+	Lines        []*CookLine // the lines of a nested recipe
+}
+
+// CookView is a scheduled meal as it will be cooked, with the substitutions that could apply to it
+type CookView struct {
+	Schedule  *Schedule
+	Food      *Food
+	Lines     []*CookLine
+	Available []*Substitution // for something in the recipe, and for everyone or someone eating
+	Applied   []int
+}
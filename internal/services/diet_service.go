@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+	"slices"
+	"strings"
+)
+
+type DietService struct {
+	db            *database.DB
+	memberService *MemberService
+}
+
+func NewDietService(db *database.DB, memberService *MemberService) *DietService {
+	return &DietService{
+		db:            db,
+		memberService: memberService,
+	}
+}
+
+// GetEffectiveLabels returns the labels of the given foods, recipes including those of their ingredients. Only
+// the foods and what they're made from are loaded.
+func (s *DietService) GetEffectiveLabels(ctx context.Context, foodIds []int) (map[int][]string, error) {
+	if len(foodIds) == 0 {
+		return map[int][]string{}, nil
+	}
+	ids := make([]int32, len(foodIds))
+	for i, id := range foodIds {
+		ids[i] = int32(id)
+	}
+
+	dbDeps, err := s.db.GetRecipeDependenciesForFoods(ctx, ids)
+	if err != nil {
+		log.Default().Printf("Error getting recipe dependencies: %v", err)
+		return nil, err
+	}
+	deps := make(map[int][]int)
+	for _, dep := range dbDeps {
+		recipeId, ingredientId := int(dep.RecipeID), int(dep.IngredientID)
+		deps[recipeId] = append(deps[recipeId], ingredientId)
+		ids = append(ids, dep.IngredientID)
+	}
+
+	dbLabels, err := s.db.GetFoodLabelsForFoods(ctx, ids)
+	if err != nil {
+		log.Default().Printf("Error getting food labels: %v", err)
+		return nil, err
+	}
+	labels := make(map[int][]string)
+	for _, label := range dbLabels {
+		labels[int(label.FoodID)] = append(labels[int(label.FoodID)], label.Label)
+	}
+
+	return utils.PropagateLabels(deps, labels, foodIds), nil
+}
+
+// LabelFood fills in the effective labels of the food and of its recipe's ingredients
+func (s *DietService) LabelFood(ctx context.Context, food *models.Food) error {
+	// The ingredients go first so the recipe reuses their labels
+	var foodIds []int
+	if food.Recipe != nil {
+		for _, ing := range food.Recipe.Ingredients {
+			if ing.Food != nil {
+				foodIds = append(foodIds, ing.Food.ID)
+			}
+		}
+	}
+	labels, err := s.GetEffectiveLabels(ctx, append(foodIds, food.ID))
+	if err != nil {
+		return err
+	}
+	food.EffectiveLabels = labels[food.ID]
+	if food.Recipe != nil {
+		for _, ing := range food.Recipe.Ingredients {
+			if ing.Food != nil {
+				ing.Food.EffectiveLabels = labels[ing.Food.ID]
+			}
+		}
+	}
+	return nil
+}
+
+// LabelSchedules fills in the effective labels of each scheduled food
+func (s *DietService) LabelSchedules(ctx context.Context, schedules []*models.Schedule) error {
+	if len(schedules) == 0 {
+		return nil
+	}
+	foodIds := make([]int, 0, len(schedules))
+	for _, schedule := range schedules {
+		if !slices.Contains(foodIds, schedule.FoodID) {
+			foodIds = append(foodIds, schedule.FoodID)
+		}
+	}
+	labels, err := s.GetEffectiveLabels(ctx, foodIds)
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		schedule.Labels = labels[schedule.FoodID]
+	}
+	return nil
+}
+
+// CheckAttendees compares a food with the restrictions of the members eating it. Allergies are returned
+// separately from diet conflicts so the caller can refuse the first and only warn about the second.
+func (s *DietService) CheckAttendees(ctx context.Context, foodId int, attendeeIds []int) (allergies, diets string, err error) {
+	if len(attendeeIds) == 0 {
+		return "", "", nil
+	}
+	labels, err := s.GetEffectiveLabels(ctx, []int{foodId})
+	if err != nil {
+		return "", "", err
+	}
+	if len(labels[foodId]) == 0 {
+		return "", "", nil
+	}
+	members, err := s.memberService.GetMembers(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	var allergyMessages, dietMessages []string
+	for _, member := range members {
+		if !slices.Contains(attendeeIds, member.ID) {
+			continue
+		}
+		for _, conflict := range member.Conflicts(labels[foodId]) {
+			if conflict.Allergy {
+				allergyMessages = append(allergyMessages, fmt.Sprintf("%s is allergic to %s", conflict.MemberName, strings.Join(conflict.Labels, ", ")))
+			} else {
+				dietMessages = append(dietMessages, fmt.Sprintf("Contains %s, %s is %s", strings.Join(conflict.Labels, ", "), conflict.MemberName, member.Diet))
+			}
+		}
+	}
+	return strings.Join(allergyMessages, ". "), strings.Join(dietMessages, ". "), nil
+}
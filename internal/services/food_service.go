@@ -3,17 +3,26 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"math"
 	"mealplanner/internal/database"
 	"mealplanner/internal/database/db"
 	"mealplanner/internal/models"
 	"mealplanner/internal/utils"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	ErrIngredientNotInRecipe = errors.New("the recipe doesn't use this ingredient")
+	ErrIncompatibleUnit      = errors.New("the unit can't be converted to the recipe's")
+	ErrFoodUnitInUse         = errors.New("removed units are still used by recipes, substitutions or shopping lists")
+)
+
 type FoodService struct {
 	db *database.DB
 }
@@ -22,19 +31,31 @@ func NewFoodService(db *database.DB) *FoodService {
 	return &FoodService{db: db}
 }
 
-func (s *FoodService) CreateFood(ctx context.Context, params db.CreateFoodParams) (*db.Food, error) {
+func (s *FoodService) CreateFood(ctx context.Context, params db.CreateFoodParams, labels []string, units []*models.FoodUnit, packages []*models.FoodPackage, storeIds []int) (*db.Food, error) {
 	log.Default().Printf("Creating food: %v", params.Name)
 	var food *db.Food
 	err := s.db.WithTx(ctx, func(q *db.Queries) error {
 		log.Default().Printf("Add food to db: %v", params.Name)
 		var err error
 		food, err = q.CreateFood(ctx, params)
-		return err
+		if err != nil {
+			return err
+		}
+		if err := replaceFoodUnits(ctx, q, food.ID, units); err != nil {
+			return err
+		}
+		if err := replaceFoodPackages(ctx, q, food.ID, packages); err != nil {
+			return err
+		}
+		if err := replaceFoodStores(ctx, q, food.ID, storeIds); err != nil {
+			return err
+		}
+		return replaceFoodLabels(ctx, q, food.ID, labels)
 	})
 	return food, err
 }
 
-func (s *FoodService) CreateRecipeWithIngredients(ctx context.Context, recipeParams db.CreateRecipeParams, ingredients []db.AddRecipeIngredientParams) error {
+func (s *FoodService) CreateRecipeWithIngredients(ctx context.Context, recipeParams db.CreateRecipeParams, ingredients []db.AddRecipeIngredientParams, tags []string) error {
 	log.Default().Printf("adding recipe with ingredients: %v", recipeParams.FoodID)
 	return s.db.WithTx(ctx, func(q *db.Queries) error {
 		recipe, err := q.CreateRecipe(ctx, recipeParams)
@@ -48,10 +69,124 @@ func (s *FoodService) CreateRecipeWithIngredients(ctx context.Context, recipePar
 				return err
 			}
 		}
-		return nil
+		return replaceRecipeTags(ctx, q, recipe.FoodID, tags)
 	})
 }
 
+// replaceFoodLabels swaps the diet and allergen labels set on a food for the given set
+func replaceFoodLabels(ctx context.Context, q *db.Queries, foodId int32, labels []string) error {
+	if err := q.DeleteFoodLabels(ctx, foodId); err != nil {
+		return err
+	}
+	for _, label := range labels {
+		err := q.AddFoodLabel(ctx, db.AddFoodLabelParams{
+			FoodID: foodId,
+			Label:  label,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceFoodUnits swaps the units only this food is measured in for the given set. A unit can't be removed, or
+// renamed, while something is still measured in it, it would no longer convert.
+func replaceFoodUnits(ctx context.Context, q *db.Queries, foodId int32, units []*models.FoodUnit) error {
+	existing, err := q.GetFoodUnitsForFoods(ctx, []int32{foodId})
+	if err != nil {
+		return err
+	}
+	var removed []string
+	for _, unit := range existing {
+		if !slices.ContainsFunc(units, func(u *models.FoodUnit) bool { return u.Name == unit.Name }) {
+			removed = append(removed, unit.Name)
+		}
+	}
+	if len(removed) > 0 {
+		inUse, err := q.GetFoodUnitsInUse(ctx, db.GetFoodUnitsInUseParams{
+			FoodID: foodId,
+			Units:  removed,
+		})
+		if err != nil {
+			return err
+		}
+		if len(inUse) > 0 {
+			return fmt.Errorf("%w: %s", ErrFoodUnitInUse, strings.Join(inUse, ", "))
+		}
+	}
+
+	if err := q.DeleteFoodUnits(ctx, foodId); err != nil {
+		return err
+	}
+	for _, unit := range units {
+		err := q.AddFoodUnit(ctx, db.AddFoodUnitParams{
+			FoodID:         foodId,
+			Name:           unit.Name,
+			Quantity:       utils.Float64ToNumeric(unit.Quantity),
+			Unit:           unit.Unit,
+			IsPurchaseUnit: unit.IsPurchaseUnit,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceFoodPackages swaps the sizes a food is sold in for the given set
+func replaceFoodPackages(ctx context.Context, q *db.Queries, foodId int32, packages []*models.FoodPackage) error {
+	if err := q.DeleteFoodPackages(ctx, foodId); err != nil {
+		return err
+	}
+	for _, pkg := range packages {
+		err := q.AddFoodPackage(ctx, db.AddFoodPackageParams{
+			FoodID: foodId,
+			Size:   utils.Float64ToNumeric(pkg.Size),
+			Unit:   pkg.Unit,
+			Price:  utils.OptionalNumeric(pkg.Price),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceFoodStores swaps the stores a food is usually bought from for the given set
+func replaceFoodStores(ctx context.Context, q *db.Queries, foodId int32, storeIds []int) error {
+	if err := q.DeleteFoodStores(ctx, foodId); err != nil {
+		return err
+	}
+	for _, storeId := range storeIds {
+		err := q.AddFoodStore(ctx, db.AddFoodStoreParams{
+			FoodID:  foodId,
+			StoreID: int32(storeId),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceRecipeTags swaps the tags of a recipe for the given set
+func replaceRecipeTags(ctx context.Context, q *db.Queries, recipeId int32, tags []string) error {
+	if err := q.DeleteRecipeTags(ctx, recipeId); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		err := q.AddRecipeTag(ctx, db.AddRecipeTagParams{
+			RecipeID: recipeId,
+			Tag:      tag,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *FoodService) GetFoods(ctx context.Context, queryString string) ([]*models.Food, error) {
 	dbFoods, err := s.db.Queries.SearchFoods(ctx, db.SearchFoodsParams{
 		Btrim: queryString,
@@ -76,32 +211,47 @@ func (s *FoodService) GetFoods(ctx context.Context, queryString string) ([]*mode
 			BaseUnit: dbFood.BaseUnit,
 			Density:  density.Float64,
 			IsRecipe: dbFood.IsRecipe,
+			IsStaple: dbFood.IsStaple,
 		}
 	}
 	return foods, nil
 }
 
 // New paginated method for main food list
-func (s *FoodService) GetFoodsPaginated(ctx context.Context, queryString string, page, pageSize int) ([]*models.Food, *models.PaginationMeta, error) {
+func (s *FoodService) GetFoodsPaginated(ctx context.Context, queryString string, filter *models.FoodFilter, page, pageSize int) ([]*models.Food, *models.PaginationMeta, error) {
 	if page < 1 {
 		page = 1
 	}
 	if pageSize < 1 || pageSize > 100 {
 		pageSize = 20
 	}
+	if filter == nil {
+		filter = &models.FoodFilter{}
+	}
 	
 	offset := (page - 1) * pageSize
 	
-	totalCount, err := s.db.Queries.CountSearchFoods(ctx, queryString)
+	totalCount, err := s.db.Queries.CountSearchFoods(ctx, db.CountSearchFoodsParams{
+		Search:         queryString,
+		FavouritesOnly: filter.FavouritesOnly,
+		RecipesOnly:    filter.RecipesOnly,
+		MinRating:      utils.Float64ToNumeric(filter.MinRating),
+		NotCookedDays:  int32(filter.NotCookedDays),
+	})
 	if err != nil {
 		log.Default().Printf("Error counting foods: %v", err)
 		return nil, nil, err
 	}
 	
-	dbFoods, err := s.db.Queries.SearchFoods(ctx, db.SearchFoodsParams{
-		Btrim: queryString,
-		Limit:   int32(pageSize),
-		Offset:  int32(offset),
+	dbFoods, err := s.db.Queries.SearchFoodsWithStats(ctx, db.SearchFoodsWithStatsParams{
+		Search:         queryString,
+		FavouritesOnly: filter.FavouritesOnly,
+		RecipesOnly:    filter.RecipesOnly,
+		MinRating:      utils.Float64ToNumeric(filter.MinRating),
+		NotCookedDays:  int32(filter.NotCookedDays),
+		Sort:           filter.Sort,
+		PageSize:       int32(pageSize),
+		PageOffset:     int32(offset),
 	})
 	if err != nil {
 		log.Default().Printf("Error searching foods: %v", err)
@@ -110,19 +260,7 @@ func (s *FoodService) GetFoodsPaginated(ctx context.Context, queryString string,
 
 	foods := make([]*models.Food, len(dbFoods))
 	for i, dbFood := range dbFoods {
-		density, err := dbFood.Density.Float64Value()
-		if err != nil {
-			log.Default().Println("Error parsing density: ", err)
-			return nil, nil, err
-		}
-		foods[i] = &models.Food{
-			ID:       int(dbFood.ID),
-			Name:     dbFood.Name,
-			UnitType: dbFood.UnitType,
-			BaseUnit: dbFood.BaseUnit,
-			Density:  density.Float64,
-			IsRecipe: dbFood.IsRecipe,
-		}
+		foods[i] = toFoodWithStats(dbFood)
 	}
 
 	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
@@ -140,19 +278,27 @@ func (s *FoodService) GetFoodsPaginated(ctx context.Context, queryString string,
 }
 
 // Autocomplete search - fast, limited results
-func (s *FoodService) SearchFoodsAutocomplete(ctx context.Context, query string, limit int) ([]*models.Food, error) {
+func (s *FoodService) SearchFoodsAutocomplete(ctx context.Context, query string, filter *models.FoodFilter, limit int) ([]*models.Food, error) {
 	if limit <= 0 || limit > 20 {
 		limit = 10
 	}
 	
-	if query == "" {
+	if query == "" && filter.IsZero() {
 		// Return recent foods if no query
 		return s.GetRecentFoods(ctx, limit)
 	}
+	if filter == nil {
+		filter = &models.FoodFilter{}
+	}
 	
 	dbFoods, err := s.db.Queries.SearchFoodsAutocomplete(ctx, db.SearchFoodsAutocompleteParams{
-		Column1: pgtype.Text{String: query, Valid: true},
-		Limit:   int32(limit),
+		Prefix:         query,
+		FavouritesOnly: filter.FavouritesOnly,
+		RecipesOnly:    filter.RecipesOnly,
+		MinRating:      utils.Float64ToNumeric(filter.MinRating),
+		NotCookedDays:  int32(filter.NotCookedDays),
+		Sort:           filter.Sort,
+		MaxResults:     int32(limit),
 	})
 	if err != nil {
 		log.Default().Printf("Error searching foods for autocomplete: %v", err)
@@ -164,19 +310,7 @@ func (s *FoodService) SearchFoodsAutocomplete(ctx context.Context, query string,
 
 	foods := make([]*models.Food, len(dbFoods))
 	for i, dbFood := range dbFoods {
-		density, err := dbFood.Density.Float64Value()
-		if err != nil {
-			log.Default().Println("Error parsing density: ", err)
-			return nil, err
-		}
-		foods[i] = &models.Food{
-			ID:       int(dbFood.ID),
-			Name:     dbFood.Name,
-			UnitType: dbFood.UnitType,
-			BaseUnit: dbFood.BaseUnit,
-			Density:  density.Float64,
-			IsRecipe: dbFood.IsRecipe,
-		}
+		foods[i] = toFoodWithStats(dbFood)
 	}
 	return foods, nil
 }
@@ -207,6 +341,7 @@ func (s *FoodService) GetRecentFoods(ctx context.Context, limit int) ([]*models.
 			BaseUnit: dbFood.BaseUnit,
 			Density:  density.Float64,
 			IsRecipe: dbFood.IsRecipe,
+			IsStaple: dbFood.IsStaple,
 		}
 	}
 	return foods, nil
@@ -243,11 +378,128 @@ func (s *FoodService) GetFoodDetails(ctx context.Context, id string, depth int)
 	}
 
 	foods := SearchResultToFoods(dbFoods)
+	if err := s.loadFoodMeasures(ctx, foods[0]); err != nil {
+		return nil, err
+	}
 
 	return foods[0], nil
 }
 
-func (s *FoodService) UpdateFood(ctx context.Context, updateParams db.UpdateFoodWithRecipeParams, ingredients []db.AddRecipeIngredientParams, returnUpdated bool) (*models.Food, error) {
+// GetFoodUnitsByFood returns the custom units of the given foods, keyed by food
+func (s *FoodService) GetFoodUnitsByFood(ctx context.Context, foodIds []int32) (map[int][]*models.FoodUnit, error) {
+	dbUnits, err := s.db.GetFoodUnitsForFoods(ctx, foodIds)
+	if err != nil {
+		log.Default().Printf("Error getting food units: %v", err)
+		return nil, err
+	}
+	units := make(map[int][]*models.FoodUnit)
+	for _, row := range dbUnits {
+		units[int(row.FoodID)] = append(units[int(row.FoodID)], &models.FoodUnit{
+			ID:             int(row.ID),
+			FoodID:         int(row.FoodID),
+			Name:           row.Name,
+			Quantity:       numericToFloat64(row.Quantity),
+			Unit:           row.Unit,
+			IsPurchaseUnit: row.IsPurchaseUnit,
+		})
+	}
+	return units, nil
+}
+
+// GetFoodPackagesByFood returns the sizes the given foods are sold in, keyed by food
+func (s *FoodService) GetFoodPackagesByFood(ctx context.Context, foodIds []int32) (map[int][]*models.FoodPackage, error) {
+	dbPackages, err := s.db.GetFoodPackagesForFoods(ctx, foodIds)
+	if err != nil {
+		log.Default().Printf("Error getting food packages: %v", err)
+		return nil, err
+	}
+	packages := make(map[int][]*models.FoodPackage)
+	for _, row := range dbPackages {
+		packages[int(row.FoodID)] = append(packages[int(row.FoodID)], &models.FoodPackage{
+			ID:     int(row.ID),
+			FoodID: int(row.FoodID),
+			Size:   numericToFloat64(row.Size),
+			Unit:   row.Unit,
+			Price:  numericToFloat64(row.Price),
+		})
+	}
+	return packages, nil
+}
+
+func (s *FoodService) GetFoodStoresByFood(ctx context.Context, foodIds []int32) (map[int][]int, error) {
+	dbStores, err := s.db.GetFoodStoresForFoods(ctx, foodIds)
+	if err != nil {
+		log.Default().Printf("Error getting food stores: %v", err)
+		return nil, err
+	}
+	stores := make(map[int][]int)
+	for _, row := range dbStores {
+		stores[int(row.FoodID)] = append(stores[int(row.FoodID)], int(row.StoreID))
+	}
+	return stores, nil
+}
+
+// GetFoodPricesByFood gets the latest price paid for each food at each store
+func (s *FoodService) GetFoodPricesByFood(ctx context.Context, foodIds []int32) (map[int][]*models.StorePrice, error) {
+	dbPrices, err := s.db.GetLatestStorePricesForFoods(ctx, foodIds)
+	if err != nil {
+		log.Default().Printf("Error getting store prices: %v", err)
+		return nil, err
+	}
+	prices := make(map[int][]*models.StorePrice)
+	for _, row := range dbPrices {
+		prices[int(row.FoodID)] = append(prices[int(row.FoodID)], &models.StorePrice{
+			FoodID:     int(row.FoodID),
+			StoreID:    int(row.StoreID),
+			StoreName:  row.StoreName,
+			Price:      numericToFloat64(row.Price),
+			Quantity:   numericToFloat64(row.Quantity),
+			Unit:       row.Unit,
+			ObservedAt: row.ObservedAt.Time,
+		})
+	}
+	return prices, nil
+}
+
+// loadFoodMeasures fills in the custom units, package sizes, stores and prices of a food and of its direct ingredients
+func (s *FoodService) loadFoodMeasures(ctx context.Context, food *models.Food) error {
+	foods := []*models.Food{food}
+	if food.Recipe != nil {
+		for _, ingredient := range food.Recipe.Ingredients {
+			foods = append(foods, ingredient.Food)
+		}
+	}
+	foodIds := make([]int32, len(foods))
+	for i, f := range foods {
+		foodIds[i] = int32(f.ID)
+	}
+
+	units, err := s.GetFoodUnitsByFood(ctx, foodIds)
+	if err != nil {
+		return err
+	}
+	packages, err := s.GetFoodPackagesByFood(ctx, foodIds)
+	if err != nil {
+		return err
+	}
+	stores, err := s.GetFoodStoresByFood(ctx, foodIds)
+	if err != nil {
+		return err
+	}
+	prices, err := s.GetFoodPricesByFood(ctx, foodIds)
+	if err != nil {
+		return err
+	}
+	for _, f := range foods {
+		f.Units = units[f.ID]
+		f.Packages = packages[f.ID]
+		f.StoreIDs = stores[f.ID]
+		f.Prices = prices[f.ID]
+	}
+	return nil
+}
+
+func (s *FoodService) UpdateFood(ctx context.Context, updateParams db.UpdateFoodWithRecipeParams, ingredients []db.AddRecipeIngredientParams, tags []string, labels []string, units []*models.FoodUnit, packages []*models.FoodPackage, storeIds []int, returnUpdated bool) (*models.Food, error) {
 
 	err := s.db.WithTx(ctx, func(q *db.Queries) error {
 		var err error
@@ -262,6 +514,21 @@ func (s *FoodService) UpdateFood(ctx context.Context, updateParams db.UpdateFood
 				return err
 			}
 		}
+		if err := replaceFoodLabels(ctx, q, updatedFood.ID, labels); err != nil {
+			return err
+		}
+		if err := replaceFoodUnits(ctx, q, updatedFood.ID, units); err != nil {
+			return err
+		}
+		if err := replaceFoodPackages(ctx, q, updatedFood.ID, packages); err != nil {
+			return err
+		}
+		if err := replaceFoodStores(ctx, q, updatedFood.ID, storeIds); err != nil {
+			return err
+		}
+		if updateParams.IsRecipe {
+			return replaceRecipeTags(ctx, q, updatedFood.ID, tags)
+		}
 		return nil
 	})
 	if err != nil {
@@ -277,6 +544,62 @@ func (s *FoodService) UpdateFood(ctx context.Context, updateParams db.UpdateFood
 	return nil, nil
 }
 
+// LoadFoodHistory fills in the average rating and cook history of food
+func (s *FoodService) LoadFoodHistory(ctx context.Context, food *models.Food) error {
+	history, err := s.db.GetFoodHistory(ctx, int32(food.ID))
+	if err != nil {
+		return err
+	}
+	food.AverageRating = numericToFloat64(history.AverageRating)
+	food.LastCookedAt = history.LastCookedAt.Time
+	food.TimesCooked = int(history.TimesCooked)
+	return nil
+}
+
+func (s *FoodService) SetFavourite(ctx context.Context, foodId int, isFavourite bool) error {
+	return s.db.SetFoodFavourite(ctx, db.SetFoodFavouriteParams{
+		ID:          int32(foodId),
+		IsFavourite: isFavourite,
+	})
+}
+
+func (s *FoodService) GetRecipeRatings(ctx context.Context, recipeId int) ([]*models.RecipeRating, error) {
+	dbRatings, err := s.db.GetRecipeRatings(ctx, int32(recipeId))
+	if err != nil {
+		log.Default().Printf("Error getting recipe ratings: %v", err)
+		return nil, err
+	}
+
+	ratings := make([]*models.RecipeRating, len(dbRatings))
+	for i, dbRating := range dbRatings {
+		ratings[i] = &models.RecipeRating{
+			MemberID:   int(dbRating.MemberID),
+			MemberName: dbRating.MemberName,
+			Rating:     int(dbRating.Rating),
+			Comment:    dbRating.Comment,
+			UpdatedAt:  dbRating.UpdatedAt.Time,
+		}
+	}
+	return ratings, nil
+}
+
+// RateRecipe records a member's rating, replacing any earlier rating by the same member
+func (s *FoodService) RateRecipe(ctx context.Context, recipeId, memberId, rating int, comment string) error {
+	return s.db.UpsertRecipeRating(ctx, db.UpsertRecipeRatingParams{
+		RecipeID: int32(recipeId),
+		MemberID: int32(memberId),
+		Rating:   int32(rating),
+		Comment:  comment,
+	})
+}
+
+func (s *FoodService) DeleteRecipeRating(ctx context.Context, recipeId, memberId int) error {
+	return s.db.DeleteRecipeRating(ctx, db.DeleteRecipeRatingParams{
+		RecipeID: int32(recipeId),
+		MemberID: int32(memberId),
+	})
+}
+
 func SearchResultToFoods(rows []*db.SearchFoodsWithDependenciesRow) []*models.Food {
 	foodMap := make(map[int32]*models.Food)
 
@@ -292,6 +615,11 @@ func SearchResultToFoods(rows []*db.SearchFoodsWithDependenciesRow) []*models.Fo
 			UnitType: row.UnitType,
 			BaseUnit: row.BaseUnit,
 			IsRecipe: row.IsRecipe,
+			IsStaple: row.IsStaple,
+			Category: row.Category,
+
+			IsFavourite: row.IsFavourite,
+			Labels:      row.Labels,
 		}
 
 		if row.Density.Valid {
@@ -307,10 +635,13 @@ func SearchResultToFoods(rows []*db.SearchFoodsWithDependenciesRow) []*models.Fo
 			}
 
 			food.Recipe = &models.Recipe{
-				Instructions:  row.Instructions.String,
-				URL:           row.Url.String,
-				YieldQuantity: yieldQty,
-				Ingredients:   make([]*models.RecipeItem, 0),
+				Instructions:   row.Instructions.String,
+				URL:            row.Url.String,
+				YieldQuantity:  yieldQty,
+				PrepMinutes:    int(row.PrepMinutes.Int32),
+				CostPerServing: numericToFloat64(row.CostPerServing),
+				Tags:           row.Tags,
+				Ingredients:    make([]*models.RecipeItem, 0),
 			}
 		}
 
@@ -329,6 +660,7 @@ func SearchResultToFoods(rows []*db.SearchFoodsWithDependenciesRow) []*models.Fo
 					Food:     foodMap[row.ID],
 					Quantity: quantity.Float64,
 					Unit:     row.Unit.String,
+					IsMain:   row.IsMain.Bool,
 				}
 
 				parentFood.Recipe.Ingredients = append(parentFood.Recipe.Ingredients, ingredient)
@@ -349,6 +681,23 @@ func SearchResultToFoods(rows []*db.SearchFoodsWithDependenciesRow) []*models.Fo
 	return result
 }
 
+func toFoodWithStats(row *db.FoodStat) *models.Food {
+	return &models.Food{
+		ID:            int(row.ID),
+		Name:          row.Name,
+		UnitType:      row.UnitType,
+		BaseUnit:      row.BaseUnit,
+		Density:       numericToFloat64(row.Density),
+		IsRecipe:      row.IsRecipe,
+		IsStaple:      row.IsStaple,
+		IsFavourite:   row.IsFavourite,
+		AverageRating: numericToFloat64(row.AverageRating),
+		RatingCount:   int(row.RatingCount),
+		LastCookedAt:  row.LastCookedAt.Time,
+		TimesCooked:   int(row.TimesCooked),
+	}
+}
+
 // Helper function to convert pgtype.Numeric to float64
 func numericToFloat64(n pgtype.Numeric) float64 {
 	if !n.Valid {
@@ -369,6 +718,129 @@ func (s *FoodService) GetFoodUnits(ctx context.Context, id string) ([]string, st
 		log.Default().Printf("Invalid unit type: %s", targetFood.UnitType)
 		return nil, "", errors.New("invalid unit type")
 	}
+	for _, unit := range targetFood.Units {
+		units = append(units, unit.Name)
+	}
 
 	return units, targetFood.BaseUnit, nil
 }
+
+// scaledLines walks the recipe like collectBaseIngredients but keeps nested recipes as nested lines.
+// substitutions are keyed by the food they replace and may be nil. usedFoods collects every food the
+// recipe calls for, substituted or not.
+func (s *FoodService) scaledLines(ctx context.Context, recipe *models.Food, scaleFactor float64, substitutions map[int]*models.Substitution, usedFoods map[int]bool, depth int) ([]*models.CookLine, error) {
+	if depth > 15 {
+		return nil, fmt.Errorf("recipe depth limit exceeded")
+	}
+
+	lines := make([]*models.CookLine, 0, len(recipe.Recipe.Ingredients))
+	for _, ingredient := range recipe.Recipe.Ingredients {
+		usedFoods[ingredient.Food.ID] = true
+		line := &models.CookLine{
+			Item: &models.RecipeItem{
+				FoodID:   ingredient.FoodID,
+				Food:     ingredient.Food,
+				Quantity: ingredient.Quantity * scaleFactor,
+				Unit:     ingredient.Unit,
+				IsMain:   ingredient.IsMain,
+			},
+		}
+		lines = append(lines, line)
+
+		if substitution := substitutions[ingredient.Food.ID]; substitution != nil {
+			if substitutes, ok := utils.SubstituteIngredient(line.Item, substitution); ok {
+				line.Substitution = substitution
+				line.Substitutes = substitutes
+				continue
+			}
+		}
+		if ingredient.Food.IsRecipe {
+			nested, err := s.GetFoodDetails(ctx, strconv.Itoa(ingredient.Food.ID), 1)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get recipe %d: %w", ingredient.Food.ID, err)
+			}
+			line.Lines, err = s.scaledLines(ctx, nested, line.Item.Quantity/nested.Recipe.YieldQuantity, substitutions, usedFoods, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return lines, nil
+}
+
+// ScaleToIngredient scales a recipe so it uses up the limit's quantity of one ingredient, which may be called for
+// directly or by a nested recipe. The servings are in the recipe's base unit, like its yield.
+func (s *FoodService) ScaleToIngredient(ctx context.Context, recipeId int, limit *models.RecipeItem) (*models.ScaledRecipe, error) {
+	recipe, err := s.GetFoodDetails(ctx, strconv.Itoa(recipeId), 1)
+	if err != nil {
+		return nil, err
+	}
+	if !recipe.IsRecipe || recipe.Recipe == nil {
+		return nil, fmt.Errorf("food %d is not a recipe", recipeId)
+	}
+
+	perYield, err := s.ingredientAmount(ctx, recipe, limit.FoodID, limit.Unit, 0)
+	if err != nil {
+		return nil, err
+	}
+	if perYield <= 0 {
+		return nil, ErrIngredientNotInRecipe
+	}
+
+	servings := recipe.Recipe.YieldQuantity * limit.Quantity / perYield
+	lines, err := s.scaledLines(ctx, recipe, servings/recipe.Recipe.YieldQuantity, nil, make(map[int]bool), 0)
+	if err != nil {
+		return nil, err
+	}
+	if limit.Food == nil {
+		limit.Food = lineFood(lines, limit.FoodID)
+	}
+	return &models.ScaledRecipe{
+		Food:     recipe,
+		Servings: servings,
+		Limit:    limit,
+		Lines:    lines,
+	}, nil
+}
+
+// lineFood finds a food among the scaled lines at any depth
+func lineFood(lines []*models.CookLine, foodId int) *models.Food {
+	for _, line := range lines {
+		if line.Item.Food.ID == foodId {
+			return line.Item.Food
+		}
+		if food := lineFood(line.Lines, foodId); food != nil {
+			return food
+		}
+	}
+	return nil
+}
+
+// ingredientAmount adds up how much of a food one full yield of the recipe uses, in the given unit
+func (s *FoodService) ingredientAmount(ctx context.Context, recipe *models.Food, foodId int, unit string, depth int) (float64, error) {
+	if depth > 15 {
+		return 0, fmt.Errorf("recipe depth limit exceeded")
+	}
+
+	total := 0.0
+	for _, ingredient := range recipe.Recipe.Ingredients {
+		if ingredient.Food.ID == foodId {
+			quantity, ok := utils.ConvertFoodUnit(ingredient.Food, ingredient.Quantity, ingredient.Unit, unit)
+			if !ok {
+				return 0, ErrIncompatibleUnit
+			}
+			total += quantity
+		} else if ingredient.Food.IsRecipe {
+			nested, err := s.GetFoodDetails(ctx, strconv.Itoa(ingredient.Food.ID), 1)
+			if err != nil {
+				return 0, fmt.Errorf("failed to get recipe %d: %w", ingredient.Food.ID, err)
+			}
+			amount, err := s.ingredientAmount(ctx, nested, foodId, unit, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			total += amount * ingredient.Quantity / nested.Recipe.YieldQuantity
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ImportService struct {
+	db          *database.DB
+	foodService *FoodService
+}
+
+func NewImportService(db *database.DB, foodService *FoodService) *ImportService {
+	return &ImportService{
+		db:          db,
+		foodService: foodService,
+	}
+}
+
+// DryRun parses and matches the file without creating anything
+func (s *ImportService) DryRun(ctx context.Context, format, content, defaultTime string, timeZone *time.Location) (*models.ImportReport, error) {
+	report, err := s.buildReport(ctx, s.db.Queries, format, content, defaultTime, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = true
+	return report, nil
+}
+
+// Import saves the given title -> food mappings as aliases, then creates a schedule for every
+// matched row that isn't already planned. It all happens in one transaction, so a failed import leaves
+// neither aliases nor meals behind. Rows without servings are reported rather than aborting the import.
+func (s *ImportService) Import(ctx context.Context, format, content, defaultTime string, aliases map[string]int, timeZone *time.Location) (*models.ImportReport, error) {
+	var report *models.ImportReport
+	err := s.db.WithTx(ctx, func(q *db.Queries) error {
+		for title, foodId := range aliases {
+			err := q.UpsertFoodAlias(ctx, db.UpsertFoodAliasParams{
+				FoodID: int32(foodId),
+				Alias:  utils.NormalizeFoodName(title),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save alias %q: %w", title, err)
+			}
+		}
+
+		var err error
+		report, err = s.buildReport(ctx, q, format, content, defaultTime, timeZone)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range report.Rows {
+			if !row.IsImportable() {
+				continue
+			}
+			_, err := createSchedule(ctx, q, row.FoodID, row.Servings, row.ScheduledAt.UTC(), 0, nil, timeZone)
+			if err == ErrNoServings {
+				row.Error = "No servings given"
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to import line %d: %w", row.Line, err)
+			}
+			report.Created++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *ImportService) buildReport(ctx context.Context, q *db.Queries, format, content, defaultTime string, timeZone *time.Location) (*models.ImportReport, error) {
+	var rows []*models.ImportRow
+	switch format {
+	case utils.ImportFormatICS:
+		rows = utils.ParseICalendarImport(content, timeZone, defaultTime)
+	case utils.ImportFormatCSV:
+		var err error
+		rows, err = utils.ParseCSVImport(content, timeZone, defaultTime)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+
+	matcher, err := s.getFoodMatcher(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Error != "" {
+			continue
+		}
+		if food, matchType := matcher.Match(row.Title); food != nil {
+			row.FoodID = food.ID
+			row.FoodName = food.Name
+			row.MatchType = matchType
+		}
+	}
+
+	if err := markDuplicates(ctx, q, rows, timeZone); err != nil {
+		return nil, err
+	}
+
+	return &models.ImportReport{
+		Format: format,
+		Rows:   rows,
+	}, nil
+}
+
+func (s *ImportService) getFoodMatcher(ctx context.Context, q *db.Queries) (*utils.FoodMatcher, error) {
+	foods, err := s.foodService.GetFoods(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	dbAliases, err := q.GetFoodAliases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]int, len(dbAliases))
+	for _, dbAlias := range dbAliases {
+		aliases[dbAlias.Alias] = int(dbAlias.FoodID)
+	}
+
+	return utils.NewFoodMatcher(foods, aliases), nil
+}
+
+// markDuplicates flags matched rows whose food is already scheduled at the same time,
+// so importing the same file twice doesn't double the plan
+func markDuplicates(ctx context.Context, q *db.Queries, rows []*models.ImportRow, timeZone *time.Location) error {
+	var start, end time.Time
+	for _, row := range rows {
+		if !row.IsMatched() {
+			continue
+		}
+		if start.IsZero() || row.ScheduledAt.Before(start) {
+			start = row.ScheduledAt
+		}
+		if end.IsZero() || row.ScheduledAt.After(end) {
+			end = row.ScheduledAt
+		}
+	}
+	if start.IsZero() {
+		return nil
+	}
+
+	// The range query includes end, which is the last row's time
+	dbSchedules, err := q.GetSchedulesInRange(ctx, db.GetSchedulesInRangeParams{
+		ScheduledAt:   pgtype.Timestamptz{Time: start, Valid: true},
+		ScheduledAt_2: pgtype.Timestamptz{Time: end, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	existing := models.ToSchedulesModelFromGetSchedulesInRangeRow(dbSchedules, timeZone)
+	planned := make(map[string]bool, len(existing))
+	for _, schedule := range existing {
+		planned[fmt.Sprintf("%d@%d", schedule.FoodID, schedule.ScheduledAt.Unix())] = true
+	}
+	for _, row := range rows {
+		if row.IsMatched() && planned[fmt.Sprintf("%d@%d", row.FoodID, row.ScheduledAt.Unix())] {
+			row.Duplicate = true
+		}
+	}
+	return nil
+}
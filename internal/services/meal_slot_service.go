@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type MealSlotService struct {
+	db *database.DB
+}
+
+func NewMealSlotService(db *database.DB) *MealSlotService {
+	return &MealSlotService{db: db}
+}
+
+func (s *MealSlotService) GetMealSlots(ctx context.Context) ([]*models.MealSlot, error) {
+	dbSlots, err := s.db.GetMealSlots(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting meal slots: %v", err)
+		return nil, err
+	}
+
+	slots := make([]*models.MealSlot, len(dbSlots))
+	for i, dbSlot := range dbSlots {
+		slots[i] = toMealSlotModel(dbSlot)
+	}
+	return slots, nil
+}
+
+func (s *MealSlotService) GetMealSlotById(ctx context.Context, id int) (*models.MealSlot, error) {
+	dbSlot, err := s.db.GetMealSlotById(ctx, int32(id))
+	if err != nil {
+		return nil, err
+	}
+	return toMealSlotModel(dbSlot), nil
+}
+
+func (s *MealSlotService) CreateMealSlot(ctx context.Context, name string, defaultTime time.Time, sortOrder int) (*models.MealSlot, error) {
+	dbSlot, err := s.db.CreateMealSlot(ctx, db.CreateMealSlotParams{
+		Name:        name,
+		DefaultTime: timeOfDayToPgTime(defaultTime),
+		SortOrder:   int32(sortOrder),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toMealSlotModel(dbSlot), nil
+}
+
+func (s *MealSlotService) UpdateMealSlot(ctx context.Context, id int, name string, defaultTime time.Time, sortOrder int) (*models.MealSlot, error) {
+	dbSlot, err := s.db.UpdateMealSlot(ctx, db.UpdateMealSlotParams{
+		ID:          int32(id),
+		Name:        name,
+		DefaultTime: timeOfDayToPgTime(defaultTime),
+		SortOrder:   int32(sortOrder),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toMealSlotModel(dbSlot), nil
+}
+
+func (s *MealSlotService) DeleteMealSlot(ctx context.Context, id int) error {
+	return s.db.DeleteMealSlot(ctx, int32(id))
+}
+
+func toMealSlotModel(dbSlot *db.MealSlot) *models.MealSlot {
+	return &models.MealSlot{
+		ID:          int(dbSlot.ID),
+		Name:        dbSlot.Name,
+		DefaultTime: pgTimeToTimeOfDay(dbSlot.DefaultTime),
+		SortOrder:   int(dbSlot.SortOrder),
+	}
+}
+
+// pgTimeToTimeOfDay formats a TIME column as "15:04"
+func pgTimeToTimeOfDay(t pgtype.Time) string {
+	minutes := t.Microseconds / int64(time.Minute/time.Microsecond)
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+func timeOfDayToPgTime(t time.Time) pgtype.Time {
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	return pgtype.Time{Microseconds: sinceMidnight.Microseconds(), Valid: true}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrMemberAwayNotFound = errors.New("the member has no such away period")
+
+type MemberService struct {
+	db *database.DB
+}
+
+func NewMemberService(db *database.DB) *MemberService {
+	return &MemberService{db: db}
+}
+
+func (s *MemberService) GetMembers(ctx context.Context) ([]*models.HouseholdMember, error) {
+	dbMembers, err := s.db.GetHouseholdMembers(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting household members: %v", err)
+		return nil, err
+	}
+
+	members := make([]*models.HouseholdMember, len(dbMembers))
+	for i, dbMember := range dbMembers {
+		members[i] = toHouseholdMemberModel(dbMember)
+	}
+	return members, nil
+}
+
+func (s *MemberService) CreateMember(ctx context.Context, member *models.HouseholdMember) (*models.HouseholdMember, error) {
+	dbMember, err := s.db.CreateHouseholdMember(ctx, db.CreateHouseholdMemberParams{
+		Name:          member.Name,
+		PortionFactor: utils.Float64ToNumeric(member.PortionFactor),
+		IsGuest:       member.IsGuest,
+		Diet:          member.Diet,
+		Allergens:     nonNilStrings(member.Allergens),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toHouseholdMemberModel(dbMember), nil
+}
+
+// UpdateMember saves the member and recalculates the servings of the upcoming meals they attend
+func (s *MemberService) UpdateMember(ctx context.Context, member *models.HouseholdMember) (*models.HouseholdMember, error) {
+	var updated *models.HouseholdMember
+	err := s.db.WithTx(ctx, func(q *db.Queries) error {
+		dbMember, err := q.UpdateHouseholdMember(ctx, db.UpdateHouseholdMemberParams{
+			ID:            int32(member.ID),
+			Name:          member.Name,
+			PortionFactor: utils.Float64ToNumeric(member.PortionFactor),
+			IsGuest:       member.IsGuest,
+			Diet:          member.Diet,
+			Allergens:     nonNilStrings(member.Allergens),
+		})
+		if err != nil {
+			return err
+		}
+		updated = toHouseholdMemberModel(dbMember)
+
+		scheduleIds, err := q.GetUpcomingScheduleIdsForMember(ctx, int32(member.ID))
+		if err != nil {
+			return err
+		}
+		return q.RecalculateScheduleServings(ctx, scheduleIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteMember removes a member along with their ratings, upcoming meals they attended are recalculated without them
+func (s *MemberService) DeleteMember(ctx context.Context, id int) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		scheduleIds, err := q.GetUpcomingScheduleIdsForMember(ctx, int32(id))
+		if err != nil {
+			return err
+		}
+		if err := q.DeleteHouseholdMember(ctx, int32(id)); err != nil {
+			return err
+		}
+		return q.RecalculateScheduleServings(ctx, scheduleIds)
+	})
+}
+
+// MarkAway records the member as away for [start, end), takes them off every meal already planned in it and
+// recalculates their servings. Meals planned in it later leave them out too, so shopping lists only buy for who
+// is home. Returns the number of meals changed.
+func (s *MemberService) MarkAway(ctx context.Context, id int, start, end time.Time) (int, error) {
+	var changed int
+	err := s.db.WithTx(ctx, func(q *db.Queries) error {
+		awayId, err := q.CreateMemberAway(ctx, db.CreateMemberAwayParams{
+			MemberID: int32(id),
+			StartAt:  pgtype.Timestamptz{Time: start, Valid: true},
+			EndAt:    pgtype.Timestamptz{Time: end, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		scheduleIds, err := q.RemoveMemberFromSchedulesInRange(ctx, db.RemoveMemberFromSchedulesInRangeParams{
+			MemberID:   int32(id),
+			RangeStart: pgtype.Timestamptz{Time: start, Valid: true},
+			RangeEnd:   pgtype.Timestamptz{Time: end, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		err = q.AddMemberAwaySchedules(ctx, db.AddMemberAwaySchedulesParams{
+			MemberAwayID: awayId,
+			ScheduleIds:  scheduleIds,
+		})
+		if err != nil {
+			return err
+		}
+		changed = len(scheduleIds)
+		return q.RecalculateScheduleServings(ctx, scheduleIds)
+	})
+	if err != nil {
+		log.Default().Printf("Error marking member %d away: %v", id, err)
+		return 0, err
+	}
+	return changed, nil
+}
+
+// GetAwayPeriods returns the away periods of every member that haven't ended yet
+func (s *MemberService) GetAwayPeriods(ctx context.Context, timeZone *time.Location) ([]*models.MemberAway, error) {
+	dbPeriods, err := s.db.GetMemberAwayPeriods(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting away periods: %v", err)
+		return nil, err
+	}
+
+	periods := make([]*models.MemberAway, len(dbPeriods))
+	for i, dbPeriod := range dbPeriods {
+		periods[i] = &models.MemberAway{
+			ID:       int(dbPeriod.ID),
+			MemberID: int(dbPeriod.MemberID),
+			Start:    dbPeriod.StartAt.Time.In(timeZone),
+			End:      dbPeriod.EndAt.Time.In(timeZone),
+		}
+	}
+	return periods, nil
+}
+
+// CancelAway removes an away period and puts the member back on the meals it took them off, unless another
+// period covers them too. Their servings are recalculated. Returns the number of meals changed.
+func (s *MemberService) CancelAway(ctx context.Context, id, awayId int) (int, error) {
+	var changed int
+	err := s.db.WithTx(ctx, func(q *db.Queries) error {
+		if err := q.HandOverMemberAwaySchedules(ctx, int32(awayId)); err != nil {
+			return err
+		}
+		scheduleIds, err := q.RestoreMemberAwayAttendees(ctx, int32(awayId))
+		if err != nil {
+			return err
+		}
+		rows, err := q.DeleteMemberAway(ctx, db.DeleteMemberAwayParams{
+			ID:       int32(awayId),
+			MemberID: int32(id),
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrMemberAwayNotFound
+		}
+		changed = len(scheduleIds)
+		return q.RecalculateScheduleServings(ctx, scheduleIds)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return changed, nil
+}
+
+// GetAwayMemberIds returns the members away at the given time
+func (s *MemberService) GetAwayMemberIds(ctx context.Context, at time.Time) ([]int, error) {
+	dbIds, err := s.db.GetAwayMemberIds(ctx, pgtype.Timestamptz{Time: at, Valid: true})
+	if err != nil {
+		log.Default().Printf("Error getting away members: %v", err)
+		return nil, err
+	}
+	ids := make([]int, len(dbIds))
+	for i, id := range dbIds {
+		ids[i] = int(id)
+	}
+	return ids, nil
+}
+
+func toHouseholdMemberModel(dbMember *db.HouseholdMember) *models.HouseholdMember {
+	return &models.HouseholdMember{
+		ID:            int(dbMember.ID),
+		Name:          dbMember.Name,
+		PortionFactor: numericToFloat64(dbMember.PortionFactor),
+		IsGuest:       dbMember.IsGuest,
+		Diet:          dbMember.Diet,
+		Allergens:     dbMember.Allergens,
+	}
+}
+
+// nonNilStrings keeps NOT NULL array columns from receiving NULL
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
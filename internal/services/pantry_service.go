@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+)
+
+type PantryService struct {
+	db *database.DB
+}
+
+func NewPantryService(db *database.DB) *PantryService {
+	return &PantryService{db: db}
+}
+
+func (s *PantryService) GetStaples(ctx context.Context) ([]*models.PantryStaple, error) {
+	dbStaples, err := s.db.GetStaplesWithStock(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting staples: %v", err)
+		return nil, err
+	}
+
+	staples := make([]*models.PantryStaple, len(dbStaples))
+	for i, dbStaple := range dbStaples {
+		staples[i] = &models.PantryStaple{
+			FoodID:   int(dbStaple.ID),
+			FoodName: dbStaple.Name,
+			UnitType: dbStaple.UnitType,
+			BaseUnit: dbStaple.BaseUnit,
+			Quantity: numericToFloat64(dbStaple.Quantity),
+			ParLevel: numericToFloat64(dbStaple.ParLevel),
+		}
+	}
+	return staples, nil
+}
+
+func (s *PantryService) GetStaplesBelowPar(ctx context.Context) ([]*models.PantryStaple, error) {
+	dbStaples, err := s.db.GetStaplesBelowPar(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting staples below par: %v", err)
+		return nil, err
+	}
+
+	staples := make([]*models.PantryStaple, len(dbStaples))
+	for i, dbStaple := range dbStaples {
+		staples[i] = &models.PantryStaple{
+			FoodID:   int(dbStaple.ID),
+			FoodName: dbStaple.Name,
+			UnitType: dbStaple.UnitType,
+			BaseUnit: dbStaple.BaseUnit,
+			Quantity: numericToFloat64(dbStaple.Quantity),
+			ParLevel: numericToFloat64(dbStaple.ParLevel),
+		}
+	}
+	return staples, nil
+}
+
+// SetStock records the current stock and par level of a food and marks it as a staple
+func (s *PantryService) SetStock(ctx context.Context, foodId int, quantity, parLevel float64) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		err := q.SetFoodStaple(ctx, db.SetFoodStapleParams{
+			ID:       int32(foodId),
+			IsStaple: true,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.UpsertPantryStock(ctx, db.UpsertPantryStockParams{
+			FoodID:   int32(foodId),
+			Quantity: utils.Float64ToNumeric(quantity),
+			ParLevel: utils.Float64ToNumeric(parLevel),
+		})
+		return err
+	})
+}
+
+func (s *PantryService) RemoveStaple(ctx context.Context, foodId int) error {
+	return s.db.SetFoodStaple(ctx, db.SetFoodStapleParams{
+		ID:       int32(foodId),
+		IsStaple: false,
+	})
+}
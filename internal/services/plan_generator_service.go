@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"mealplanner/internal/database"
+	"mealplanner/internal/models"
+	"slices"
+	"time"
+)
+
+var (
+	ErrNoMealSlots      = errors.New("no meal slots to fill")
+	ErrNoPlanCandidates = errors.New("no recipes match the required and excluded tags")
+	ErrInvalidPlanRange = errors.New("end date is before start date")
+)
+
+// Extra weight given to a recipe whose ingredients are all in stock when pantry stock is preferred
+const pantryPreferenceWeight = 3.0
+
+type PlanGeneratorService struct {
+	db              *database.DB
+	scheduleService *ScheduleService
+	mealSlotService *MealSlotService
+}
+
+func NewPlanGeneratorService(db *database.DB, scheduleService *ScheduleService, mealSlotService *MealSlotService) *PlanGeneratorService {
+	return &PlanGeneratorService{
+		db:              db,
+		scheduleService: scheduleService,
+		mealSlotService: mealSlotService,
+	}
+}
+
+type planCandidate struct {
+	foodId            int
+	name              string
+	prepMinutes       int
+	costPerServing    float64
+	tags              []string
+	ingredientCount   int
+	pantryIngredients int
+}
+
+// Generate proposes a recipe for every empty meal slot between the constraint dates. Nothing is created,
+// the proposal is meant to be reviewed and passed to ApplyProposal. The same seed and data give the same plan.
+func (s *PlanGeneratorService) Generate(ctx context.Context, constraints *models.PlanConstraints, timeZone *time.Location) (*models.PlanProposal, error) {
+	start := time.Date(constraints.StartDate.Year(), constraints.StartDate.Month(), constraints.StartDate.Day(), 0, 0, 0, 0, timeZone)
+	end := time.Date(constraints.EndDate.Year(), constraints.EndDate.Month(), constraints.EndDate.Day(), 0, 0, 0, 0, timeZone).AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return nil, ErrInvalidPlanRange
+	}
+
+	slots, err := s.mealSlotService.GetMealSlots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(constraints.MealSlotIDs) > 0 {
+		slots = slices.DeleteFunc(slots, func(slot *models.MealSlot) bool {
+			return !slices.Contains(constraints.MealSlotIDs, slot.ID)
+		})
+	}
+	if len(slots) == 0 {
+		return nil, ErrNoMealSlots
+	}
+
+	dbCandidates, err := s.db.GetPlannerCandidates(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting planner candidates: %v", err)
+		return nil, err
+	}
+	candidates := make([]*planCandidate, 0, len(dbCandidates))
+	for _, row := range dbCandidates {
+		candidate := &planCandidate{
+			foodId:            int(row.ID),
+			name:              row.Name,
+			prepMinutes:       int(row.PrepMinutes.Int32),
+			costPerServing:    numericToFloat64(row.CostPerServing),
+			tags:              row.Tags,
+			ingredientCount:   int(row.IngredientCount),
+			pantryIngredients: int(row.PantryIngredientCount),
+		}
+		if matchesTags(candidate, constraints.RequiredTags, constraints.ExcludedTags) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoPlanCandidates
+	}
+
+	// Look past both ends of the range so a recipe planned just before or after still counts as a repeat
+	lookStart := start.AddDate(0, 0, -constraints.NoRepeatDays)
+	lookEnd := end.AddDate(0, 0, constraints.NoRepeatDays)
+	existing, err := s.scheduleService.GetSchedulesForRange(ctx, &lookStart, &lookEnd, timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	return generatePlan(constraints, start, end, slots, candidates, existing), nil
+}
+
+// GetRecipeTags lists every tag in use, for suggestions in the constraint form
+func (s *PlanGeneratorService) GetRecipeTags(ctx context.Context) ([]string, error) {
+	return s.db.GetRecipeTags(ctx)
+}
+
+// ApplyProposal schedules the reviewed meals in one transaction and returns how many were created. Their servings
+// follow who eats like any meal, the proposal's servings only price the plan unless the household has no members.
+func (s *PlanGeneratorService) ApplyProposal(ctx context.Context, meals []*models.ProposedMeal, timeZone *time.Location) (int, error) {
+	if len(meals) == 0 {
+		return 0, nil
+	}
+
+	schedules := make([]*models.Schedule, len(meals))
+	for i, meal := range meals {
+		schedules[i] = &models.Schedule{
+			FoodID:      meal.FoodID,
+			Servings:    meal.Servings,
+			ScheduledAt: meal.ScheduledAt,
+			MealSlotID:  meal.MealSlotID,
+		}
+	}
+	created, err := s.scheduleService.CreateSchedules(ctx, schedules, timeZone)
+	if err != nil {
+		return 0, err
+	}
+	return len(created), nil
+}
+
+// generatePlan walks the days and slots in order, picking a weighted random candidate for every empty slot.
+// Each slot records the first constraint that left it without candidates.
+func generatePlan(constraints *models.PlanConstraints, start, end time.Time, slots []*models.MealSlot, candidates []*planCandidate, existing []*models.Schedule) *models.PlanProposal {
+	rng := rand.New(rand.NewSource(constraints.Seed))
+	proposal := &models.PlanProposal{
+		Constraints: constraints,
+		Meals:       []*models.ProposedMeal{},
+	}
+
+	plannedOn := make(map[int][]time.Time)
+	for _, schedule := range existing {
+		plannedOn[schedule.FoodID] = append(plannedOn[schedule.FoodID], schedule.ScheduledAt)
+	}
+	budgetLeft := constraints.Budget
+
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		weekday := day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+		for _, slot := range slots {
+			scheduledAt := slot.DefaultTimeOn(day)
+			if isSlotTaken(slot, scheduledAt, existing) {
+				continue
+			}
+
+			stages := []struct {
+				reason string
+				keep   func(*planCandidate) bool
+			}{
+				{"No recipe fits the weekday prep time", func(c *planCandidate) bool {
+					// recipes without a prep time are assumed to fit
+					return !weekday || constraints.MaxWeekdayPrepMinutes <= 0 || c.prepMinutes <= constraints.MaxWeekdayPrepMinutes
+				}},
+				{"Every matching recipe was planned too recently", func(c *planCandidate) bool {
+					return !isPlannedWithin(plannedOn[c.foodId], day, constraints.NoRepeatDays)
+				}},
+				{"Not enough budget left", func(c *planCandidate) bool {
+					return constraints.Budget <= 0 || c.costPerServing*constraints.Servings <= budgetLeft
+				}},
+			}
+			remaining := candidates
+			reason := ""
+			for _, stage := range stages {
+				remaining = slices.DeleteFunc(slices.Clone(remaining), func(c *planCandidate) bool {
+					return !stage.keep(c)
+				})
+				if len(remaining) == 0 {
+					reason = stage.reason
+					break
+				}
+			}
+			if len(remaining) == 0 {
+				proposal.Unfilled = append(proposal.Unfilled, &models.UnfilledSlot{
+					Date:         day,
+					MealSlotName: slot.Name,
+					Reason:       reason,
+				})
+				continue
+			}
+
+			pick := pickCandidate(rng, remaining, constraints.PreferPantry)
+			cost := pick.costPerServing * constraints.Servings
+			budgetLeft -= cost
+			plannedOn[pick.foodId] = append(plannedOn[pick.foodId], scheduledAt)
+			proposal.Meals = append(proposal.Meals, &models.ProposedMeal{
+				FoodID:            pick.foodId,
+				FoodName:          pick.name,
+				ScheduledAt:       scheduledAt,
+				Servings:          constraints.Servings,
+				MealSlotID:        slot.ID,
+				MealSlotName:      slot.Name,
+				PrepMinutes:       pick.prepMinutes,
+				Cost:              cost,
+				PantryIngredients: pick.pantryIngredients,
+			})
+		}
+	}
+	return proposal
+}
+
+// pickCandidate draws a candidate at random, weighting recipes by the share of ingredients in stock when preferPantry is set
+func pickCandidate(rng *rand.Rand, candidates []*planCandidate, preferPantry bool) *planCandidate {
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, c := range candidates {
+		weights[i] = 1
+		if preferPantry && c.ingredientCount > 0 {
+			weights[i] += pantryPreferenceWeight * float64(c.pantryIngredients) / float64(c.ingredientCount)
+		}
+		total += weights[i]
+	}
+
+	r := rng.Float64() * total
+	for i, weight := range weights {
+		if r < weight {
+			return candidates[i]
+		}
+		r -= weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+func matchesTags(c *planCandidate, required, excluded []string) bool {
+	for _, tag := range required {
+		if !slices.Contains(c.tags, tag) {
+			return false
+		}
+	}
+	for _, tag := range excluded {
+		if slices.Contains(c.tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPlannedWithin reports whether any of the dates falls within days calendar days of day
+func isPlannedWithin(dates []time.Time, day time.Time, days int) bool {
+	if days <= 0 {
+		return false
+	}
+	for _, date := range dates {
+		if diff := daysBetween(day, date); diff >= -days && diff <= days {
+			return true
+		}
+	}
+	return false
+}
+
+// isSlotTaken matches existing meals by slot, or by the slot's default time for meals without one
+func isSlotTaken(slot *models.MealSlot, scheduledAt time.Time, existing []*models.Schedule) bool {
+	for _, schedule := range existing {
+		if !sameDay(schedule.ScheduledAt, scheduledAt) {
+			continue
+		}
+		if schedule.MealSlotID == slot.ID {
+			return true
+		}
+		if schedule.MealSlotID == 0 && schedule.ScheduledAt.Hour() == scheduledAt.Hour() && schedule.ScheduledAt.Minute() == scheduledAt.Minute() {
+			return true
+		}
+	}
+	return false
+}
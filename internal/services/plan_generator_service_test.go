@@ -0,0 +1,147 @@
+package services
+
+import (
+	"mealplanner/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	testPlanStart = time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC) // a Monday
+	testPlanSlots = []*models.MealSlot{
+		{ID: 1, Name: "Lunch", DefaultTime: "12:00", SortOrder: 1},
+		{ID: 2, Name: "Dinner", DefaultTime: "18:30", SortOrder: 2},
+	}
+	testPlanCandidates = []*planCandidate{
+		{foodId: 1, name: "Pasta", prepMinutes: 20, costPerServing: 2.5, ingredientCount: 4, pantryIngredients: 4},
+		{foodId: 2, name: "Curry", prepMinutes: 45, costPerServing: 3, ingredientCount: 8, pantryIngredients: 2},
+		{foodId: 3, name: "Salad", prepMinutes: 10, costPerServing: 4, ingredientCount: 5},
+		{foodId: 4, name: "Roast", prepMinutes: 90, costPerServing: 6, ingredientCount: 6, pantryIngredients: 3},
+		{foodId: 5, name: "Soup", costPerServing: 1.5, ingredientCount: 3, pantryIngredients: 1},
+	}
+	testPlanExisting = []*models.Schedule{
+		{FoodID: 2, ScheduledAt: testPlanStart.Add(18*time.Hour + 30*time.Minute), MealSlotID: 2},
+	}
+)
+
+func TestGeneratePlan(t *testing.T) {
+	start, end := testPlanStart, testPlanStart.AddDate(0, 0, 7)
+	slots, candidates, existing := testPlanSlots, testPlanCandidates, testPlanExisting
+
+	tests := []struct {
+		name        string
+		constraints models.PlanConstraints
+	}{
+		{"defaults", models.PlanConstraints{Servings: 2, Seed: 1}},
+		{"no repeats", models.PlanConstraints{Servings: 2, NoRepeatDays: 2, Seed: 42}},
+		{"weekday prep", models.PlanConstraints{Servings: 2, MaxWeekdayPrepMinutes: 30, Seed: 3}},
+		{"weekday prep and budget", models.PlanConstraints{Servings: 4, MaxWeekdayPrepMinutes: 30, Budget: 100, Seed: 7}},
+		{"prefer pantry", models.PlanConstraints{Servings: 2, PreferPantry: true, Seed: 1234}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.constraints
+			second := tt.constraints
+			a := generatePlan(&first, start, end, slots, candidates, existing)
+			b := generatePlan(&second, start, end, slots, candidates, existing)
+
+			if len(a.Meals) == 0 {
+				t.Fatal("expected meals to be planned")
+			}
+			if !reflect.DeepEqual(a.Meals, b.Meals) {
+				t.Errorf("meals differ for seed %d:\n%v\n%v", tt.constraints.Seed, mealNames(a), mealNames(b))
+			}
+			if !reflect.DeepEqual(a.Unfilled, b.Unfilled) {
+				t.Errorf("unfilled slots differ for seed %d", tt.constraints.Seed)
+			}
+
+			checkPlanConstraints(t, &tt.constraints, a, existing)
+		})
+	}
+}
+
+// checkPlanConstraints fails the test for every meal of the proposal that breaks one of the constraints
+func checkPlanConstraints(t *testing.T, constraints *models.PlanConstraints, proposal *models.PlanProposal, existing []*models.Schedule) {
+	t.Helper()
+
+	if constraints.NoRepeatDays > 0 {
+		planned := make(map[int][]time.Time)
+		for _, schedule := range existing {
+			planned[schedule.FoodID] = append(planned[schedule.FoodID], schedule.ScheduledAt)
+		}
+		for _, meal := range proposal.Meals {
+			for _, other := range planned[meal.FoodID] {
+				if diff := daysBetween(other, meal.ScheduledAt); diff >= -constraints.NoRepeatDays && diff <= constraints.NoRepeatDays {
+					t.Errorf("%s on %s is planned again within %d days", meal.FoodName, meal.ScheduledAt.Format("Jan 2"), constraints.NoRepeatDays)
+				}
+			}
+			planned[meal.FoodID] = append(planned[meal.FoodID], meal.ScheduledAt)
+		}
+	}
+
+	if constraints.MaxWeekdayPrepMinutes > 0 {
+		for _, meal := range proposal.Meals {
+			weekday := meal.ScheduledAt.Weekday() != time.Saturday && meal.ScheduledAt.Weekday() != time.Sunday
+			if weekday && meal.PrepMinutes > constraints.MaxWeekdayPrepMinutes {
+				t.Errorf("%s takes %d minutes on %s", meal.FoodName, meal.PrepMinutes, meal.ScheduledAt.Weekday())
+			}
+		}
+	}
+
+	if constraints.Budget > 0 {
+		total := 0.0
+		for _, meal := range proposal.Meals {
+			total += meal.Cost
+		}
+		if total > constraints.Budget {
+			t.Errorf("plan costs %.2f, over the budget of %.2f", total, constraints.Budget)
+		}
+	}
+}
+
+func TestGeneratePlanPrefersPantry(t *testing.T) {
+	// Only the pantry weighting tells these two apart
+	candidates := []*planCandidate{
+		{foodId: 1, name: "Shopping", ingredientCount: 4},
+		{foodId: 2, name: "Pantry", ingredientCount: 4, pantryIngredients: 4},
+	}
+	slots := testPlanSlots[:1]
+
+	tests := []struct {
+		name         string
+		preferPantry bool
+		atLeast      float64 // share of first meals that use the pantry recipe
+		atMost       float64
+	}{
+		{"preferred", true, 0.7, 1},
+		{"not preferred", false, 0.35, 0.65},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const runs = 400
+			pantryFirst := 0
+			for seed := int64(1); seed <= runs; seed++ {
+				constraints := &models.PlanConstraints{Servings: 2, PreferPantry: tt.preferPantry, Seed: seed}
+				proposal := generatePlan(constraints, testPlanStart, testPlanStart.AddDate(0, 0, 1), slots, candidates, nil)
+				if len(proposal.Meals) != 1 {
+					t.Fatalf("expected one meal, got %v", mealNames(proposal))
+				}
+				if proposal.Meals[0].FoodID == 2 {
+					pantryFirst++
+				}
+			}
+			if share := float64(pantryFirst) / runs; share < tt.atLeast || share > tt.atMost {
+				t.Errorf("pantry recipe picked first in %.0f%% of plans, want %.0f%% to %.0f%%", share*100, tt.atLeast*100, tt.atMost*100)
+			}
+		})
+	}
+}
+
+func mealNames(proposal *models.PlanProposal) []string {
+	names := make([]string, len(proposal.Meals))
+	for i, meal := range proposal.Meals {
+		names[i] = meal.FoodName
+	}
+	return names
+}
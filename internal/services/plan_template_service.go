@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrEmptyPlanTemplate     = errors.New("no meals scheduled in the selected range")
+	ErrPlanTemplateNameTaken = errors.New("a template with this name already exists")
+)
+
+type PlanTemplateService struct {
+	db              *database.DB
+	scheduleService *ScheduleService
+}
+
+func NewPlanTemplateService(db *database.DB, scheduleService *ScheduleService) *PlanTemplateService {
+	return &PlanTemplateService{
+		db:              db,
+		scheduleService: scheduleService,
+	}
+}
+
+func (s *PlanTemplateService) GetTemplates(ctx context.Context) ([]*models.PlanTemplate, error) {
+	dbTemplates, err := s.db.GetPlanTemplates(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting plan templates: %v", err)
+		return nil, err
+	}
+
+	templates := make([]*models.PlanTemplate, len(dbTemplates))
+	for i, dbTemplate := range dbTemplates {
+		templates[i] = &models.PlanTemplate{
+			ID:         int(dbTemplate.ID),
+			Name:       dbTemplate.Name,
+			Days:       int(dbTemplate.Days),
+			EntryCount: int(dbTemplate.EntryCount),
+			CreatedAt:  dbTemplate.CreatedAt.Time,
+		}
+	}
+	return templates, nil
+}
+
+// GetTemplateById returns the template with its entries
+func (s *PlanTemplateService) GetTemplateById(ctx context.Context, id int) (*models.PlanTemplate, error) {
+	dbTemplate, err := s.db.GetPlanTemplateById(ctx, int32(id))
+	if err != nil {
+		return nil, err
+	}
+
+	dbEntries, err := s.db.GetPlanTemplateEntries(ctx, int32(id))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*models.PlanTemplateEntry, len(dbEntries))
+	for i, dbEntry := range dbEntries {
+		entries[i] = &models.PlanTemplateEntry{
+			ID:           int(dbEntry.ID),
+			FoodID:       int(dbEntry.FoodID),
+			FoodName:     dbEntry.FoodName,
+			DayOffset:    int(dbEntry.DayOffset),
+			TimeOfDay:    pgTimeToTimeOfDay(dbEntry.TimeOfDay),
+			Servings:     numericToFloat64(dbEntry.Servings),
+			MealSlotID:   int(dbEntry.MealSlotID.Int32),
+			MealSlotName: dbEntry.MealSlotName.String,
+		}
+	}
+
+	return &models.PlanTemplate{
+		ID:         int(dbTemplate.ID),
+		Name:       dbTemplate.Name,
+		Days:       int(dbTemplate.Days),
+		EntryCount: len(entries),
+		CreatedAt:  dbTemplate.CreatedAt.Time,
+		Entries:    entries,
+	}, nil
+}
+
+// CreateTemplateFromRange saves the schedules in [start, end) as a template.
+// start and end are local midnights, entries keep their local time of day.
+func (s *PlanTemplateService) CreateTemplateFromRange(ctx context.Context, name string, start, end time.Time, timeZone *time.Location) (*models.PlanTemplate, error) {
+	days := daysBetween(start, end)
+	if days <= 0 {
+		return nil, fmt.Errorf("template range must cover at least one day")
+	}
+
+	schedules, err := s.scheduleService.GetSchedulesForRange(ctx, &start, &end, timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var templateId int32
+	err = s.db.WithTx(ctx, func(q *db.Queries) error {
+		exists, err := q.PlanTemplateNameExists(ctx, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrPlanTemplateNameTaken
+		}
+
+		template, err := q.CreatePlanTemplate(ctx, db.CreatePlanTemplateParams{
+			Name: name,
+			Days: int32(days),
+		})
+		if err != nil {
+			return err
+		}
+		templateId = template.ID
+
+		entryCount := 0
+		for _, schedule := range schedules {
+			dayOffset := daysBetween(start, schedule.ScheduledAt)
+			// the range query is inclusive, skip meals at exactly midnight of the following day
+			if dayOffset < 0 || dayOffset >= days {
+				continue
+			}
+			err := q.CreatePlanTemplateEntry(ctx, db.CreatePlanTemplateEntryParams{
+				TemplateID: template.ID,
+				FoodID:     int32(schedule.FoodID),
+				DayOffset:  int32(dayOffset),
+				TimeOfDay:  timeOfDayToPgTime(schedule.ScheduledAt),
+				Servings:   utils.Float64ToNumeric(schedule.Servings),
+				MealSlotID: pgtype.Int4{Int32: int32(schedule.MealSlotID), Valid: schedule.MealSlotID > 0},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save %s: %w", schedule.FoodName, err)
+			}
+			entryCount++
+		}
+		if entryCount == 0 {
+			return ErrEmptyPlanTemplate
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTemplateById(ctx, int(templateId))
+}
+
+// PreviewTemplate resolves the template onto startDate with the servings scaled by scale.
+// An entry conflicts with an existing meal on the same day in the same slot, or at the same time when it has no slot.
+func (s *PlanTemplateService) PreviewTemplate(ctx context.Context, id int, startDate time.Time, scale float64, timeZone *time.Location) (*models.PlanTemplatePreview, error) {
+	template, err := s.GetTemplateById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, timeZone)
+	end := start.AddDate(0, 0, template.Days)
+	existing, err := s.scheduleService.GetSchedulesForRange(ctx, &start, &end, timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*models.PlanTemplatePreviewItem, len(template.Entries))
+	for i, entry := range template.Entries {
+		scheduledAt := start.AddDate(0, 0, entry.DayOffset)
+		if t, err := time.Parse("15:04", entry.TimeOfDay); err == nil {
+			scheduledAt = time.Date(scheduledAt.Year(), scheduledAt.Month(), scheduledAt.Day(), t.Hour(), t.Minute(), 0, 0, timeZone)
+		}
+
+		item := &models.PlanTemplatePreviewItem{
+			Entry:       entry,
+			ScheduledAt: scheduledAt,
+			Servings:    entry.Servings * scale,
+		}
+		for _, schedule := range existing {
+			if isConflictingSchedule(item, schedule) {
+				item.Conflicts = append(item.Conflicts, schedule)
+			}
+		}
+		items[i] = item
+	}
+
+	return &models.PlanTemplatePreview{
+		Template:  template,
+		StartDate: start,
+		Scale:     scale,
+		Items:     items,
+	}, nil
+}
+
+// ApplyTemplate creates the previewed schedules in one transaction and returns how many were created.
+// When skipConflicts is set, entries that clash with an existing meal are left out.
+func (s *PlanTemplateService) ApplyTemplate(ctx context.Context, id int, startDate time.Time, scale float64, skipConflicts bool, timeZone *time.Location) (int, error) {
+	preview, err := s.PreviewTemplate(ctx, id, startDate, scale, timeZone)
+	if err != nil {
+		return 0, err
+	}
+
+	schedules := make([]*models.Schedule, 0, len(preview.Items))
+	for _, item := range preview.Items {
+		if skipConflicts && item.HasConflict() {
+			continue
+		}
+		schedules = append(schedules, &models.Schedule{
+			FoodID:         item.Entry.FoodID,
+			Servings:       item.Servings,
+			ServingsManual: scale != 1, // a scaled template keeps its servings, otherwise they follow who eats
+			ScheduledAt:    item.ScheduledAt,
+			MealSlotID:     item.Entry.MealSlotID,
+		})
+	}
+	if len(schedules) == 0 {
+		return 0, nil
+	}
+
+	created, err := s.scheduleService.CreateSchedules(ctx, schedules, timeZone)
+	if err != nil {
+		return 0, err
+	}
+	return len(created), nil
+}
+
+func (s *PlanTemplateService) DeleteTemplate(ctx context.Context, id int) error {
+	return s.db.DeletePlanTemplate(ctx, int32(id))
+}
+
+func isConflictingSchedule(item *models.PlanTemplatePreviewItem, schedule *models.Schedule) bool {
+	if !sameDay(item.ScheduledAt, schedule.ScheduledAt) {
+		return false
+	}
+	if item.Entry.MealSlotID > 0 && schedule.MealSlotID > 0 {
+		return item.Entry.MealSlotID == schedule.MealSlotID
+	}
+	return item.ScheduledAt.Hour() == schedule.ScheduledAt.Hour() && item.ScheduledAt.Minute() == schedule.ScheduledAt.Minute()
+}
+
+// daysBetween counts calendar days from a to b, ignoring the time of day and DST shifts
+func daysBetween(a, b time.Time) int {
+	dayA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dayB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dayB.Sub(dayA).Hours() / 24)
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
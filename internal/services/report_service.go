@@ -0,0 +1,130 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ReportService struct {
+	db              *database.DB
+	settingsService *SettingsService
+	foodService     *FoodService
+}
+
+func NewReportService(db *database.DB, settingsService *SettingsService, foodService *FoodService) *ReportService {
+	return &ReportService{
+		db:              db,
+		settingsService: settingsService,
+		foodService:     foodService,
+	}
+}
+
+// GetSpendingReport adds up the priced purchases from the week or month containing from through the one
+// containing to, by period against the budget, by category and by store. Periods without spending are kept.
+func (s *ReportService) GetSpendingReport(ctx context.Context, period string, from, to time.Time) (*models.SpendingReport, error) {
+	settings, err := s.settingsService.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	budget := settings.WeeklyBudget
+	if period == models.ReportMonthly {
+		budget = settings.MonthlyBudget
+	}
+
+	report := &models.SpendingReport{Period: period}
+	report.From, report.To = utils.ReportRange(period, from, to, settings.WeekStart)
+	for start := report.From; start.Before(report.To); start = utils.NextReportPeriod(period, start) {
+		report.Periods = append(report.Periods, &models.SpendingPeriod{
+			Start:  start,
+			End:    utils.NextReportPeriod(period, start),
+			Budget: budget,
+		})
+	}
+
+	purchases, err := s.db.GetPricedPurchases(ctx, db.GetPricedPurchasesParams{
+		FromTime: pgtype.Timestamptz{Time: report.From, Valid: true},
+		ToTime:   pgtype.Timestamptz{Time: report.To, Valid: true},
+	})
+	if err != nil {
+		log.Default().Printf("Error getting purchases: %v", err)
+		return nil, err
+	}
+
+	categories := make(map[string]float64)
+	stores := make(map[string]float64)
+	for _, purchase := range purchases {
+		price := numericToFloat64(purchase.ActualPrice)
+		purchasedAt := purchase.PurchasedAt.Time.In(from.Location())
+		for _, p := range report.Periods {
+			if !purchasedAt.Before(p.Start) && purchasedAt.Before(p.End) {
+				p.Total += price
+			}
+		}
+		report.Total += price
+
+		category := "Uncategorised"
+		if purchase.Category != "" {
+			category = (&models.ShoppingListGroup{Category: purchase.Category}).Title()
+		}
+		categories[category] += price
+		store := purchase.StoreName
+		if store == "" {
+			store = "Unknown store"
+		}
+		stores[store] += price
+	}
+	report.Categories = spendingTotals(categories)
+	report.Stores = spendingTotals(stores)
+	return report, nil
+}
+
+// GetPriceTrend lists every price paid for a food, oldest first, compared per kilogram, liter or base unit
+func (s *ReportService) GetPriceTrend(ctx context.Context, foodId int) (*models.PriceTrend, error) {
+	food, err := s.foodService.GetFoodDetails(ctx, strconv.Itoa(foodId), 0)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.db.GetFoodPriceHistory(ctx, int32(foodId))
+	if err != nil {
+		log.Default().Printf("Error getting price history: %v", err)
+		return nil, err
+	}
+
+	trend := &models.PriceTrend{Food: food, PriceUnit: utils.PriceUnit(food)}
+	for _, row := range history {
+		point := &models.PricePoint{
+			PurchasedAt: row.PurchasedAt.Time,
+			StoreName:   row.StoreName,
+			Price:       numericToFloat64(row.ActualPrice),
+			Quantity:    numericToFloat64(row.Quantity),
+			Unit:        row.Unit,
+		}
+		if quantity, ok := utils.ConvertFoodUnit(food, point.Quantity, point.Unit, trend.PriceUnit); ok && quantity > 0 {
+			point.UnitPrice = point.Price / quantity
+		}
+		trend.Points = append(trend.Points, point)
+	}
+	return trend, nil
+}
+
+// spendingTotals sorts the amounts spent by name into the biggest first
+func spendingTotals(amounts map[string]float64) []*models.SpendingTotal {
+	totals := make([]*models.SpendingTotal, 0, len(amounts))
+	for name, total := range amounts {
+		totals = append(totals, &models.SpendingTotal{Name: name, Total: total})
+	}
+	slices.SortFunc(totals, func(a, b *models.SpendingTotal) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), cmp.Compare(a.Name, b.Name))
+	})
+	return totals
+}
@@ -2,16 +2,22 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"mealplanner/internal/database"
 	"mealplanner/internal/database/db"
 	"mealplanner/internal/models"
 	"mealplanner/internal/utils"
+	"slices"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// ErrNoServings is returned for a meal with neither servings nor attendees to calculate them from
+var ErrNoServings = errors.New("set the servings or choose who is eating")
+
 type ScheduleService struct {
 	db *database.DB
 }
@@ -34,38 +40,114 @@ func (s *ScheduleService) GetSchedulesForRange(ctx context.Context, start, end *
 	return models.ToSchedulesModelFromGetSchedulesInRangeRow(dbSchedules, timeZone), nil
 }
 
-func (s *ScheduleService) CreateSchedule(ctx context.Context, foodId int, servings float64, scheduledAt time.Time, timeZone *time.Location) (*models.Schedule, error) {
-	dbSchedule, err := s.db.CreateSchedule(ctx, db.CreateScheduleParams{
-		FoodID:      pgtype.Int4{Int32: int32(foodId), Valid: true},
-		Servings: utils.Float64ToNumeric(servings),
-		ScheduledAt: pgtype.Timestamptz{Time: scheduledAt, Valid: true},
+// GetSchedulesForRangeInSlots returns the schedules in the range that belong to one of the given meal slots
+func (s *ScheduleService) GetSchedulesForRangeInSlots(ctx context.Context, start, end *time.Time, mealSlotIds []int, timeZone *time.Location) ([]*models.Schedule, error) {
+	mealSlotIdsAsInt32 := make([]int32, len(mealSlotIds))
+	for i, id := range mealSlotIds {
+		mealSlotIdsAsInt32[i] = int32(id)
+	}
+	dbSchedules, err := s.db.GetSchedulesInRangeForSlots(ctx, db.GetSchedulesInRangeForSlotsParams{
+		RangeStart:  pgtype.Timestamptz{Time: *start, Valid: true},
+		RangeEnd:    pgtype.Timestamptz{Time: *end, Valid: true},
+		MealSlotIds: mealSlotIdsAsInt32,
 	})
 	if err != nil {
+		log.Default().Printf("Error getting schedules for meal slots: %s", err)
 		return nil, err
 	}
-	return models.ToScheduleModelFromCreateScheduleRow(dbSchedule, timeZone), nil
+	return models.ToSchedulesModelFromGetSchedulesInRangeForSlotsRow(dbSchedules, timeZone), nil
 }
 
-func (s *ScheduleService) UpdateSchedule(ctx context.Context, scheduleId int, foodId int, servings float64, scheduledAt time.Time, timeZone *time.Location) (*models.Schedule, error) {
-    dbSchedule, err := s.db.UpdateSchedule(ctx, db.UpdateScheduleParams{
-        ID:          int32(scheduleId),
-        FoodID:      pgtype.Int4{Int32: int32(foodId), Valid: true},
-        Servings:    utils.Float64ToNumeric(servings),
-        ScheduledAt: pgtype.Timestamptz{Time: scheduledAt, Valid: true},
+// CreateSchedule schedules a meal for the attendees, leaving out anyone away at the time. Servings of 0 are
+// calculated from the attendees' portion factors, anything else is kept as typed.
+func (s *ScheduleService) CreateSchedule(ctx context.Context, foodId int, servings float64, scheduledAt time.Time, mealSlotId int, attendeeIds []int, timeZone *time.Location) (*models.Schedule, error) {
+	var schedule *models.Schedule
+	err := s.db.WithTx(ctx, func(q *db.Queries) error {
+		var err error
+		schedule, err = createSchedule(ctx, q, foodId, servings, scheduledAt, mealSlotId, attendeeIds, timeZone)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// CreateSchedules creates all the given schedules in a single transaction, either all of them are created or none.
+// Everyone but guests eats them, like a meal added by hand, and servings follow who eats unless the schedule's
+// servings are marked manual or the household has no members.
+func (s *ScheduleService) CreateSchedules(ctx context.Context, schedules []*models.Schedule, timeZone *time.Location) ([]*models.Schedule, error) {
+	created := make([]*models.Schedule, 0, len(schedules))
+	err := s.db.WithTx(ctx, func(q *db.Queries) error {
+		members, err := q.GetHouseholdMembers(ctx)
+		if err != nil {
+			return err
+		}
+		attendeeIds := []int{}
+		for _, member := range members {
+			if !member.IsGuest {
+				attendeeIds = append(attendeeIds, int(member.ID))
+			}
+		}
+
+		for _, schedule := range schedules {
+			servings := schedule.Servings
+			if !schedule.ServingsManual && len(attendeeIds) > 0 {
+				servings = 0
+			}
+			dbSchedule, err := createSchedule(ctx, q, schedule.FoodID, servings, schedule.ScheduledAt.UTC(), schedule.MealSlotID, attendeeIds, timeZone)
+			if err != nil {
+				return err
+			}
+			created = append(created, dbSchedule)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Default().Printf("Error creating schedules: %s", err)
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateSchedule saves the meal and its attendees, servings and who is away work the same as in CreateSchedule
+func (s *ScheduleService) UpdateSchedule(ctx context.Context, scheduleId int, foodId int, servings float64, scheduledAt time.Time, mealSlotId int, attendeeIds []int, timeZone *time.Location) (*models.Schedule, error) {
+    if servings <= 0 && len(attendeeIds) == 0 {
+        return nil, ErrNoServings
+    }
+
+    var schedule *models.Schedule
+    err := s.db.WithTx(ctx, func(q *db.Queries) error {
+        _, err := q.UpdateSchedule(ctx, db.UpdateScheduleParams{
+            ID:          int32(scheduleId),
+            FoodID:      pgtype.Int4{Int32: int32(foodId), Valid: true},
+            Servings:    utils.Float64ToNumeric(max(servings, 1)), // placeholder until recalculated
+            ScheduledAt: pgtype.Timestamptz{Time: scheduledAt, Valid: true},
+            MealSlotID:  pgtype.Int4{Int32: int32(mealSlotId), Valid: mealSlotId > 0},
+        })
+        if err != nil {
+            return err
+        }
+        attendeeIds, err = withoutAwayMembers(ctx, q, scheduledAt, attendeeIds)
+        if err != nil {
+            return err
+        }
+        if err := setAttendance(ctx, q, int32(scheduleId), servings, attendeeIds); err != nil {
+            return err
+        }
+        schedule, err = getScheduleById(ctx, q, scheduleId, timeZone)
+        return err
     })
     if err != nil {
         return nil, err
     }
-    return models.ToScheduleModelFromUpdateScheduleRow(dbSchedule, timeZone), nil
+    return schedule, nil
 }
 
+// GetScheduleById returns the schedule along with its attendees
 func (s *ScheduleService) GetScheduleById(ctx context.Context, scheduleId int, timeZone *time.Location) (*models.Schedule, error) {
 	log.Default().Printf("Getting schedule %d...", scheduleId)
-	dbSchedule, err := s.db.GetScheduleById(ctx, int32(scheduleId))
-	if err != nil {
-		return nil, err
-	}
-	return models.ToScheduleModelFromGetScheduleByIdRow(dbSchedule, timeZone), nil
+	return getScheduleById(ctx, s.db.Queries, scheduleId, timeZone)
 }
 
 func (s *ScheduleService) DeleteSchedules(ctx context.Context, scheduleIds []int) error {
@@ -82,3 +164,270 @@ func (s *ScheduleService) DeleteSchedulesInRange(ctx context.Context, start, end
 		ScheduledAt_2: pgtype.Timestamptz{Time: end, Valid: true},
 	})
 }
+
+// MoveSchedules moves the given schedules onto date. Each keeps its own time of day
+// unless timeOfDay ("15:04") is given.
+func (s *ScheduleService) MoveSchedules(ctx context.Context, scheduleIds []int, date time.Time, timeOfDay string, timeZone *time.Location) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		schedules, err := getSchedulesByIds(ctx, q, scheduleIds, timeZone)
+		if err != nil {
+			return err
+		}
+		for _, schedule := range schedules {
+			scheduledAt, err := rescheduleOnDate(schedule.ScheduledAt, date, timeOfDay, timeZone)
+			if err != nil {
+				return err
+			}
+			err = q.UpdateScheduleTime(ctx, db.UpdateScheduleTimeParams{
+				ID:          int32(schedule.ID),
+				ScheduledAt: pgtype.Timestamptz{Time: scheduledAt.UTC(), Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CopySchedules duplicates the given schedules onto date, keeping food, servings and meal slot
+func (s *ScheduleService) CopySchedules(ctx context.Context, scheduleIds []int, date time.Time, timeOfDay string, timeZone *time.Location) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		schedules, err := getSchedulesByIds(ctx, q, scheduleIds, timeZone)
+		if err != nil {
+			return err
+		}
+		for _, schedule := range schedules {
+			scheduledAt, err := rescheduleOnDate(schedule.ScheduledAt, date, timeOfDay, timeZone)
+			if err != nil {
+				return err
+			}
+			copied, err := q.CreateSchedule(ctx, db.CreateScheduleParams{
+				FoodID:      pgtype.Int4{Int32: int32(schedule.FoodID), Valid: true},
+				Servings:    utils.Float64ToNumeric(schedule.Servings),
+				ScheduledAt: pgtype.Timestamptz{Time: scheduledAt.UTC(), Valid: true},
+				MealSlotID:  pgtype.Int4{Int32: int32(schedule.MealSlotID), Valid: schedule.MealSlotID > 0},
+			})
+			if err != nil {
+				return err
+			}
+			// The copy is eaten by the same people and cooked the same way
+			err = q.CopyScheduleAttendees(ctx, db.CopyScheduleAttendeesParams{
+				ToScheduleID:   copied.ID,
+				FromScheduleID: int32(schedule.ID),
+			})
+			if err != nil {
+				return err
+			}
+			err = q.CopyScheduleSubstitutions(ctx, db.CopyScheduleSubstitutionsParams{
+				ToScheduleID:   copied.ID,
+				FromScheduleID: int32(schedule.ID),
+			})
+			if err != nil {
+				return err
+			}
+			err = q.SetScheduleServingsManual(ctx, db.SetScheduleServingsManualParams{
+				ID:             copied.ID,
+				ServingsManual: schedule.ServingsManual,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ShiftSchedulesInRange moves every schedule in [start, end) by days calendar days, keeping local times.
+// Returns the number of schedules moved.
+func (s *ScheduleService) ShiftSchedulesInRange(ctx context.Context, start, end time.Time, days int, timeZone *time.Location) (int, error) {
+	shifted := 0
+	err := s.db.WithTx(ctx, func(q *db.Queries) error {
+		schedules, err := getSchedulesInRange(ctx, q, start, end, timeZone)
+		if err != nil {
+			return err
+		}
+		for _, schedule := range schedules {
+			err := q.UpdateScheduleTime(ctx, db.UpdateScheduleTimeParams{
+				ID:          int32(schedule.ID),
+				ScheduledAt: pgtype.Timestamptz{Time: schedule.ScheduledAt.AddDate(0, 0, days).UTC(), Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			shifted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return shifted, nil
+}
+
+// SwapDays exchanges all meals of dayA with the meals of dayB, keeping their times of day
+func (s *ScheduleService) SwapDays(ctx context.Context, dayA, dayB time.Time, timeZone *time.Location) error {
+	startA := time.Date(dayA.Year(), dayA.Month(), dayA.Day(), 0, 0, 0, 0, timeZone)
+	startB := time.Date(dayB.Year(), dayB.Month(), dayB.Day(), 0, 0, 0, 0, timeZone)
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		// Load both days before updating anything so moved meals aren't picked up twice
+		schedulesA, err := getSchedulesInRange(ctx, q, startA, startA.AddDate(0, 0, 1), timeZone)
+		if err != nil {
+			return err
+		}
+		schedulesB, err := getSchedulesInRange(ctx, q, startB, startB.AddDate(0, 0, 1), timeZone)
+		if err != nil {
+			return err
+		}
+
+		moves := []struct {
+			schedules []*models.Schedule
+			to        time.Time
+		}{
+			{schedulesA, startB},
+			{schedulesB, startA},
+		}
+		for _, move := range moves {
+			for _, schedule := range move.schedules {
+				scheduledAt, _ := rescheduleOnDate(schedule.ScheduledAt, move.to, "", timeZone)
+				err := q.UpdateScheduleTime(ctx, db.UpdateScheduleTimeParams{
+					ID:          int32(schedule.ID),
+					ScheduledAt: pgtype.Timestamptz{Time: scheduledAt.UTC(), Valid: true},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func getScheduleById(ctx context.Context, q *db.Queries, scheduleId int, timeZone *time.Location) (*models.Schedule, error) {
+	dbSchedule, err := q.GetScheduleById(ctx, int32(scheduleId))
+	if err != nil {
+		return nil, err
+	}
+	schedule := models.ToScheduleModelFromGetScheduleByIdRow(dbSchedule, timeZone)
+
+	attendeeIds, err := q.GetScheduleAttendeeIds(ctx, int32(scheduleId))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range attendeeIds {
+		schedule.AttendeeIDs = append(schedule.AttendeeIDs, int(id))
+	}
+	return schedule, nil
+}
+
+// createSchedule is CreateSchedule within the caller's transaction
+func createSchedule(ctx context.Context, q *db.Queries, foodId int, servings float64, scheduledAt time.Time, mealSlotId int, attendeeIds []int, timeZone *time.Location) (*models.Schedule, error) {
+	if servings <= 0 && len(attendeeIds) == 0 {
+		return nil, ErrNoServings
+	}
+
+	dbSchedule, err := q.CreateSchedule(ctx, db.CreateScheduleParams{
+		FoodID:      pgtype.Int4{Int32: int32(foodId), Valid: true},
+		Servings:    utils.Float64ToNumeric(max(servings, 1)), // placeholder until recalculated
+		ScheduledAt: pgtype.Timestamptz{Time: scheduledAt, Valid: true},
+		MealSlotID:  pgtype.Int4{Int32: int32(mealSlotId), Valid: mealSlotId > 0},
+	})
+	if err != nil {
+		return nil, err
+	}
+	attendeeIds, err = withoutAwayMembers(ctx, q, scheduledAt, attendeeIds)
+	if err != nil {
+		return nil, err
+	}
+	if err := setAttendance(ctx, q, dbSchedule.ID, servings, attendeeIds); err != nil {
+		return nil, err
+	}
+	return getScheduleById(ctx, q, int(dbSchedule.ID), timeZone)
+}
+
+// withoutAwayMembers leaves out the attendees away at scheduledAt
+func withoutAwayMembers(ctx context.Context, q *db.Queries, scheduledAt time.Time, attendeeIds []int) ([]int, error) {
+	awayIds, err := q.GetAwayMemberIds(ctx, pgtype.Timestamptz{Time: scheduledAt, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(slices.Clone(attendeeIds), func(id int) bool {
+		return slices.Contains(awayIds, int32(id))
+	}), nil
+}
+
+// setAttendance replaces the attendees of a schedule. Servings of 0 switch the schedule to servings calculated
+// from the attendees, anything else marks the typed servings as a manual override.
+func setAttendance(ctx context.Context, q *db.Queries, scheduleId int32, servings float64, attendeeIds []int) error {
+	err := q.SetScheduleServingsManual(ctx, db.SetScheduleServingsManualParams{
+		ID:             scheduleId,
+		ServingsManual: servings > 0,
+	})
+	if err != nil {
+		return err
+	}
+	if err := q.DeleteScheduleAttendees(ctx, scheduleId); err != nil {
+		return err
+	}
+	for _, memberId := range attendeeIds {
+		err := q.AddScheduleAttendee(ctx, db.AddScheduleAttendeeParams{
+			ScheduleID: scheduleId,
+			MemberID:   int32(memberId),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return q.RecalculateScheduleServings(ctx, []int32{scheduleId})
+}
+
+func getSchedulesByIds(ctx context.Context, q *db.Queries, scheduleIds []int, timeZone *time.Location) ([]*models.Schedule, error) {
+	scheduleIdsAsInt32 := make([]int32, len(scheduleIds))
+	for i, id := range scheduleIds {
+		scheduleIdsAsInt32[i] = int32(id)
+	}
+	// The same meal picked twice is still one schedule
+	slices.Sort(scheduleIdsAsInt32)
+	scheduleIdsAsInt32 = slices.Compact(scheduleIdsAsInt32)
+
+	dbSchedules, err := q.GetSchedulesByIds(ctx, scheduleIdsAsInt32)
+	if err != nil {
+		return nil, err
+	}
+	if len(dbSchedules) != len(scheduleIdsAsInt32) {
+		return nil, fmt.Errorf("one or more schedules were not found")
+	}
+	return models.ToSchedulesModelFromGetSchedulesByIdsRow(dbSchedules, timeZone), nil
+}
+
+// getSchedulesInRange returns the schedules in [start, end), the range query itself is inclusive of end
+func getSchedulesInRange(ctx context.Context, q *db.Queries, start, end time.Time, timeZone *time.Location) ([]*models.Schedule, error) {
+	dbSchedules, err := q.GetSchedulesInRange(ctx, db.GetSchedulesInRangeParams{
+		ScheduledAt:   pgtype.Timestamptz{Time: start, Valid: true},
+		ScheduledAt_2: pgtype.Timestamptz{Time: end, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]*models.Schedule, 0, len(dbSchedules))
+	for _, schedule := range models.ToSchedulesModelFromGetSchedulesInRangeRow(dbSchedules, timeZone) {
+		if schedule.ScheduledAt.Before(end) {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+// rescheduleOnDate puts scheduledAt on date, at timeOfDay ("15:04") if given, else at its current local time
+func rescheduleOnDate(scheduledAt, date time.Time, timeOfDay string, timeZone *time.Location) (time.Time, error) {
+	hour, minute := scheduledAt.In(timeZone).Hour(), scheduledAt.In(timeZone).Minute()
+	if timeOfDay != "" {
+		t, err := time.Parse("15:04", timeOfDay)
+		if err != nil {
+			return time.Time{}, err
+		}
+		hour, minute = t.Hour(), t.Minute()
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, timeZone), nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type SettingsService struct {
+	db *database.DB
+}
+
+func NewSettingsService(db *database.DB) *SettingsService {
+	return &SettingsService{db: db}
+}
+
+func (s *SettingsService) GetSettings(ctx context.Context) (*models.HouseholdSettings, error) {
+	dbSettings, err := s.db.GetHouseholdSettings(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting household settings: %v", err)
+		return nil, err
+	}
+	return toSettingsModel(dbSettings), nil
+}
+
+func (s *SettingsService) UpdateWeekStart(ctx context.Context, weekStart time.Weekday) (*models.HouseholdSettings, error) {
+	dbSettings, err := s.db.UpdateWeekStart(ctx, int32(weekStart))
+	if err != nil {
+		return nil, err
+	}
+	return toSettingsModel(dbSettings), nil
+}
+
+func (s *SettingsService) UpdateVarietySettings(ctx context.Context, windowDays int, tags []string, maxMealsPerDay, maxDailyPrepMinutes int) (*models.HouseholdSettings, error) {
+	dbSettings, err := s.db.UpdateVarietySettings(ctx, db.UpdateVarietySettingsParams{
+		VarietyWindowDays:   int32(windowDays),
+		VarietyTags:         nonNilStrings(tags),
+		MaxMealsPerDay:      int32(maxMealsPerDay),
+		MaxDailyPrepMinutes: int32(maxDailyPrepMinutes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toSettingsModel(dbSettings), nil
+}
+
+func (s *SettingsService) UpdateBudget(ctx context.Context, weeklyBudget, monthlyBudget float64) (*models.HouseholdSettings, error) {
+	dbSettings, err := s.db.UpdateBudget(ctx, db.UpdateBudgetParams{
+		WeeklyBudget:  utils.Float64ToNumeric(weeklyBudget),
+		MonthlyBudget: utils.Float64ToNumeric(monthlyBudget),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toSettingsModel(dbSettings), nil
+}
+
+// RotateCalendarFeedToken enables the calendar feed under a fresh secret token,
+// any previously shared feed URL stops working
+func (s *SettingsService) RotateCalendarFeedToken(ctx context.Context) (*models.HouseholdSettings, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	dbSettings, err := s.db.UpdateCalendarFeedToken(ctx, pgtype.Text{String: hex.EncodeToString(token), Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	return toSettingsModel(dbSettings), nil
+}
+
+func (s *SettingsService) RevokeCalendarFeedToken(ctx context.Context) (*models.HouseholdSettings, error) {
+	dbSettings, err := s.db.UpdateCalendarFeedToken(ctx, pgtype.Text{})
+	if err != nil {
+		return nil, err
+	}
+	return toSettingsModel(dbSettings), nil
+}
+
+// IsValidCalendarFeedToken reports whether token matches the active feed token
+func (s *SettingsService) IsValidCalendarFeedToken(ctx context.Context, token string) (bool, error) {
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !settings.CalendarFeedEnabled() {
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(settings.CalendarFeedToken)) == 1, nil
+}
+
+func toSettingsModel(dbSettings *db.HouseholdSetting) *models.HouseholdSettings {
+	return &models.HouseholdSettings{
+		WeekStart:           time.Weekday(dbSettings.WeekStart),
+		CalendarFeedToken:   dbSettings.CalendarFeedToken.String,
+		VarietyWindowDays:   int(dbSettings.VarietyWindowDays),
+		VarietyTags:         dbSettings.VarietyTags,
+		MaxMealsPerDay:      int(dbSettings.MaxMealsPerDay),
+		MaxDailyPrepMinutes: int(dbSettings.MaxDailyPrepMinutes),
+		WeeklyBudget:        numericToFloat64(dbSettings.WeeklyBudget),
+		MonthlyBudget:       numericToFloat64(dbSettings.MonthlyBudget),
+	}
+}
@@ -1,29 +1,49 @@
 package services
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"mealplanner/internal/database"
 	"mealplanner/internal/database/db"
 	"mealplanner/internal/models"
 	"mealplanner/internal/utils"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// ErrContributionNotFound is returned for a source that doesn't add to the item, or an item or source on
+// another list
+var (
+	ErrContributionNotFound = errors.New("the source doesn't add to this item")
+	ErrSubstitutionUnit     = errors.New("the substitution can't be converted from the recipe's unit")
+)
+
 type ShoppingService struct {
-	db              *database.DB
-	scheduleService *ScheduleService
-	foodService     *FoodService
+	db                  *database.DB
+	scheduleService     *ScheduleService
+	foodService         *FoodService
+	pantryService       *PantryService
+	substitutionService *SubstitutionService
+	storeService        *StoreService
 }
 
-func NewShoppingService(db *database.DB, scheduleService *ScheduleService, foodService *FoodService) *ShoppingService {
+func NewShoppingService(db *database.DB, scheduleService *ScheduleService, foodService *FoodService, pantryService *PantryService, substitutionService *SubstitutionService, storeService *StoreService) *ShoppingService {
 	return &ShoppingService{
-		db:              db,
-		scheduleService: scheduleService,
-		foodService:     foodService,
+		db:                  db,
+		scheduleService:     scheduleService,
+		foodService:         foodService,
+		pantryService:       pantryService,
+		substitutionService: substitutionService,
+		storeService:        storeService,
 	}
 }
 
@@ -48,25 +68,81 @@ func (s *ShoppingService) CreateShoppingList(ctx context.Context, name, notes st
 	}, nil
 }
 
-func (s *ShoppingService) GetShoppingLists(ctx context.Context) ([]*models.ShoppingList, error) {
-	dbLists, err := s.db.GetShoppingLists(ctx)
+// GetShoppingLists gets the active lists, or the archived ones, most recently changed first
+func (s *ShoppingService) GetShoppingLists(ctx context.Context, archived bool) ([]*models.ShoppingList, error) {
+	dbLists, err := s.db.GetShoppingLists(ctx, archived)
+	if err != nil {
+		return nil, err
+	}
+
+	listIds := make([]int32, len(dbLists))
+	for i, dbList := range dbLists {
+		listIds[i] = dbList.ID
+	}
+	dbStats, err := s.db.GetShoppingListsStats(ctx, listIds)
 	if err != nil {
+		log.Default().Printf("Error getting shopping list stats: %v", err)
 		return nil, err
 	}
+	stats := make(map[int32]*db.GetShoppingListsStatsRow, len(dbStats))
+	for _, row := range dbStats {
+		stats[row.ShoppingListID.Int32] = row
+	}
 
 	lists := make([]*models.ShoppingList, len(dbLists))
 	for i, dbList := range dbLists {
 		lists[i] = &models.ShoppingList{
-			ID:        int(dbList.ID),
-			Name:      dbList.Name,
-			Notes:     dbList.Notes.String,
-			CreatedAt: dbList.CreatedAt.Time,
-			UpdatedAt: dbList.UpdatedAt.Time,
+			ID:         int(dbList.ID),
+			Name:       dbList.Name,
+			Notes:      dbList.Notes.String,
+			CreatedAt:  dbList.CreatedAt.Time,
+			UpdatedAt:  dbList.UpdatedAt.Time,
+			ArchivedAt: dbList.ArchivedAt.Time,
+			Progress:   &models.ShoppingListProgress{ListID: int(dbList.ID)},
+		}
+		// The overview only shows the totals, GetShoppingListProgress adds the sources
+		if row := stats[dbList.ID]; row != nil {
+			lists[i].Progress.TotalItems = int(row.TotalItems)
+			lists[i].Progress.PurchasedItems = int(row.PurchasedItems)
+			lists[i].Progress.Spent = numericToFloat64(row.TotalSpent)
 		}
 	}
 	return lists, nil
 }
 
+// GetShoppingListProgress counts the purchased items of a list, overall and by source, and what's been spent.
+// It doesn't estimate the list's cost, GetShoppingListById fills that in from the items.
+func (s *ShoppingService) GetShoppingListProgress(ctx context.Context, listId int) (*models.ShoppingListProgress, error) {
+	id := pgtype.Int4{Int32: int32(listId), Valid: true}
+	stats, err := s.db.GetShoppingListStats(ctx, id)
+	if err != nil {
+		log.Default().Printf("Error getting shopping list stats: %v", err)
+		return nil, err
+	}
+	dbSources, err := s.db.GetShoppingListProgress(ctx, id)
+	if err != nil {
+		log.Default().Printf("Error getting shopping list progress: %v", err)
+		return nil, err
+	}
+
+	progress := &models.ShoppingListProgress{
+		ListID:         listId,
+		TotalItems:     int(stats.TotalItems),
+		PurchasedItems: int(stats.PurchasedItems),
+		Spent:          numericToFloat64(stats.TotalSpent),
+		Sources:        make([]*models.SourceProgress, len(dbSources)),
+	}
+	for i, row := range dbSources {
+		progress.Sources[i] = &models.SourceProgress{
+			SourceName:     row.SourceName,
+			SourceType:     row.SourceType,
+			TotalItems:     int(row.TotalItems),
+			PurchasedItems: int(row.PurchasedItems),
+		}
+	}
+	return progress, nil
+}
+
 func (s *ShoppingService) GetShoppingListById(ctx context.Context, id int) (*models.ShoppingList, error) {
 	dbList, err := s.db.GetShoppingListById(ctx, int32(id))
 	if err != nil {
@@ -74,34 +150,286 @@ func (s *ShoppingService) GetShoppingListById(ctx context.Context, id int) (*mod
 	}
 
 	list := &models.ShoppingList{
-		ID:        int(dbList.ID),
-		Name:      dbList.Name,
-		Notes:     dbList.Notes.String,
-		CreatedAt: dbList.CreatedAt.Time,
-		UpdatedAt: dbList.UpdatedAt.Time,
+		ID:         int(dbList.ID),
+		Name:       dbList.Name,
+		Notes:      dbList.Notes.String,
+		CreatedAt:  dbList.CreatedAt.Time,
+		UpdatedAt:  dbList.UpdatedAt.Time,
+		StoreID:    int(dbList.StoreID.Int32),
+		SplitBy:    dbList.SplitBy,
+		ArchivedAt: dbList.ArchivedAt.Time,
 	}
 
 	// Get items with sources
-	items, err := s.getShoppingListItemsWithSources(ctx, id)
+	items, err := s.getShoppingListItemsWithSources(ctx, list)
 	if err != nil {
 		return nil, err
 	}
-	list.Items = items
+
+	// Split the items by store when asked, and order them by each store's layout
+	stores, err := s.storeService.GetStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var listStore *models.Store
+	for _, store := range stores {
+		if store.ID == list.StoreID {
+			listStore = store
+		}
+	}
+	if list.Split() {
+		list.Sections = models.SplitByStore(items, stores, listStore)
+	} else {
+		var aisles []string
+		if listStore != nil {
+			aisles = listStore.Aisles
+		}
+		list.Sections = []*models.ShoppingListSection{{Store: listStore, Groups: models.GroupByAisle(items, aisles)}}
+	}
+	list.Items = make([]*models.ShoppingListItem, 0, len(items))
+	for _, section := range list.Sections {
+		for _, group := range section.Groups {
+			list.Items = append(list.Items, group.Items...)
+		}
+	}
 
 	// Get sources
-	sources, err := s.getShoppingListSources(ctx, id)
+	sources, err := getShoppingListSources(ctx, s.db.Queries, id)
 	if err != nil {
 		return nil, err
 	}
 	list.Sources = sources
 
+	list.Progress, err = s.GetShoppingListProgress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range list.Items {
+		list.Progress.Estimated += item.Estimate()
+		if !item.Purchased {
+			list.Progress.RemainingEstimate += item.Estimate()
+		}
+		if item.Shortfall() > 0 {
+			list.Progress.ShortItems++
+		}
+	}
+
 	return list, nil
 }
 
+func (s *ShoppingService) UpdateShoppingList(ctx context.Context, id int, name, notes string) error {
+	_, err := s.db.UpdateShoppingList(ctx, db.UpdateShoppingListParams{
+		ID:    int32(id),
+		Name:  name,
+		Notes: pgtype.Text{String: notes, Valid: notes != ""},
+	})
+	return err
+}
+
 func (s *ShoppingService) DeleteShoppingList(ctx context.Context, id int) error {
 	return s.db.DeleteShoppingList(ctx, int32(id))
 }
 
+// SetShoppingListArchived archives a list or brings it back into the active lists
+func (s *ShoppingService) SetShoppingListArchived(ctx context.Context, id int, archived bool) error {
+	return s.db.SetShoppingListArchived(ctx, db.SetShoppingListArchivedParams{
+		ID:       int32(id),
+		Archived: archived,
+	})
+}
+
+// DuplicateShoppingList starts a new list with the items of another, nothing bought yet. The items come from a
+// single copy source pointing back at the original, so they can be removed together like any other source.
+func (s *ShoppingService) DuplicateShoppingList(ctx context.Context, id int) (*models.ShoppingList, error) {
+	original, err := s.GetShoppingListById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var listId int32
+	err = s.db.WithTx(ctx, func(q *db.Queries) error {
+		listId, err = createListLike(ctx, q, original, original.Name+" (copy)")
+		if err != nil {
+			return err
+		}
+		return s.copyItems(ctx, q, listId, original, fmt.Sprintf("Copy of %s", original.Name), original.Items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetShoppingListById(ctx, int(listId))
+}
+
+// CloseShoppingList ends a shopping trip. What's still to buy, the items not bought and the shortfall of items
+// bought short, carries over to the active list targetId, or to a new list when targetId is 0, and the closed
+// list is archived. It returns the list carried over to, nil when there was nothing left to buy.
+func (s *ShoppingService) CloseShoppingList(ctx context.Context, id, targetId int) (*models.ShoppingList, error) {
+	list, err := s.GetShoppingListById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if list.Archived() {
+		return nil, fmt.Errorf("list %d is already closed", id)
+	}
+	if targetId == id {
+		return nil, fmt.Errorf("can't carry list %d over to itself", id)
+	}
+	if targetId != 0 {
+		target, err := s.GetShoppingListById(ctx, targetId)
+		if err != nil {
+			return nil, err
+		}
+		if target.Archived() {
+			return nil, fmt.Errorf("can't carry over to archived list %d", targetId)
+		}
+	}
+	carryOver := list.CarryOver()
+
+	listId := int32(targetId)
+	err = s.db.WithTx(ctx, func(q *db.Queries) error {
+		if len(carryOver) > 0 {
+			if targetId == 0 {
+				listId, err = createListLike(ctx, q, list, list.Name+" (carried over)")
+				if err != nil {
+					return err
+				}
+			}
+			err = s.copyItems(ctx, q, listId, list, fmt.Sprintf("Carried over from %s", list.Name), carryOver)
+			if err != nil {
+				return err
+			}
+		}
+
+		return q.SetShoppingListArchived(ctx, db.SetShoppingListArchivedParams{
+			ID:       int32(id),
+			Archived: true,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(carryOver) == 0 {
+		return nil, nil
+	}
+	return s.GetShoppingListById(ctx, int(listId))
+}
+
+// createListLike starts an empty list with the notes, store and split of another
+func createListLike(ctx context.Context, q *db.Queries, original *models.ShoppingList, name string) (int32, error) {
+	dbList, err := q.CreateShoppingList(ctx, db.CreateShoppingListParams{
+		Name:  name,
+		Notes: pgtype.Text{String: original.Notes, Valid: original.Notes != ""},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create list: %w", err)
+	}
+
+	err = q.SetShoppingListStore(ctx, db.SetShoppingListStoreParams{
+		ID:      dbList.ID,
+		StoreID: utils.OptionalInt4(original.StoreID),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to set store: %w", err)
+	}
+	err = q.SetShoppingListSplit(ctx, db.SetShoppingListSplitParams{
+		ID:      dbList.ID,
+		SplitBy: original.SplitBy,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to set split: %w", err)
+	}
+	return dbList.ID, nil
+}
+
+// copyItems adds items of another list to listId, not bought yet, under a single copy source pointing back at
+// the original so they can be removed together like any other source
+func (s *ShoppingService) copyItems(ctx context.Context, q *db.Queries, listId int32, original *models.ShoppingList, sourceName string, items []*models.ShoppingListItem) error {
+	source, err := q.CreateShoppingListSource(ctx, db.CreateShoppingListSourceParams{
+		ShoppingListID: pgtype.Int4{Int32: listId, Valid: true},
+		SourceType:     "copy",
+		SourceID:       pgtype.Int4{Int32: int32(original.ID), Valid: true},
+		SourceName:     sourceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create copy source: %w", err)
+	}
+
+	collected := make(map[string]*CollectedIngredient)
+	for _, item := range items {
+		if item.FreeText() {
+			err = addFreeTextItem(ctx, q, listId, source.ID, &models.AddFreeTextItemRequest{
+				Name:     item.FoodName,
+				Quantity: item.Quantity,
+				Unit:     item.Unit,
+				Category: item.Category,
+				Notes:    item.Notes,
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		key := fmt.Sprintf("%d|%s", item.FoodID, item.Unit)
+		if existing, ok := collected[key]; ok {
+			existing.Quantity += item.Quantity
+			continue
+		}
+		collected[key] = &CollectedIngredient{
+			FoodID:   item.FoodID,
+			FoodName: item.FoodName,
+			Unit:     item.Unit,
+			UnitType: item.UnitType,
+			Quantity: item.Quantity,
+		}
+	}
+	return s.batchInsertIngredients(ctx, q, listId, int(source.ID), collected)
+}
+
+// How long a list is left alone before the cleanup puts it away
+const (
+	archiveCompletedListsAfter = 2 * 24 * time.Hour  // everything bought
+	archiveStaleListsAfter     = 30 * 24 * time.Hour // still has items to buy
+	deleteEmptyListsAfter      = 7 * 24 * time.Hour  // nothing on it
+)
+
+// CleanupShoppingLists archives lists that were finished a couple of days ago or haven't been touched in a month,
+// and deletes lists that have stayed empty for a week
+func (s *ShoppingService) CleanupShoppingLists(ctx context.Context) error {
+	now := time.Now()
+	archived, err := s.db.ArchiveOldShoppingLists(ctx, db.ArchiveOldShoppingListsParams{
+		CompletedBefore: pgtype.Timestamptz{Time: now.Add(-archiveCompletedListsAfter), Valid: true},
+		StaleBefore:     pgtype.Timestamptz{Time: now.Add(-archiveStaleListsAfter), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive shopping lists: %w", err)
+	}
+	deleted, err := s.db.DeleteEmptyShoppingLists(ctx, pgtype.Timestamptz{Time: now.Add(-deleteEmptyListsAfter), Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to delete empty shopping lists: %w", err)
+	}
+	if archived > 0 || deleted > 0 {
+		log.Default().Printf("Shopping list cleanup archived %d and deleted %d lists", archived, deleted)
+	}
+	return nil
+}
+
+// RunCleanup cleans up the shopping lists now and then every interval until ctx is done
+func (s *ShoppingService) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.CleanupShoppingLists(ctx); err != nil {
+			log.Default().Printf("Error cleaning up shopping lists: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (s *ShoppingService) addBasicFood(ctx context.Context, q *db.Queries, listId int32, sourceID int, food *models.Food, quantity float64) error {
 	// Use same batch approach for consistency
 	collected := map[string]*CollectedIngredient{
@@ -136,14 +464,21 @@ func (s *ShoppingService) AddManualItem(ctx context.Context, listId int, req *mo
 			return fmt.Errorf("failed to create manual source: %w", err)
 		}
 
+		quantity, unit := req.Quantity, req.Unit
+		if food.CustomUnit(unit) != nil {
+			if converted, ok := utils.ConvertFoodUnit(food, quantity, unit, food.BaseUnit); ok {
+				quantity, unit = converted, food.BaseUnit
+			}
+		}
+
 		// Add single item using batch approach for consistency
 		collected := map[string]*CollectedIngredient{
-			fmt.Sprintf("%d|%s", req.FoodID, req.Unit): {
+			fmt.Sprintf("%d|%s", req.FoodID, unit): {
 				FoodID:   req.FoodID,
 				FoodName: food.Name,
-				Unit:     req.Unit,
+				Unit:     unit,
 				UnitType: food.UnitType,
-				Quantity: req.Quantity,
+				Quantity: quantity,
 			},
 		}
 
@@ -151,6 +486,22 @@ func (s *ShoppingService) AddManualItem(ctx context.Context, listId int, req *mo
 	})
 }
 
+// AddFreeTextItem adds something that isn't a food, joining an item of the same name and unit already on the list
+func (s *ShoppingService) AddFreeTextItem(ctx context.Context, listId int, req *models.AddFreeTextItemRequest) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		source, err := q.CreateShoppingListSource(ctx, db.CreateShoppingListSourceParams{
+			ShoppingListID: pgtype.Int4{Int32: int32(listId), Valid: true},
+			SourceType:     "manual",
+			SourceName:     fmt.Sprintf("Manual: %s", req.Name),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create manual source: %w", err)
+		}
+
+		return addFreeTextItem(ctx, q, int32(listId), source.ID, req)
+	})
+}
+
 func (s *ShoppingService) AddRecipe(ctx context.Context, listId int, req *models.AddRecipeRequest) error {
 	return s.db.WithTx(ctx, func(q *db.Queries) error {
 		// Get recipe details
@@ -170,6 +521,7 @@ func (s *ShoppingService) AddRecipe(ctx context.Context, listId int, req *models
 			SourceID:       pgtype.Int4{Int32: int32(req.RecipeID), Valid: true},
 			SourceName:     fmt.Sprintf("Recipe: %s (%.1fx)", recipe.Name, req.Servings),
 			Servings:       utils.Float64ToNumeric(req.Servings),
+			IncludeStaples: req.IncludeStaples,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create source: %w", err)
@@ -177,7 +529,7 @@ func (s *ShoppingService) AddRecipe(ctx context.Context, listId int, req *models
 
 		// Calculate scaling factor and add ingredients
 		scaleFactor := req.Servings / recipe.Recipe.YieldQuantity
-		return s.addRecipeIngredients(ctx, q, int32(listId), recipe, scaleFactor, int(source.ID))
+		return s.addRecipeIngredients(ctx, q, int32(listId), recipe, scaleFactor, int(source.ID), req.IncludeStaples, nil)
 	})
 }
 
@@ -203,15 +555,21 @@ func (s *ShoppingService) AddSchedules(ctx context.Context, listId int, req *mod
 				SourceID:       pgtype.Int4{Int32: int32(scheduleID), Valid: true},
 				SourceName:     fmt.Sprintf("Scheduled: %s on %s", food.Name, schedule.ScheduledAt.Format("Jan 2")),
 				Servings:       utils.Float64ToNumeric(schedule.Servings),
+				IncludeStaples: req.IncludeStaples,
 			})
 			if err != nil {
 				return fmt.Errorf("failed to create source for schedule %d: %w", scheduleID, err)
 			}
 
+			substitutions, err := s.substitutionService.GetScheduleSubstitutions(ctx, scheduleID)
+			if err != nil {
+				return fmt.Errorf("failed to get substitutions for schedule %d: %w", scheduleID, err)
+			}
+
 			// Add ingredients based on food type
 			if food.IsRecipe && food.Recipe != nil {
 				scaleFactor := schedule.Servings / food.Recipe.YieldQuantity
-				err = s.addRecipeIngredients(ctx, q, int32(listId), food, scaleFactor, int(source.ID))
+				err = s.addRecipeIngredients(ctx, q, int32(listId), food, scaleFactor, int(source.ID), req.IncludeStaples, substitutions)
 			} else {
 				err = s.addBasicFood(ctx, q, int32(listId), int(source.ID), food, schedule.Servings)
 			}
@@ -225,8 +583,14 @@ func (s *ShoppingService) AddSchedules(ctx context.Context, listId int, req *mod
 }
 
 func (s *ShoppingService) AddDateRange(ctx context.Context, listId int, req *models.AddDateRangeRequest, timeZone *time.Location) error {
-	// Get all schedules in range
-	schedules, err := s.scheduleService.GetSchedulesForRange(ctx, &req.StartDate, &req.EndDate, timeZone)
+	// Get all schedules in range, restricted to the chosen meal slots if any
+	var schedules []*models.Schedule
+	var err error
+	if len(req.MealSlotIDs) > 0 {
+		schedules, err = s.scheduleService.GetSchedulesForRangeInSlots(ctx, &req.StartDate, &req.EndDate, req.MealSlotIDs, timeZone)
+	} else {
+		schedules, err = s.scheduleService.GetSchedulesForRange(ctx, &req.StartDate, &req.EndDate, timeZone)
+	}
 	if err != nil {
 		return err
 	}
@@ -238,10 +602,91 @@ func (s *ShoppingService) AddDateRange(ctx context.Context, listId int, req *mod
 	}
 
 	return s.AddSchedules(ctx, listId, &models.AddSchedulesRequest{
-		ScheduleIDs: scheduleIDs,
+		ScheduleIDs:    scheduleIDs,
+		IncludeStaples: req.IncludeStaples,
 	}, timeZone)
 }
 
+// AddStaplesBelowPar adds top-up lines for every staple whose stock is below its par level.
+// A previous staples source on the list is reused so repeated runs don't double up. What it added to items
+// already bought stays and counts towards the top-up, only the lines still to buy are worked out again.
+func (s *ShoppingService) AddStaplesBelowPar(ctx context.Context, listId int) error {
+	staples, err := s.pantryService.GetStaplesBelowPar(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		listRef := pgtype.Int4{Int32: int32(listId), Valid: true}
+		sources, err := getShoppingListSources(ctx, q, listId)
+		if err != nil {
+			return fmt.Errorf("failed to get sources: %w", err)
+		}
+		var source *models.ShoppingListSource
+		staplesSources := make(map[int32]bool)
+		for _, existing := range sources {
+			if existing.SourceType != "staples" {
+				continue
+			}
+			if err := q.DeleteUnpurchasedShoppingListItemSourcesBySource(ctx, int32(existing.ID)); err != nil {
+				return fmt.Errorf("failed to remove previous staples lines: %w", err)
+			}
+			staplesSources[int32(existing.ID)] = true
+			if source == nil {
+				source = existing
+			}
+		}
+		if err := q.DeleteUnsourcedShoppingListItems(ctx, listRef); err != nil {
+			return err
+		}
+
+		rows, err := q.GetShoppingListContributions(ctx, listRef)
+		if err != nil {
+			return fmt.Errorf("failed to get contributions: %w", err)
+		}
+		bought := make(map[string]float64)
+		for _, row := range rows {
+			if staplesSources[row.ShoppingListSourceID] && row.Purchased {
+				bought[fmt.Sprintf("%d|%s", row.FoodID.Int32, row.Unit)] += numericToFloat64(row.ContributedQuantity)
+			}
+		}
+
+		collected := make(map[string]*CollectedIngredient)
+		for _, staple := range staples {
+			key := fmt.Sprintf("%d|%s", staple.FoodID, staple.BaseUnit)
+			if quantity := staple.Shortfall() - bought[key]; quantity > 0 {
+				collected[key] = &CollectedIngredient{
+					FoodID:   staple.FoodID,
+					FoodName: staple.FoodName,
+					Unit:     staple.BaseUnit,
+					UnitType: staple.UnitType,
+					Quantity: quantity,
+				}
+			}
+		}
+
+		if len(collected) == 0 {
+			if source != nil && len(bought) == 0 {
+				return q.DeleteShoppingListSource(ctx, int32(source.ID))
+			}
+			return nil
+		}
+
+		if source == nil {
+			dbSource, err := q.CreateShoppingListSource(ctx, db.CreateShoppingListSourceParams{
+				ShoppingListID: listRef,
+				SourceType:     "staples",
+				SourceName:     "Staples below par",
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create staples source: %w", err)
+			}
+			source = &models.ShoppingListSource{ID: int(dbSource.ID)}
+		}
+		return s.batchInsertIngredients(ctx, q, int32(listId), source.ID, collected)
+	})
+}
+
 func (s *ShoppingService) UpdateItemNotes(ctx context.Context, itemId int, notes string) error {
 	return s.db.UpdateShoppingListItemNotes(ctx, db.UpdateShoppingListItemNotesParams{
 		ID:    int32(itemId),
@@ -249,12 +694,77 @@ func (s *ShoppingService) UpdateItemNotes(ctx context.Context, itemId int, notes
 	})
 }
 
-func (s *ShoppingService) MarkItemPurchased(ctx context.Context, itemId int, purchased bool, actualQuantity, actualPrice float64) error {
-	return s.db.MarkShoppingListItemPurchased(ctx, db.MarkShoppingListItemPurchasedParams{
-		ID:             int32(itemId),
-		Purchased:      pgtype.Bool{Bool: purchased, Valid: purchased},
-		ActualQuantity: pgtype.Numeric{Valid: actualQuantity > 0},
-		ActualPrice:    pgtype.Numeric{Valid: actualPrice > 0},
+// MarkItemPurchased records what was bought and where. Anything beyond what the list needed, the actual quantity or else
+// whole packages, is recorded as surplus and added to the food's pantry stock when the pantry tracks it.
+// Only the change from the surplus recorded before reaches the stock, so unticking an item takes it back out.
+func (s *ShoppingService) MarkItemPurchased(ctx context.Context, itemId int, purchased bool, actualQuantity, actualPrice float64, storeId int) error {
+	item, err := s.db.GetShoppingListItemForPurchase(ctx, int32(itemId))
+	if err != nil {
+		return err
+	}
+
+	var food *models.Food
+	surplus := 0.0
+	required := numericToFloat64(item.RequiredQuantity)
+	if item.FoodID.Valid {
+		food, err = s.foodService.GetFoodDetails(ctx, fmt.Sprintf("%d", item.FoodID.Int32), 0)
+		if err != nil {
+			return fmt.Errorf("failed to get food %d: %w", item.FoodID.Int32, err)
+		}
+	}
+	if purchased {
+		if actualQuantity > 0 {
+			surplus = math.Max(actualQuantity-required, 0)
+		} else if plan := utils.PlanPackages(food, required, item.Unit); plan != nil {
+			surplus = plan.Surplus
+		}
+	}
+
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		change := surplus - numericToFloat64(item.SurplusQuantity)
+		if food != nil && change != 0 {
+			if stock, ok := utils.ConvertFoodUnit(food, change, item.Unit, food.BaseUnit); ok {
+				err := q.AddPantryStock(ctx, db.AddPantryStockParams{
+					FoodID:   item.FoodID.Int32,
+					Quantity: utils.Float64ToNumeric(stock),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if !purchased {
+			storeId = 0
+		}
+		err := q.MarkShoppingListItemPurchased(ctx, db.MarkShoppingListItemPurchasedParams{
+			ID:              int32(itemId),
+			Purchased:       pgtype.Bool{Bool: purchased, Valid: purchased},
+			ActualQuantity:  utils.OptionalNumeric(actualQuantity),
+			ActualPrice:     utils.OptionalNumeric(actualPrice),
+			SurplusQuantity: utils.Float64ToNumeric(surplus),
+			StoreID:         utils.OptionalInt4(storeId),
+		})
+		if err != nil {
+			return err
+		}
+
+		// The price paid becomes the latest price at the store, for what was actually bought
+		itemRef := pgtype.Int4{Int32: int32(itemId), Valid: true}
+		if !purchased || actualPrice <= 0 || storeId == 0 || !item.FoodID.Valid {
+			return q.DeleteStorePriceForItem(ctx, itemRef)
+		}
+		bought := actualQuantity
+		if bought <= 0 {
+			bought = required + surplus
+		}
+		return q.RecordStorePrice(ctx, db.RecordStorePriceParams{
+			FoodID:             item.FoodID.Int32,
+			StoreID:            int32(storeId),
+			Price:              utils.Float64ToNumeric(actualPrice),
+			Quantity:           utils.Float64ToNumeric(bought),
+			Unit:               item.Unit,
+			ShoppingListItemID: itemRef,
+		})
 	})
 }
 
@@ -264,20 +774,428 @@ func (s *ShoppingService) RemoveItem(ctx context.Context, itemId int) error {
 
 func (s *ShoppingService) RemoveItemsBySource(ctx context.Context, sourceId int) error {
 	return s.db.WithTx(ctx, func(q *db.Queries) error {
-		// Remove all item-source links for this source
-		err := q.DeleteShoppingListItemSourcesBySource(ctx, int32(sourceId))
+		source, err := q.GetShoppingListSource(ctx, int32(sourceId))
 		if err != nil {
 			return err
 		}
 
-		// Remove items that no longer have any sources
-		err = q.DeleteOrphanedShoppingListItems(ctx)
+		// Remove all item-source links for this source
+		err = q.DeleteShoppingListItemSourcesBySource(ctx, source.ID)
 		if err != nil {
 			return err
 		}
 
 		// Remove the source itself
-		return q.DeleteShoppingListSource(ctx, int32(sourceId))
+		err = q.DeleteShoppingListSource(ctx, source.ID)
+		if err != nil {
+			return err
+		}
+
+		// Remove the list's items that no longer have any sources, bought ones stay for the spend
+		return q.DeleteUnsourcedShoppingListItems(ctx, source.ShoppingListID)
+	})
+}
+
+// GetShoppingListItem gets an item of a list with every source that adds to it, nil when it isn't on the list
+func (s *ShoppingService) GetShoppingListItem(ctx context.Context, listId, itemId int) (*models.ShoppingListItem, error) {
+	list, err := s.GetShoppingListById(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := getSourceOverrides(ctx, s.db.Queries, listId)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[int]*models.ShoppingListSource, len(list.Sources))
+	for _, source := range list.Sources {
+		sources[source.ID] = source
+	}
+	for _, item := range list.Items {
+		if item.ID != itemId {
+			continue
+		}
+		key := fmt.Sprintf("%d|%s", item.FoodID, item.Unit)
+		for _, contribution := range item.Sources {
+			contribution.Source = sources[contribution.SourceID]
+			_, contribution.Adjusted = overrides[contribution.SourceID][key]
+		}
+		return item, nil
+	}
+	return nil, nil
+}
+
+// SetItemContribution changes how much of an item one source adds, 0 takes the source off the item. A change to
+// a schedule or recipe source is kept as an override, so keeping the list in sync with its meals doesn't undo
+// it. An item left without a source is removed unless it's already been bought.
+func (s *ShoppingService) SetItemContribution(ctx context.Context, listId, itemId, sourceId int, quantity float64) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		source, err := q.GetShoppingListSource(ctx, int32(sourceId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrContributionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get source %d: %w", sourceId, err)
+		}
+		item, err := q.GetShoppingListItemForPurchase(ctx, int32(itemId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrContributionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get item %d: %w", itemId, err)
+		}
+		if source.ShoppingListID.Int32 != int32(listId) || item.ShoppingListID.Int32 != int32(listId) {
+			return ErrContributionNotFound
+		}
+
+		var changed int64
+		if quantity > 0 {
+			changed, err = q.SetShoppingListItemSourceQuantity(ctx, db.SetShoppingListItemSourceQuantityParams{
+				ShoppingListItemID:   int32(itemId),
+				ShoppingListSourceID: int32(sourceId),
+				ContributedQuantity:  utils.Float64ToNumeric(quantity),
+			})
+		} else {
+			changed, err = q.DeleteShoppingListItemSource(ctx, db.DeleteShoppingListItemSourceParams{
+				ShoppingListItemID:   int32(itemId),
+				ShoppingListSourceID: int32(sourceId),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to change contribution: %w", err)
+		}
+		if changed == 0 {
+			return ErrContributionNotFound
+		}
+
+		if (&models.ShoppingListSource{SourceType: source.SourceType}).Synced() && item.FoodID.Valid {
+			// The source can add to more than one line of the food, a bought one and one still to buy, the
+			// override is what it adds to all of them
+			rows, err := q.GetShoppingListContributions(ctx, source.ShoppingListID)
+			if err != nil {
+				return fmt.Errorf("failed to get contributions: %w", err)
+			}
+			total := 0.0
+			for _, row := range rows {
+				if row.ShoppingListSourceID == source.ID && row.FoodID == item.FoodID && row.Unit == item.Unit {
+					total += numericToFloat64(row.ContributedQuantity)
+				}
+			}
+			err = q.SetShoppingListSourceOverride(ctx, db.SetShoppingListSourceOverrideParams{
+				ShoppingListSourceID: source.ID,
+				FoodID:               item.FoodID.Int32,
+				Unit:                 item.Unit,
+				Quantity:             utils.Float64ToNumeric(total),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to keep contribution override: %w", err)
+			}
+		}
+
+		return q.DeleteUnsourcedShoppingListItems(ctx, source.ShoppingListID)
+	})
+}
+
+// getSourceOverrides gets the contributions changed by hand on a list, by source and then food and unit
+func getSourceOverrides(ctx context.Context, q *db.Queries, listId int) (map[int]map[string]float64, error) {
+	rows, err := q.GetShoppingListSourceOverrides(ctx, pgtype.Int4{Int32: int32(listId), Valid: true})
+	if err != nil {
+		log.Default().Printf("Error getting shopping list overrides: %v", err)
+		return nil, err
+	}
+
+	overrides := make(map[int]map[string]float64)
+	for _, row := range rows {
+		sourceId := int(row.ShoppingListSourceID)
+		if overrides[sourceId] == nil {
+			overrides[sourceId] = make(map[string]float64)
+		}
+		overrides[sourceId][fmt.Sprintf("%d|%s", row.FoodID, row.Unit)] = numericToFloat64(row.Quantity)
+	}
+	return overrides, nil
+}
+
+// GetShoppingListDrift finds the schedule and recipe sources of a list whose meal or recipe has changed since
+// they were added, and how much of each food they need now against what they added
+func (s *ShoppingService) GetShoppingListDrift(ctx context.Context, listId int, timeZone *time.Location) ([]*models.SourceDrift, error) {
+	drifted, err := s.findDrift(ctx, s.db.Queries, listId, timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := make([]*models.SourceDrift, len(drifted))
+	for i, source := range drifted {
+		drifts[i] = source.drift
+	}
+	return drifts, nil
+}
+
+// ReconcileShoppingList brings the drifted schedule and recipe sources of a list up to date. Only their
+// contributions are worked out again, the rest of the list is left as it is. What's already been bought stays
+// bought, anything needed on top of it goes on a line still to buy. Items nothing needs any more are removed
+// unless they've already been bought.
+func (s *ShoppingService) ReconcileShoppingList(ctx context.Context, listId int, timeZone *time.Location) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		drifted, err := s.findDrift(ctx, q, listId, timeZone)
+		if err != nil {
+			return err
+		}
+
+		for _, source := range drifted {
+			sourceId := int32(source.drift.Source.ID)
+			if source.drift.Removed {
+				err := q.DeleteShoppingListItemSourcesBySource(ctx, sourceId)
+				if err != nil {
+					return fmt.Errorf("failed to remove contributions of source %d: %w", sourceId, err)
+				}
+				err = q.DeleteShoppingListSource(ctx, sourceId)
+				if err != nil {
+					return fmt.Errorf("failed to remove source %d: %w", sourceId, err)
+				}
+				continue
+			}
+
+			err := q.DeleteUnpurchasedShoppingListItemSourcesBySource(ctx, sourceId)
+			if err != nil {
+				return fmt.Errorf("failed to remove contributions of source %d: %w", sourceId, err)
+			}
+			err = q.UpdateShoppingListSource(ctx, db.UpdateShoppingListSourceParams{
+				ID:         sourceId,
+				SourceName: source.name,
+				Servings:   utils.Float64ToNumeric(source.drift.Servings),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update source %d: %w", sourceId, err)
+			}
+
+			toBuy := make(map[string]*CollectedIngredient)
+			for key, ingredient := range source.collected {
+				if remaining := ingredient.Quantity - source.bought[key]; remaining > 1e-9 {
+					toBuy[key] = &CollectedIngredient{
+						FoodID:   ingredient.FoodID,
+						FoodName: ingredient.FoodName,
+						Unit:     ingredient.Unit,
+						UnitType: ingredient.UnitType,
+						Quantity: remaining,
+					}
+				}
+			}
+			err = s.batchInsertIngredients(ctx, q, int32(listId), int(sourceId), toBuy)
+			if err != nil {
+				return fmt.Errorf("failed to add ingredients for source %d: %w", sourceId, err)
+			}
+		}
+
+		return q.DeleteUnsourcedShoppingListItems(ctx, pgtype.Int4{Int32: int32(listId), Valid: true})
+	})
+}
+
+// syncedSource is a schedule or recipe source worked out again from the meal or recipe as it is now
+type syncedSource struct {
+	drift     *models.SourceDrift
+	name      string
+	collected map[string]*CollectedIngredient
+	bought    map[string]float64 // what the source added to items already bought
+}
+
+// findDrift works out the sources of a list again. What's already been bought can't be taken off the list, so a
+// source never needs less of a food than it added to bought items.
+func (s *ShoppingService) findDrift(ctx context.Context, q *db.Queries, listId int, timeZone *time.Location) ([]*syncedSource, error) {
+	sources, err := getShoppingListSources(ctx, q, listId)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.GetShoppingListContributions(ctx, pgtype.Int4{Int32: int32(listId), Valid: true})
+	if err != nil {
+		log.Default().Printf("Error getting shopping list contributions: %v", err)
+		return nil, err
+	}
+
+	// What each source added, in the same shape as the ingredients it would add now
+	added := make(map[int]map[string]*CollectedIngredient)
+	bought := make(map[int]map[string]float64)
+	for _, row := range rows {
+		sourceId := int(row.ShoppingListSourceID)
+		if added[sourceId] == nil {
+			added[sourceId] = make(map[string]*CollectedIngredient)
+			bought[sourceId] = make(map[string]float64)
+		}
+		key := fmt.Sprintf("%d|%s", row.FoodID.Int32, row.Unit)
+		if row.Purchased {
+			bought[sourceId][key] += numericToFloat64(row.ContributedQuantity)
+		}
+		if existing := added[sourceId][key]; existing != nil {
+			existing.Quantity += numericToFloat64(row.ContributedQuantity)
+			continue
+		}
+		added[sourceId][key] = &CollectedIngredient{
+			FoodID:   int(row.FoodID.Int32),
+			FoodName: row.FoodName,
+			Unit:     row.Unit,
+			Quantity: numericToFloat64(row.ContributedQuantity),
+		}
+	}
+	overrides, err := getSourceOverrides(ctx, q, listId)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifted []*syncedSource
+	for _, source := range sources {
+		if !source.Synced() || source.SourceID == 0 {
+			continue
+		}
+		synced, err := s.syncSource(ctx, q, source, timeZone)
+		if err != nil {
+			return nil, err
+		}
+		// Contributions changed by hand stay as they were set
+		for key, quantity := range overrides[source.ID] {
+			if ingredient := synced.collected[key]; ingredient != nil && quantity == 0 {
+				delete(synced.collected, key)
+			} else if ingredient != nil {
+				ingredient.Quantity = quantity
+			}
+		}
+		synced.bought = bought[source.ID]
+		for key, quantity := range synced.bought {
+			if ingredient := synced.collected[key]; ingredient != nil {
+				ingredient.Quantity = max(ingredient.Quantity, quantity)
+			} else if !synced.drift.Removed {
+				kept := *added[source.ID][key]
+				kept.Quantity = quantity
+				synced.collected[key] = &kept
+			}
+		}
+		synced.drift.Changes = contributionChanges(added[source.ID], synced.collected)
+		if synced.drift.Removed || len(synced.drift.Changes) > 0 {
+			drifted = append(drifted, synced)
+		}
+	}
+	return drifted, nil
+}
+
+// syncSource works out what a schedule or recipe source would add if it were added again now, the same way
+// AddSchedules and AddRecipe do
+func (s *ShoppingService) syncSource(ctx context.Context, q *db.Queries, source *models.ShoppingListSource, timeZone *time.Location) (*syncedSource, error) {
+	synced := &syncedSource{
+		drift: &models.SourceDrift{Source: source, Servings: source.Servings},
+		name:  source.SourceName,
+	}
+
+	var food *models.Food
+	var substitutions map[int]*models.Substitution
+	switch source.SourceType {
+	case "schedule":
+		schedule, err := s.scheduleService.GetScheduleById(ctx, source.SourceID, timeZone)
+		if errors.Is(err, pgx.ErrNoRows) {
+			synced.drift.Removed = true
+			return synced, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get schedule %d: %w", source.SourceID, err)
+		}
+		food, err = s.foodService.GetFoodDetails(ctx, strconv.Itoa(schedule.FoodID), 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get food %d for schedule %d: %w", schedule.FoodID, source.SourceID, err)
+		}
+		substitutions, err = s.substitutionService.GetScheduleSubstitutions(ctx, source.SourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get substitutions for schedule %d: %w", source.SourceID, err)
+		}
+		synced.drift.Servings = schedule.Servings
+		synced.name = fmt.Sprintf("Scheduled: %s on %s", food.Name, schedule.ScheduledAt.Format("Jan 2"))
+	case "recipe":
+		// GetFoodDetails can't tell a deleted recipe from a failure
+		_, err := q.GetFood(ctx, int32(source.SourceID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			synced.drift.Removed = true
+			return synced, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get recipe %d: %w", source.SourceID, err)
+		}
+		food, err = s.foodService.GetFoodDetails(ctx, strconv.Itoa(source.SourceID), 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get recipe %d: %w", source.SourceID, err)
+		}
+		synced.name = fmt.Sprintf("Recipe: %s (%.1fx)", food.Name, source.Servings)
+	}
+
+	synced.collected = make(map[string]*CollectedIngredient)
+	if food.IsRecipe && food.Recipe != nil {
+		scaleFactor := synced.drift.Servings / food.Recipe.YieldQuantity
+		err := s.collectBaseIngredients(ctx, food, scaleFactor, synced.collected, 0, source.IncludeStaples, substitutions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect ingredients: %w", err)
+		}
+	} else {
+		synced.collected[fmt.Sprintf("%d|%s", food.ID, food.BaseUnit)] = &CollectedIngredient{
+			FoodID:   food.ID,
+			FoodName: food.Name,
+			Unit:     food.BaseUnit,
+			UnitType: food.UnitType,
+			Quantity: synced.drift.Servings,
+		}
+	}
+	return synced, nil
+}
+
+// contributionChanges compares what a source added to a list with what it needs now, by food name
+func contributionChanges(before, after map[string]*CollectedIngredient) []*models.ContributionChange {
+	var changes []*models.ContributionChange
+	for key, ingredient := range after {
+		was := 0.0
+		if added := before[key]; added != nil {
+			was = added.Quantity
+		}
+		if math.Abs(ingredient.Quantity-was) > 1e-6 {
+			changes = append(changes, &models.ContributionChange{
+				FoodName: ingredient.FoodName,
+				Unit:     ingredient.Unit,
+				Before:   was,
+				After:    ingredient.Quantity,
+			})
+		}
+	}
+	for key, added := range before {
+		if after[key] == nil && added.Quantity > 0 {
+			changes = append(changes, &models.ContributionChange{
+				FoodName: added.FoodName,
+				Unit:     added.Unit,
+				Before:   added.Quantity,
+			})
+		}
+	}
+	slices.SortFunc(changes, func(a, b *models.ContributionChange) int {
+		return cmp.Or(cmp.Compare(a.FoodName, b.FoodName), cmp.Compare(a.Unit, b.Unit))
+	})
+	return changes
+}
+
+func addFreeTextItem(ctx context.Context, q *db.Queries, listId, sourceId int32, req *models.AddFreeTextItemRequest) error {
+	item, err := q.FindFreeTextShoppingListItem(ctx, db.FindFreeTextShoppingListItemParams{
+		ShoppingListID: pgtype.Int4{Int32: listId, Valid: true},
+		Name:           req.Name,
+		Unit:           req.Unit,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		item, err = q.CreateFreeTextShoppingListItem(ctx, db.CreateFreeTextShoppingListItemParams{
+			ShoppingListID: pgtype.Int4{Int32: listId, Valid: true},
+			FoodName:       req.Name,
+			Unit:           req.Unit,
+			Category:       req.Category,
+			Notes:          pgtype.Text{String: req.Notes, Valid: req.Notes != ""},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", req.Name, err)
+	}
+
+	return q.CreateShoppingListItemSource(ctx, db.CreateShoppingListItemSourceParams{
+		ShoppingListItemID:   item.ID,
+		ShoppingListSourceID: sourceId,
+		ContributedQuantity:  utils.Float64ToNumeric(req.Quantity),
 	})
 }
 
@@ -301,10 +1219,10 @@ type CollectedIngredient struct {
 	Quantity float64
 }
 
-func (s *ShoppingService) addRecipeIngredients(ctx context.Context, q *db.Queries, listId int32, recipe *models.Food, scaleFactor float64, sourceID int) error {
+func (s *ShoppingService) addRecipeIngredients(ctx context.Context, q *db.Queries, listId int32, recipe *models.Food, scaleFactor float64, sourceID int, includeStaples bool, substitutions map[int]*models.Substitution) error {
 	// Step 1: Collect all base ingredients (simple recursive logic)
 	collected := make(map[string]*CollectedIngredient)
-	err := s.collectBaseIngredients(ctx, recipe, scaleFactor, collected, 0)
+	err := s.collectBaseIngredients(ctx, recipe, scaleFactor, collected, 0, includeStaples, substitutions)
 	if err != nil {
 		return fmt.Errorf("failed to collect ingredients: %w", err)
 	}
@@ -318,38 +1236,69 @@ func (s *ShoppingService) addRecipeIngredients(ctx context.Context, q *db.Querie
 	return s.batchInsertIngredients(ctx, q, listId, sourceID, collected)
 }
 
-func (s *ShoppingService) collectBaseIngredients(ctx context.Context, recipe *models.Food, scaleFactor float64, collected map[string]*CollectedIngredient, depth int) error {
+// collectBaseIngredients adds up the base foods a recipe needs. substitutions are keyed by the food they
+// replace and apply at every depth, nil buys the recipe as written.
+func (s *ShoppingService) collectBaseIngredients(ctx context.Context, recipe *models.Food, scaleFactor float64, collected map[string]*CollectedIngredient, depth int, includeStaples bool, substitutions map[int]*models.Substitution) error {
 	if depth > 15 {
 		return fmt.Errorf("recipe depth limit exceeded")
 	}
 
 	for _, ingredient := range recipe.Recipe.Ingredients {
-		scaledQty := ingredient.Quantity * scaleFactor
+		// A substituted line buys the substitutes instead, which may be recipes themselves
+		lines := []*models.RecipeItem{ingredient}
+		if substitution := substitutions[ingredient.Food.ID]; substitution != nil {
+			substitutes, ok := utils.SubstituteIngredient(ingredient, substitution)
+			if !ok {
+				// Buying the original instead would quietly undo the substitution
+				return fmt.Errorf("%w: %s in %s", ErrSubstitutionUnit, ingredient.Food.Name, recipe.Name)
+			}
+			lines = substitutes
+		}
 
-		if ingredient.Food.IsRecipe && ingredient.Food.Recipe != nil {
-			// Get full recipe details and recurse
-			fullRecipe, err := s.foodService.GetFoodDetails(ctx, fmt.Sprintf("%d", ingredient.Food.ID), 1)
-			if err != nil {
-				return fmt.Errorf("failed to get recipe %d: %w", ingredient.Food.ID, err)
+		for _, line := range lines {
+			scaledQty := line.Quantity * scaleFactor
+
+			if line.Food.IsRecipe {
+				// Get full recipe details and recurse
+				fullRecipe, err := s.foodService.GetFoodDetails(ctx, fmt.Sprintf("%d", line.Food.ID), 1)
+				if err != nil {
+					return fmt.Errorf("failed to get recipe %d: %w", line.Food.ID, err)
+				}
+
+				if fullRecipe.Recipe != nil {
+					nestedScale := scaledQty / fullRecipe.Recipe.YieldQuantity
+					err = s.collectBaseIngredients(ctx, fullRecipe, nestedScale, collected, depth+1, includeStaples, substitutions)
+					if err != nil {
+						return err
+					}
+					continue
+				}
 			}
 
-			nestedScale := scaledQty / fullRecipe.Recipe.YieldQuantity
-			err = s.collectBaseIngredients(ctx, fullRecipe, nestedScale, collected, depth+1)
-			if err != nil {
-				return err
+			if line.Food.IsStaple && !includeStaples {
+				// Staples are topped up from the pantry instead of bought per recipe
+				continue
 			}
-		} else {
+
+			// The food's own units, like cans, add up in its base unit
+			unit := line.Unit
+			if line.Food.CustomUnit(unit) != nil {
+				if quantity, ok := utils.ConvertFoodUnit(line.Food, scaledQty, unit, line.Food.BaseUnit); ok {
+					scaledQty, unit = quantity, line.Food.BaseUnit
+				}
+			}
+
 			// Base ingredient - aggregate by food+unit key
-			key := fmt.Sprintf("%d|%s", ingredient.Food.ID, ingredient.Unit)
+			key := fmt.Sprintf("%d|%s", line.Food.ID, unit)
 
 			if existing := collected[key]; existing != nil {
 				existing.Quantity += scaledQty
 			} else {
 				collected[key] = &CollectedIngredient{
-					FoodID:   ingredient.Food.ID,
-					FoodName: ingredient.Food.Name,
-					Unit:     ingredient.Unit,
-					UnitType: ingredient.Food.UnitType,
+					FoodID:   line.Food.ID,
+					FoodName: line.Food.Name,
+					Unit:     unit,
+					UnitType: line.Food.UnitType,
 					Quantity: scaledQty,
 				}
 			}
@@ -472,8 +1421,9 @@ func (s *ShoppingService) batchInsertIngredients(ctx context.Context, q *db.Quer
 	return nil
 }
 
-func (s *ShoppingService) getShoppingListItemsWithSources(ctx context.Context, listId int) ([]*models.ShoppingListItem, error) {
-	dbItems, err := s.db.GetShoppingListItemsWithCalculatedQuantities(ctx, pgtype.Int4{Int32: int32(listId), Valid: true})
+// getShoppingListItemsWithSources gets the items of the list, each with the store it's bought at
+func (s *ShoppingService) getShoppingListItemsWithSources(ctx context.Context, list *models.ShoppingList) ([]*models.ShoppingListItem, error) {
+	dbItems, err := s.db.GetShoppingListItemsWithCalculatedQuantities(ctx, pgtype.Int4{Int32: int32(list.ID), Valid: true})
 	if err != nil {
 		return nil, err
 	}
@@ -492,6 +1442,7 @@ func (s *ShoppingService) getShoppingListItemsWithSources(ctx context.Context, l
 				ShoppingListID: int(dbItem.ShoppingListID.Int32),
 				FoodID:         int(dbItem.FoodID.Int32),
 				FoodName:       dbItem.FoodName,
+				Category:       dbItem.Category,
 				Quantity:       calculatedQuantity.Float64, // Now calculated from sources
 				Unit:           dbItem.Unit,
 				UnitType:       dbItem.UnitType,
@@ -500,6 +1451,9 @@ func (s *ShoppingService) getShoppingListItemsWithSources(ctx context.Context, l
 				ActualQuantity: actualQuantity.Float64,
 				ActualPrice:    actualPrice.Float64,
 				Sources:        []*models.ShoppingListItemSource{},
+
+				SurplusQuantity: numericToFloat64(dbItem.SurplusQuantity),
+				StoreID:         int(dbItem.StoreID.Int32),
 			}
 			itemMap[dbItem.ID] = item
 		}
@@ -517,15 +1471,63 @@ func (s *ShoppingService) getShoppingListItemsWithSources(ctx context.Context, l
 
 	// Convert map to slice
 	items := make([]*models.ShoppingListItem, 0, len(itemMap))
+	foodIds := make([]int32, 0, len(itemMap))
 	for _, item := range itemMap {
 		items = append(items, item)
+		foodIds = append(foodIds, int32(item.FoodID))
+	}
+
+	// Also show the items in the unit they're bought in, like cans, and rounded up to whole packages
+	units, err := s.foodService.GetFoodUnitsByFood(ctx, foodIds)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := s.foodService.GetFoodPackagesByFood(ctx, foodIds)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := s.foodService.GetFoodStoresByFood(ctx, foodIds)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.foodService.GetFoodPricesByFood(ctx, foodIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		food := &models.Food{
+			ID:       item.FoodID,
+			Units:    units[item.FoodID],
+			Packages: packages[item.FoodID],
+			StoreIDs: stores[item.FoodID],
+			Prices:   prices[item.FoodID],
+		}
+		if purchaseUnit := food.PurchaseUnit(); purchaseUnit != nil {
+			if quantity, ok := utils.ConvertFoodUnit(food, item.Quantity, item.Unit, purchaseUnit.Name); ok {
+				item.PurchaseQuantity = quantity
+				item.PurchaseUnit = purchaseUnit.Name
+			}
+		}
+		item.Packages = utils.PlanPackages(food, item.Quantity, item.Unit)
+
+		// Items already bought stay with the store they were bought at, the rest go where the list's split
+		// puts them or else to the list's store
+		if !item.Purchased || item.StoreID == 0 {
+			item.StoreID, item.EstimatedPrice = utils.ChooseStore(food, item.Quantity, item.Unit, list.SplitBy)
+			if item.StoreID == 0 {
+				item.StoreID = list.StoreID
+			}
+		}
+		if item.EstimatedPrice == 0 && item.StoreID != 0 {
+			item.EstimatedPrice = utils.EstimatePrice(food, item.StoreID, item.Quantity, item.Unit)
+		}
 	}
 
 	return items, nil
 }
 
-func (s *ShoppingService) getShoppingListSources(ctx context.Context, listId int) ([]*models.ShoppingListSource, error) {
-	dbSources, err := s.db.GetShoppingListSources(ctx, pgtype.Int4{Int32: int32(listId), Valid: true})
+func getShoppingListSources(ctx context.Context, q *db.Queries, listId int) ([]*models.ShoppingListSource, error) {
+	dbSources, err := q.GetShoppingListSources(ctx, pgtype.Int4{Int32: int32(listId), Valid: true})
 	if err != nil {
 		return nil, err
 	}
@@ -540,6 +1542,7 @@ func (s *ShoppingService) getShoppingListSources(ctx context.Context, listId int
 			SourceID:       int(dbSource.SourceID.Int32),
 			SourceName:     dbSource.SourceName,
 			Servings:       servings.Float64,
+			IncludeStaples: dbSource.IncludeStaples,
 			AddedAt:        dbSource.AddedAt.Time,
 		}
 	}
@@ -560,19 +1563,15 @@ func (s *ShoppingService) ExportShoppingListAsText(ctx context.Context, id int)
 	text += fmt.Sprintf("Created: %s\n\n", list.CreatedAt.Format("Jan 2, 2006"))
 
 	text += "ITEMS TO BUY:\n"
-	for _, item := range list.Items {
-		status := ""
-		if item.Purchased {
-			status = " ✓"
+	for _, section := range list.Sections {
+		if list.Split() {
+			text += fmt.Sprintf("\n== %s ==\n", section.Title())
+			if total := section.EstimatedTotal(); total > 0 {
+				text += fmt.Sprintf("Estimated: $%.2f\n", total)
+			}
 		}
-		text += fmt.Sprintf("□ %s: %s %s%s\n",
-			item.FoodName,
-			utils.FormatQuantity(item.Quantity),
-			item.Unit,
-			status)
-
-		if item.Notes != "" {
-			text += fmt.Sprintf("  Note: %s\n", item.Notes)
+		for _, group := range section.Groups {
+			text += exportShoppingListGroup(group)
 		}
 	}
 
@@ -585,3 +1584,34 @@ func (s *ShoppingService) ExportShoppingListAsText(ctx context.Context, id int)
 
 	return text, nil
 }
+
+// exportShoppingListGroup writes one aisle of the list as text
+func exportShoppingListGroup(group *models.ShoppingListGroup) string {
+	text := fmt.Sprintf("\n%s\n", strings.ToUpper(group.Title()))
+	for _, item := range group.Items {
+		status := ""
+		if item.Purchased {
+			status = " ✓"
+		}
+		amount := item.Unit
+		if item.Quantity > 0 {
+			amount = strings.TrimSpace(utils.FormatQuantity(item.Quantity) + " " + item.Unit)
+		}
+		if amount == "" {
+			text += fmt.Sprintf("□ %s%s\n", item.FoodName, status)
+		} else {
+			text += fmt.Sprintf("□ %s: %s%s%s\n", item.FoodName, amount, item.PurchaseAmount(), status)
+		}
+		if item.Packages != nil {
+			text += fmt.Sprintf("  Buy %s\n", item.Packages)
+		}
+		if shortfall := item.Shortfall(); shortfall > 0 {
+			text += fmt.Sprintf("  Short: %s\n", strings.TrimSpace(utils.FormatQuantity(shortfall)+" "+item.Unit))
+		}
+
+		if item.Notes != "" {
+			text += fmt.Sprintf("  Note: %s\n", item.Notes)
+		}
+	}
+	return text
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+)
+
+type StoreService struct {
+	db *database.DB
+}
+
+func NewStoreService(db *database.DB) *StoreService {
+	return &StoreService{db: db}
+}
+
+// GetStores lists the stores by name, each with its aisles in walking order
+func (s *StoreService) GetStores(ctx context.Context) ([]*models.Store, error) {
+	dbStores, err := s.db.GetStores(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting stores: %v", err)
+		return nil, err
+	}
+	dbAisles, err := s.db.GetStoreAisles(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting store aisles: %v", err)
+		return nil, err
+	}
+
+	aisles := make(map[int32][]string)
+	for _, aisle := range dbAisles {
+		aisles[aisle.StoreID] = append(aisles[aisle.StoreID], aisle.Category)
+	}
+	stores := make([]*models.Store, len(dbStores))
+	for i, row := range dbStores {
+		stores[i] = &models.Store{
+			ID:     int(row.ID),
+			Name:   row.Name,
+			Aisles: aisles[row.ID],
+		}
+	}
+	return stores, nil
+}
+
+// GetStore finds one store by id, nil when there's no such store
+func (s *StoreService) GetStore(ctx context.Context, id int) (*models.Store, error) {
+	stores, err := s.GetStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, store := range stores {
+		if store.ID == id {
+			return store, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *StoreService) CreateStore(ctx context.Context, name string, aisles []string) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		store, err := q.CreateStore(ctx, name)
+		if err != nil {
+			return err
+		}
+		return replaceStoreAisles(ctx, q, store.ID, aisles)
+	})
+}
+
+// SetAisles replaces the order the store's sections are walked in
+func (s *StoreService) SetAisles(ctx context.Context, id int, aisles []string) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		return replaceStoreAisles(ctx, q, int32(id), aisles)
+	})
+}
+
+func (s *StoreService) DeleteStore(ctx context.Context, id int) error {
+	return s.db.DeleteStore(ctx, int32(id))
+}
+
+// SetShoppingListStore picks the store whose layout orders a list, 0 for none
+func (s *StoreService) SetShoppingListStore(ctx context.Context, listId, storeId int) error {
+	return s.db.SetShoppingListStore(ctx, db.SetShoppingListStoreParams{
+		ID:      int32(listId),
+		StoreID: utils.OptionalInt4(storeId),
+	})
+}
+
+// SetShoppingListSplit sets how a list is split into per-store sections, one of the models.Split modes
+func (s *StoreService) SetShoppingListSplit(ctx context.Context, listId int, splitBy string) error {
+	return s.db.SetShoppingListSplit(ctx, db.SetShoppingListSplitParams{
+		ID:      int32(listId),
+		SplitBy: splitBy,
+	})
+}
+
+func replaceStoreAisles(ctx context.Context, q *db.Queries, storeId int32, aisles []string) error {
+	if err := q.DeleteStoreAisles(ctx, storeId); err != nil {
+		return err
+	}
+	for i, category := range aisles {
+		err := q.AddStoreAisle(ctx, db.AddStoreAisleParams{
+			StoreID:  storeId,
+			Category: category,
+			Position: int32(i),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"mealplanner/internal/utils"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var ErrConflictingSubstitutions = errors.New("only one substitution per food can be applied to a meal")
+
+type SubstitutionService struct {
+	db              *database.DB
+	scheduleService *ScheduleService
+	foodService     *FoodService
+}
+
+func NewSubstitutionService(db *database.DB, scheduleService *ScheduleService, foodService *FoodService) *SubstitutionService {
+	return &SubstitutionService{
+		db:              db,
+		scheduleService: scheduleService,
+		foodService:     foodService,
+	}
+}
+
+// GetSubstitutions lists every rule with its substitute lines, ordered by the food they replace
+func (s *SubstitutionService) GetSubstitutions(ctx context.Context) ([]*models.Substitution, error) {
+	dbSubstitutions, err := s.db.GetSubstitutions(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting substitutions: %v", err)
+		return nil, err
+	}
+	dbItems, err := s.db.GetSubstitutionItems(ctx)
+	if err != nil {
+		log.Default().Printf("Error getting substitution items: %v", err)
+		return nil, err
+	}
+
+	items := make(map[int32][]*models.RecipeItem)
+	for _, item := range dbItems {
+		items[item.SubstitutionID] = append(items[item.SubstitutionID], &models.RecipeItem{
+			FoodID: int(item.FoodID),
+			Food: &models.Food{
+				ID:       int(item.FoodID),
+				Name:     item.FoodName,
+				UnitType: item.UnitType,
+				BaseUnit: item.BaseUnit,
+				IsRecipe: item.IsRecipe,
+				IsStaple: item.IsStaple,
+			},
+			Quantity: numericToFloat64(item.Quantity),
+			Unit:     item.Unit,
+		})
+	}
+
+	substitutions := make([]*models.Substitution, len(dbSubstitutions))
+	for i, row := range dbSubstitutions {
+		substitutions[i] = &models.Substitution{
+			ID:         int(row.ID),
+			FoodID:     int(row.FoodID),
+			FoodName:   row.FoodName,
+			Quantity:   numericToFloat64(row.Quantity),
+			Unit:       row.Unit,
+			MemberID:   int(row.MemberID.Int32),
+			MemberName: row.MemberName,
+			Items:      items[row.ID],
+		}
+	}
+	return substitutions, nil
+}
+
+func (s *SubstitutionService) CreateSubstitution(ctx context.Context, substitution *models.Substitution) error {
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		id, err := q.CreateSubstitution(ctx, db.CreateSubstitutionParams{
+			FoodID:   int32(substitution.FoodID),
+			Quantity: utils.Float64ToNumeric(substitution.Quantity),
+			Unit:     substitution.Unit,
+			MemberID: utils.OptionalInt4(substitution.MemberID),
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range substitution.Items {
+			err := q.AddSubstitutionItem(ctx, db.AddSubstitutionItemParams{
+				SubstitutionID: id,
+				FoodID:         int32(item.FoodID),
+				Quantity:       utils.Float64ToNumeric(item.Quantity),
+				Unit:           item.Unit,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SubstitutionService) DeleteSubstitution(ctx context.Context, id int) error {
+	return s.db.DeleteSubstitution(ctx, int32(id))
+}
+
+// GetScheduleSubstitutions returns the substitutions applied to a meal, keyed by the food they replace
+func (s *SubstitutionService) GetScheduleSubstitutions(ctx context.Context, scheduleId int) (map[int]*models.Substitution, error) {
+	ids, err := s.db.GetScheduleSubstitutionIds(ctx, int32(scheduleId))
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]*models.Substitution)
+	if len(ids) == 0 {
+		return applied, nil
+	}
+
+	substitutions, err := s.GetSubstitutions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, substitution := range substitutions {
+		if slices.Contains(ids, int32(substitution.ID)) {
+			applied[substitution.FoodID] = substitution
+		}
+	}
+	return applied, nil
+}
+
+// SetScheduleSubstitutions replaces the substitutions applied to a meal
+func (s *SubstitutionService) SetScheduleSubstitutions(ctx context.Context, scheduleId int, substitutionIds []int) error {
+	substitutions, err := s.GetSubstitutions(ctx)
+	if err != nil {
+		return err
+	}
+	replaced := make(map[int]bool)
+	for _, substitution := range substitutions {
+		if !slices.Contains(substitutionIds, substitution.ID) {
+			continue
+		}
+		if replaced[substitution.FoodID] {
+			return ErrConflictingSubstitutions
+		}
+		replaced[substitution.FoodID] = true
+	}
+
+	return s.db.WithTx(ctx, func(q *db.Queries) error {
+		if err := q.DeleteScheduleSubstitutions(ctx, int32(scheduleId)); err != nil {
+			return err
+		}
+		for _, id := range substitutionIds {
+			err := q.AddScheduleSubstitution(ctx, db.AddScheduleSubstitutionParams{
+				ScheduleID:     int32(scheduleId),
+				SubstitutionID: int32(id),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetCookView scales the meal's recipe to its servings with the applied substitutions swapped in. The rules on
+// offer are the ones for a food anywhere in the recipe, meant for everyone or for someone eating the meal.
+func (s *SubstitutionService) GetCookView(ctx context.Context, scheduleId int, timeZone *time.Location) (*models.CookView, error) {
+	schedule, err := s.scheduleService.GetScheduleById(ctx, scheduleId, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	food, err := s.foodService.GetFoodDetails(ctx, strconv.Itoa(schedule.FoodID), 1)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.GetScheduleSubstitutions(ctx, scheduleId)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &models.CookView{
+		Schedule: schedule,
+		Food:     food,
+	}
+	usedFoods := make(map[int]bool)
+	if food.IsRecipe && food.Recipe != nil {
+		view.Lines, err = s.foodService.scaledLines(ctx, food, schedule.Servings/food.Recipe.YieldQuantity, applied, usedFoods, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	substitutions, err := s.GetSubstitutions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, substitution := range substitutions {
+		isApplied := applied[substitution.FoodID] != nil && applied[substitution.FoodID].ID == substitution.ID
+		forAttendee := substitution.MemberID == 0 || slices.Contains(schedule.AttendeeIDs, substitution.MemberID)
+		if isApplied || (usedFoods[substitution.FoodID] && forAttendee) {
+			view.Available = append(view.Available, substitution)
+		}
+		if isApplied {
+			view.Applied = append(view.Applied, substitution.ID)
+		}
+	}
+	return view, nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mealplanner/internal/database"
+	"mealplanner/internal/database/db"
+	"mealplanner/internal/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type VarietyService struct {
+	db              *database.DB
+	settingsService *SettingsService
+}
+
+func NewVarietyService(db *database.DB, settingsService *SettingsService) *VarietyService {
+	return &VarietyService{
+		db:              db,
+		settingsService: settingsService,
+	}
+}
+
+// varietyProfile is what the warnings compare between a meal and the ones planned around it
+type varietyProfile struct {
+	foodId          int
+	name            string
+	scheduledAt     time.Time
+	prepMinutes     int
+	tags            []string
+	mainIngredients []string
+}
+
+// CheckSchedule warns when a meal repeats a recent recipe, variety tag or main ingredient, or pushes its day
+// over the configured limits. The warnings are keyed by the schedule form field they belong to and never block
+// scheduling. excludeScheduleId is the schedule being edited, 0 when creating one.
+func (s *VarietyService) CheckSchedule(ctx context.Context, foodId int, scheduledAt time.Time, excludeScheduleId int, timeZone *time.Location) (map[string]string, error) {
+	settings, err := s.settingsService.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	food, err := s.db.GetFoodVarietyProfile(ctx, int32(foodId))
+	if err != nil {
+		log.Default().Printf("Error getting variety profile for food %d: %v", foodId, err)
+		return nil, err
+	}
+	candidate := &varietyProfile{
+		foodId:          int(food.ID),
+		name:            food.Name,
+		scheduledAt:     scheduledAt.In(timeZone),
+		prepMinutes:     int(food.PrepMinutes),
+		tags:            food.Tags,
+		mainIngredients: food.MainIngredients,
+	}
+
+	day := time.Date(candidate.scheduledAt.Year(), candidate.scheduledAt.Month(), candidate.scheduledAt.Day(), 0, 0, 0, 0, timeZone)
+	rangeStart := day.AddDate(0, 0, -settings.VarietyWindowDays)
+	rangeEnd := day.AddDate(0, 0, settings.VarietyWindowDays+1)
+	rows, err := s.db.GetScheduleVarietyProfiles(ctx, db.GetScheduleVarietyProfilesParams{
+		RangeStart: pgtype.Timestamptz{Time: rangeStart, Valid: true},
+		RangeEnd:   pgtype.Timestamptz{Time: rangeEnd, Valid: true},
+		ExcludeID:  int32(excludeScheduleId),
+	})
+	if err != nil {
+		log.Default().Printf("Error getting schedules for variety check: %v", err)
+		return nil, err
+	}
+	planned := make([]*varietyProfile, len(rows))
+	for i, row := range rows {
+		planned[i] = &varietyProfile{
+			foodId:          int(row.FoodID.Int32),
+			name:            row.FoodName,
+			scheduledAt:     row.ScheduledAt.Time.In(timeZone),
+			prepMinutes:     int(row.PrepMinutes),
+			tags:            row.Tags,
+			mainIngredients: row.MainIngredients,
+		}
+	}
+
+	return varietyWarnings(settings, candidate, planned), nil
+}
+
+// varietyWarnings compares the candidate with the planned meals, all times in the household's time zone.
+// Repeats are reported under "food" and day limits under "time".
+func varietyWarnings(settings *models.HouseholdSettings, candidate *varietyProfile, planned []*varietyProfile) map[string]string {
+	warnings := make(map[string]string)
+
+	// only the tags picked in the settings make two different recipes a repeat
+	candidateTags := slices.DeleteFunc(slices.Clone(candidate.tags), func(tag string) bool {
+		return !slices.Contains(settings.VarietyTags, tag)
+	})
+
+	var repeats []string
+	sameDayMeals, sameDayPrep := 1, candidate.prepMinutes
+	for _, meal := range planned {
+		if sameDay(meal.scheduledAt, candidate.scheduledAt) {
+			sameDayMeals++
+			sameDayPrep += meal.prepMinutes
+		}
+		if diff := daysBetween(candidate.scheduledAt, meal.scheduledAt); settings.VarietyWindowDays <= 0 || diff < -settings.VarietyWindowDays || diff > settings.VarietyWindowDays {
+			continue
+		}
+
+		on := meal.scheduledAt.Format("Mon Jan 2")
+		if meal.foodId == candidate.foodId {
+			repeats = append(repeats, fmt.Sprintf("%s is already planned on %s", meal.name, on))
+		} else if tag := firstShared(candidateTags, meal.tags); tag != "" {
+			repeats = append(repeats, fmt.Sprintf("Shares the %s tag with %s on %s", tag, meal.name, on))
+		} else if ingredient := firstShared(candidate.mainIngredients, meal.mainIngredients); ingredient != "" {
+			repeats = append(repeats, fmt.Sprintf("Shares %s with %s on %s", ingredient, meal.name, on))
+		}
+	}
+	if len(repeats) > 0 {
+		warnings["food"] = strings.Join(repeats, ". ")
+	}
+
+	var limits []string
+	if settings.MaxMealsPerDay > 0 && sameDayMeals > settings.MaxMealsPerDay {
+		limits = append(limits, fmt.Sprintf("This would be meal %d of the day, the limit is %d", sameDayMeals, settings.MaxMealsPerDay))
+	}
+	if settings.MaxDailyPrepMinutes > 0 && candidate.prepMinutes > 0 && sameDayPrep > settings.MaxDailyPrepMinutes {
+		limits = append(limits, fmt.Sprintf("The day would need %d minutes of prep, the limit is %d", sameDayPrep, settings.MaxDailyPrepMinutes))
+	}
+	if len(limits) > 0 {
+		warnings["time"] = strings.Join(limits, ". ")
+	}
+	return warnings
+}
+
+// firstShared returns the first value of a that is also in b
+func firstShared(a, b []string) string {
+	for _, value := range a {
+		if slices.Contains(b, value) {
+			return value
+		}
+	}
+	return ""
+}
@@ -13,6 +13,34 @@ type DayData struct {
 	Schedules      []*models.Schedule
 }
 
+// Calendar views supported by the calendar page
+const (
+	CalendarViewDay   = "day"
+	CalendarViewWeek  = "week"
+	CalendarViewMonth = "month"
+)
+
+// Number of meals shown per day in the week and month views before collapsing into "+N more"
+const (
+	WeekViewScheduleLimit  = 4
+	MonthViewScheduleLimit = 2
+)
+
+type CalendarData struct {
+	View      string
+	Date      time.Time
+	WeekStart time.Weekday
+	Days      []*DayData
+	MealSlots []*models.MealSlot
+}
+
+// SlotGroup holds the schedules of a day that belong to one meal slot.
+// Slot is nil for schedules that are not assigned to any slot.
+type SlotGroup struct {
+	Slot      *models.MealSlot
+	Schedules []*models.Schedule
+}
+
 
 // GetDayData returns data for a single day
 // Optimized by pre-computing today's date once
@@ -28,6 +56,90 @@ func GetDayData(date *time.Time, schedules []*models.Schedule) *DayData {
 }
 
 
+// GetCalendarRange returns the [start, end) range to load for the given view.
+// Times are midnights in the location of date so the X-Timezone semantics are kept.
+func GetCalendarRange(view string, date time.Time, weekStart time.Weekday) (time.Time, time.Time) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	switch view {
+	case CalendarViewWeek:
+		start := StartOfWeek(day, weekStart)
+		return start, start.AddDate(0, 0, 7)
+	case CalendarViewMonth:
+		firstOfMonth := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+		start := StartOfWeek(firstOfMonth, weekStart)
+		end := StartOfWeek(lastOfMonth, weekStart).AddDate(0, 0, 7)
+		return start, end
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+// GetCalendarData builds the day cells for the view from schedules already loaded for the whole range
+func GetCalendarData(view string, date time.Time, weekStart time.Weekday, schedules []*models.Schedule) *CalendarData {
+	start, end := GetCalendarRange(view, date, weekStart)
+
+	days := make([]*DayData, 0)
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		current := day
+		dayData := GetDayData(&current, schedules)
+		dayData.IsCurrentMonth = current.Month() == date.Month() && current.Year() == date.Year()
+		days = append(days, dayData)
+	}
+
+	return &CalendarData{
+		View:      view,
+		Date:      date,
+		WeekStart: weekStart,
+		Days:      days,
+	}
+}
+
+// StartOfWeek returns midnight of the first day of the week containing date
+func StartOfWeek(date time.Time, weekStart time.Weekday) time.Time {
+	offset := (int(date.Weekday()) - int(weekStart) + 7) % 7
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return day.AddDate(0, 0, -offset)
+}
+
+// WeekdayHeaders returns the short weekday names starting from weekStart
+func WeekdayHeaders(weekStart time.Weekday) []string {
+	headers := make([]string, 7)
+	for i := 0; i < 7; i++ {
+		headers[i] = time.Weekday((int(weekStart) + i) % 7).String()[:3]
+	}
+	return headers
+}
+
+// GroupSchedulesBySlot splits the schedules of a day by meal slot, keeping the slot order.
+// Every slot gets a group so meals can be added to empty slots, unslotted meals go last.
+func GroupSchedulesBySlot(schedules []*models.Schedule, slots []*models.MealSlot) []*SlotGroup {
+	groups := make([]*SlotGroup, len(slots))
+	groupsBySlotID := make(map[int]*SlotGroup, len(slots))
+	for i, slot := range slots {
+		groups[i] = &SlotGroup{Slot: slot, Schedules: []*models.Schedule{}}
+		groupsBySlotID[slot.ID] = groups[i]
+	}
+
+	other := &SlotGroup{Schedules: []*models.Schedule{}}
+	for _, schedule := range schedules {
+		if group, ok := groupsBySlotID[schedule.MealSlotID]; ok {
+			group.Schedules = append(group.Schedules, schedule)
+		} else {
+			other.Schedules = append(other.Schedules, schedule)
+		}
+	}
+	if len(other.Schedules) > 0 {
+		groups = append(groups, other)
+	}
+	return groups
+}
+
+func IsValidCalendarView(view string) bool {
+	return view == CalendarViewDay || view == CalendarViewWeek || view == CalendarViewMonth
+}
+
 func GetVisibleSchedules(schedules []*models.Schedule, limit int) []*models.Schedule {
 	if len(schedules) <= limit {
 		return schedules
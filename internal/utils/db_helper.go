@@ -14,6 +14,19 @@ func Float64ToNumeric(val float64) pgtype.Numeric {
 	return n
 }
 
+// OptionalInt4 stores zero as NULL, for optional fields where zero means unknown
+func OptionalInt4(val int) pgtype.Int4 {
+	return pgtype.Int4{Int32: int32(val), Valid: val != 0}
+}
+
+// OptionalNumeric stores zero as NULL, for optional fields where zero means unknown
+func OptionalNumeric(val float64) pgtype.Numeric {
+	if val == 0 {
+		return pgtype.Numeric{}
+	}
+	return Float64ToNumeric(val)
+}
+
 // FormatQuantity formats a numeric quantity in a user-friendly way:
 // - Integers are displayed without decimal points (e.g., 2 instead of 2.0)
 // - Fractions are displayed with 2 decimal places (e.g., 2.50)
@@ -33,3 +46,11 @@ func FormatQuantity(quantity float64) string {
 	// For other decimals, use 2 decimal places
 	return fmt.Sprintf("%.2f", quantity)
 }
+
+// FormatOptionalQuantity formats like FormatQuantity, but leaves zero empty for optional form fields
+func FormatOptionalQuantity(quantity float64) string {
+	if quantity == 0 {
+		return ""
+	}
+	return FormatQuantity(quantity)
+}
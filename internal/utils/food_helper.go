@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"math"
 	"mealplanner/internal/models"
 	"strconv"
 	"strings"
@@ -13,7 +14,8 @@ import (
 
 type FoodFormProps struct {
 	Food   *models.Food
-	Foods  []*models.Food // For ingredient selection
+	Foods  []*models.Food  // For ingredient selection
+	Stores []*models.Store // For preferred store selection
 	Errors map[string]string
 	IsEdit bool
 }
@@ -22,6 +24,22 @@ type IngredientForm struct {
 	FoodID   string  `form:"ingredients[].food_id"`  // Matches name="ingredients[%d].food_id"
 	Quantity float64 `form:"ingredients[].quantity"` // Matches name="ingredients[%d].quantity"
 	Unit     string  `form:"ingredients[].unit"`     // Matches name="ingredients[%d].unit"
+	IsMain   bool    `form:"ingredients[].is_main"`  // Matches name="ingredients[%d].is_main"
+}
+
+// FoodUnitForm is one row of the food's own units, "1 Name = Quantity Unit"
+type FoodUnitForm struct {
+	Name           string
+	Quantity       float64
+	Unit           string
+	IsPurchaseUnit bool
+}
+
+// FoodPackageForm is one row of the sizes the food is sold in
+type FoodPackageForm struct {
+	Size  float64
+	Unit  string
+	Price float64
 }
 
 type FoodForm struct {
@@ -30,11 +48,22 @@ type FoodForm struct {
 	UnitType string `form:"unit_type"`
 	BaseUnit string `form:"base_unit"`
 	IsRecipe bool   `form:"is_recipe"`
+	IsStaple bool   `form:"is_staple"`
+	Category string `form:"category"`
 
 	RecipeURL     string           `form:"recipe_url"`     // Matches name="recipe_url"
 	Instructions  string           `form:"instructions"`   // Matches name="instructions"
 	Ingredients   []IngredientForm `form:"-"`              // Handled specially due to array indexing
 	YieldQuantity float64          `form:"yield_quantity"` // Matches name="yield_quantity"
+
+	PrepMinutes    int     `form:"prep_minutes"`     // Matches name="prep_minutes"
+	CostPerServing float64 `form:"cost_per_serving"` // Matches name="cost_per_serving"
+	Tags           string  `form:"tags"`             // Comma separated
+
+	Labels   []string          `form:"labels"`    // Matches the name="labels" checkboxes
+	StoreIDs []int             `form:"store_ids"` // Matches the name="store_ids" checkboxes
+	Units    []FoodUnitForm    `form:"-"`         // Handled like the ingredients
+	Packages []FoodPackageForm `form:"-"`         // Handled like the ingredients
 }
 
 // Special binding method needed for ingredients array
@@ -56,6 +85,7 @@ func (f *FoodForm) BindIngredients(c echo.Context) error {
 		ing.Quantity = quantity
 
 		ing.Unit = c.FormValue(fmt.Sprintf("ingredients[%d].unit", i))
+		ing.IsMain = c.FormValue(fmt.Sprintf("ingredients[%d].is_main", i)) == "true"
 		ingredients = append(ingredients, ing)
 		i++
 	}
@@ -63,6 +93,58 @@ func (f *FoodForm) BindIngredients(c echo.Context) error {
 	return nil
 }
 
+// BindUnits reads the custom unit rows, rows left without a name are skipped
+func (f *FoodForm) BindUnits(c echo.Context) error {
+	var units []FoodUnitForm
+	for i := 0; c.FormValue(fmt.Sprintf("units[%d].unit", i)) != ""; i++ {
+		name := strings.TrimSpace(c.FormValue(fmt.Sprintf("units[%d].name", i)))
+		if name == "" {
+			continue
+		}
+		quantity, err := strconv.ParseFloat(c.FormValue(fmt.Sprintf("units[%d].quantity", i)), 64)
+		if err != nil {
+			return err
+		}
+		units = append(units, FoodUnitForm{
+			Name:           name,
+			Quantity:       quantity,
+			Unit:           c.FormValue(fmt.Sprintf("units[%d].unit", i)),
+			IsPurchaseUnit: c.FormValue(fmt.Sprintf("units[%d].is_purchase_unit", i)) == "true",
+		})
+	}
+	f.Units = units
+	return nil
+}
+
+// BindPackages reads the package rows, rows left without a size are skipped
+func (f *FoodForm) BindPackages(c echo.Context) error {
+	var packages []FoodPackageForm
+	for i := 0; c.FormValue(fmt.Sprintf("packages[%d].unit", i)) != ""; i++ {
+		sizeValue := c.FormValue(fmt.Sprintf("packages[%d].size", i))
+		if sizeValue == "" {
+			continue
+		}
+		size, err := strconv.ParseFloat(sizeValue, 64)
+		if err != nil {
+			return err
+		}
+		price := 0.0
+		if priceValue := c.FormValue(fmt.Sprintf("packages[%d].price", i)); priceValue != "" {
+			price, err = strconv.ParseFloat(priceValue, 64)
+			if err != nil {
+				return err
+			}
+		}
+		packages = append(packages, FoodPackageForm{
+			Size:  size,
+			Unit:  c.FormValue(fmt.Sprintf("packages[%d].unit", i)),
+			Price: price,
+		})
+	}
+	f.Packages = packages
+	return nil
+}
+
 func (f *FoodForm) CombineDuplicateIngredients() {
     if len(f.Ingredients) <= 1 {
         return
@@ -80,6 +162,7 @@ func (f *FoodForm) CombineDuplicateIngredients() {
         
         if existing, exists := combined[key]; exists {
             existing.Quantity += ing.Quantity
+            existing.IsMain = existing.IsMain || ing.IsMain
             combined[key] = existing
         } else {
             combined[key] = ing
@@ -109,10 +192,57 @@ func (f *FoodForm) Validate() *ValidationError {
 		errors["base_unit"] = "Invalid base unit for selected unit type"
 	}
 
+	if f.Category != "" && !slices.Contains(models.FoodCategories, f.Category) {
+		errors["category"] = "Unknown category"
+	}
+
+	for _, label := range f.Labels {
+		if !slices.Contains(models.FoodLabels, label) {
+			errors["labels"] = "Unknown label " + label
+		}
+	}
+
+	purchaseUnits := 0
+	for i, unit := range f.Units {
+		if UnitTypeOf(unit.Name) != "" || slices.ContainsFunc(f.Units[:i], func(other FoodUnitForm) bool { return other.Name == unit.Name }) {
+			errors[fmt.Sprintf("units[%d].name", i)] = "Unit names must be new and unique"
+		}
+		if unit.Quantity <= 0 {
+			errors[fmt.Sprintf("units[%d].quantity", i)] = "Quantity must be greater than 0"
+		}
+		if UnitTypeOf(unit.Unit) != f.UnitType {
+			errors[fmt.Sprintf("units[%d].unit", i)] = "Must be a " + f.UnitType + " unit"
+		}
+		if unit.IsPurchaseUnit {
+			purchaseUnits++
+		}
+	}
+	if purchaseUnits > 1 {
+		errors["units"] = "Choose one unit to buy in"
+	}
+
+	for i, pkg := range f.Packages {
+		if pkg.Size <= 0 {
+			errors[fmt.Sprintf("packages[%d].size", i)] = "Size must be greater than 0"
+		}
+		if UnitTypeOf(pkg.Unit) != f.UnitType {
+			errors[fmt.Sprintf("packages[%d].unit", i)] = "Must be a " + f.UnitType + " unit"
+		}
+		if pkg.Price < 0 {
+			errors[fmt.Sprintf("packages[%d].price", i)] = "Price can't be negative"
+		}
+	}
+
 	if f.IsRecipe {
 		if f.YieldQuantity <= 0 {
 			errors["yield_quantity"] = "Yield quantity must be greater than 0"
 		}
+		if f.PrepMinutes < 0 {
+			errors["prep_minutes"] = "Prep time can't be negative"
+		}
+		if f.CostPerServing < 0 {
+			errors["cost_per_serving"] = "Cost can't be negative"
+		}
 
 		// Ingredient validation
 		if len(f.Ingredients) == 0 {
@@ -144,14 +274,23 @@ func (f *FoodForm) ToModel() *models.Food {
 		UnitType: f.UnitType,
 		BaseUnit: f.BaseUnit,
 		IsRecipe: f.IsRecipe,
+		IsStaple: f.IsStaple,
+		Category: f.Category,
+		Labels:   f.Labels,
+		Units:    f.FoodUnits(),
+		Packages: f.FoodPackages(),
+		StoreIDs: f.StoreIDs,
 	}
 
 	if f.IsRecipe {
 		food.Recipe = &models.Recipe{
-			URL:           f.RecipeURL,
-			Instructions:  f.Instructions,
-			YieldQuantity: f.YieldQuantity,
-			Ingredients:   make([]*models.RecipeItem, len(f.Ingredients)),
+			URL:            f.RecipeURL,
+			Instructions:   f.Instructions,
+			YieldQuantity:  f.YieldQuantity,
+			PrepMinutes:    f.PrepMinutes,
+			CostPerServing: f.CostPerServing,
+			Tags:           f.TagList(),
+			Ingredients:    make([]*models.RecipeItem, len(f.Ingredients)),
 		}
 
 		for i, ing := range f.Ingredients {
@@ -163,6 +302,7 @@ func (f *FoodForm) ToModel() *models.Food {
 				FoodID:   foodId,
 				Quantity: ing.Quantity,
 				Unit:     ing.Unit,
+				IsMain:   ing.IsMain,
 			}
 		}
 	}
@@ -170,45 +310,98 @@ func (f *FoodForm) ToModel() *models.Food {
 	return food
 }
 
-func ValidateAndFilterDependencies(foods []*models.Food, targetID int) []*models.Food {
-	// Create graph representation of dependencies
-	deps := make(map[int][]int)
-	for _, food := range foods {
-		if food.IsRecipe && food.Recipe != nil {
-			deps[food.ID] = make([]int, 0)
-			for _, ing := range food.Recipe.Ingredients {
-				deps[food.ID] = append(deps[food.ID], ing.Food.ID)
-			}
+// FoodUnits converts the custom unit rows to models
+func (f *FoodForm) FoodUnits() []*models.FoodUnit {
+	units := make([]*models.FoodUnit, len(f.Units))
+	for i, unit := range f.Units {
+		units[i] = &models.FoodUnit{
+			Name:           unit.Name,
+			Quantity:       unit.Quantity,
+			Unit:           unit.Unit,
+			IsPurchaseUnit: unit.IsPurchaseUnit,
 		}
 	}
+	return units
+}
 
-	// Helper function to check dependency chain depth and circularity
-	var checkDependencyChain func(foodID int, visited map[int]bool, depth int) bool
-	checkDependencyChain = func(foodID int, visited map[int]bool, depth int) bool {
-		// Check maximum depth
-		if depth > 15 {
-			return false
+// FoodPackages converts the package rows to models
+func (f *FoodForm) FoodPackages() []*models.FoodPackage {
+	packages := make([]*models.FoodPackage, len(f.Packages))
+	for i, pkg := range f.Packages {
+		packages[i] = &models.FoodPackage{
+			Size:  pkg.Size,
+			Unit:  pkg.Unit,
+			Price: pkg.Price,
 		}
+	}
+	return packages
+}
 
-		// Check for circular dependencies
-		if visited[foodID] {
-			return false
+// TagList splits the comma separated tags into unique lower case tags
+func (f *FoodForm) TagList() []string {
+	return ParseTags(f.Tags)
+}
+
+// ParseTags splits a comma separated list into unique, trimmed, lower case tags
+func ParseTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
 		}
+	}
+	return tags
+}
 
-		// Mark current food as visited
-		visited[foodID] = true
-		defer delete(visited, foodID) // Clean up after checking this branch
+// Deepest chain of nested recipes followed, matching the limit of the recursive food queries
+const maxDependencyDepth = 15
 
-		// Check all ingredients recursively
-		for _, depID := range deps[foodID] {
-			if !checkDependencyChain(depID, visited, depth+1) {
-				return false
-			}
-		}
+// walkDependencyChain calls visit for foodID and then everything it's made from, depth first. visit returning
+// false skips what the food is made from. It reports false when the chain is circular or deeper than
+// maxDependencyDepth, the rest of the chain is still walked.
+func walkDependencyChain(deps map[int][]int, foodID int, visited map[int]bool, depth int, visit func(foodID int) bool) bool {
+	// Check maximum depth
+	if depth > maxDependencyDepth {
+		return false
+	}
+
+	// Check for circular dependencies
+	if visited[foodID] {
+		return false
+	}
 
+	if visit != nil && !visit(foodID) {
 		return true
 	}
 
+	// Mark current food as visited
+	visited[foodID] = true
+	defer delete(visited, foodID) // Clean up after checking this branch
+
+	// Check all ingredients recursively
+	valid := true
+	for _, depID := range deps[foodID] {
+		if !walkDependencyChain(deps, depID, visited, depth+1, visit) {
+			valid = false
+		}
+	}
+
+	return valid
+}
+
+func ValidateAndFilterDependencies(foods []*models.Food, targetID int) []*models.Food {
+	// Create graph representation of dependencies
+	deps := make(map[int][]int)
+	for _, food := range foods {
+		if food.IsRecipe && food.Recipe != nil {
+			deps[food.ID] = make([]int, 0)
+			for _, ing := range food.Recipe.Ingredients {
+				deps[food.ID] = append(deps[food.ID], ing.Food.ID)
+			}
+		}
+	}
+
 	// Filter foods based on dependency rules
 	validFoods := make([]*models.Food, 0)
 	for _, food := range foods {
@@ -230,7 +423,7 @@ func ValidateAndFilterDependencies(foods []*models.Food, targetID int) []*models
 		}
 
 		// Validate the dependency chain
-		isValid := checkDependencyChain(food.ID, make(map[int]bool), 1)
+		isValid := walkDependencyChain(deps, food.ID, make(map[int]bool), 1, nil)
 
 		// Clean up temporary dependency
 		if targetID > 0 {
@@ -266,3 +459,180 @@ func GetUnitsByType(unitType string) []string {
 	}
 	return units
 }
+
+// Size of each mass and volume unit in grams or milliliters
+var unitSizes = map[string]float64{
+	"grams":       1,
+	"kilograms":   1000,
+	"ounces":      28.3495,
+	"pounds":      453.592,
+	"milliliters": 1,
+	"liters":      1000,
+	"teaspoons":   4.92892,
+	"tablespoons": 14.7868,
+	"cups":        236.588,
+	"fluidOunces": 29.5735,
+}
+
+// ConvertUnit converts a quantity between two units of the same type, count units only convert to themselves
+func ConvertUnit(quantity float64, from, to string) (float64, bool) {
+	if from == to {
+		return quantity, true
+	}
+	fromSize, fromOk := unitSizes[from]
+	toSize, toOk := unitSizes[to]
+	if !fromOk || !toOk || UnitTypeOf(from) != UnitTypeOf(to) {
+		return 0, false
+	}
+	return quantity * fromSize / toSize, true
+}
+
+// ConvertFoodUnit converts like ConvertUnit but also understands the food's own units, e.g. cans to grams
+func ConvertFoodUnit(food *models.Food, quantity float64, from, to string) (float64, bool) {
+	if from == to {
+		return quantity, true
+	}
+	if custom := food.CustomUnit(from); custom != nil {
+		quantity, from = quantity*custom.Quantity, custom.Unit
+	}
+	if custom := food.CustomUnit(to); custom != nil {
+		converted, ok := ConvertUnit(quantity, from, custom.Unit)
+		return converted / custom.Quantity, ok
+	}
+	return ConvertUnit(quantity, from, to)
+}
+
+// PlanPackages rounds a quantity up to whole packages of the food, picking the size that leaves the least
+// over and then the fewest packages. It's nil when none of the sizes convert to the unit.
+func PlanPackages(food *models.Food, quantity float64, unit string) *models.PackagePlan {
+	if food == nil || quantity <= 0 {
+		return nil
+	}
+
+	var best *models.PackagePlan
+	for _, pkg := range food.Packages {
+		needed, ok := ConvertFoodUnit(food, quantity, unit, pkg.Unit)
+		if !ok {
+			continue
+		}
+		// allow for rounding in the conversion so exactly one package isn't rounded up to two
+		count := int(math.Ceil(needed/pkg.Size - 1e-9))
+		bought, _ := ConvertFoodUnit(food, float64(count)*pkg.Size, pkg.Unit, unit)
+		plan := &models.PackagePlan{
+			Package: pkg,
+			Count:   count,
+			Surplus: math.Max(bought-quantity, 0),
+		}
+		if best == nil || plan.Surplus < best.Surplus || (plan.Surplus == best.Surplus && plan.Count < best.Count) {
+			best = plan
+		}
+	}
+	return best
+}
+
+// PriceAt estimates what a quantity of the food costs at a price paid before, false when the units don't convert
+func PriceAt(food *models.Food, price *models.StorePrice, quantity float64, unit string) (float64, bool) {
+	converted, ok := ConvertFoodUnit(food, quantity, unit, price.Unit)
+	if !ok {
+		return 0, false
+	}
+	return converted / price.Quantity * price.Price, true
+}
+
+// EstimatePrice estimates what a quantity of the food costs at a store from the latest price there, 0 when unknown
+func EstimatePrice(food *models.Food, storeId int, quantity float64, unit string) float64 {
+	for _, price := range food.Prices {
+		if price.StoreID == storeId {
+			cost, _ := PriceAt(food, price, quantity, unit)
+			return cost
+		}
+	}
+	return 0
+}
+
+// ChooseStore picks the store to buy a quantity of the food from when a list is split. Cheapest picks the lowest
+// latest price anywhere, preferred the lowest among the food's preferred stores, and both fall back to the first
+// preferred store. It returns the store, 0 when none fits, and the estimated cost there, 0 when unknown.
+func ChooseStore(food *models.Food, quantity float64, unit, splitBy string) (int, float64) {
+	if food == nil || splitBy == models.SplitNone {
+		return 0, 0
+	}
+
+	storeId, cost := 0, 0.0
+	for _, price := range food.Prices {
+		if splitBy == models.SplitPreferred && !slices.Contains(food.StoreIDs, price.StoreID) {
+			continue
+		}
+		estimate, ok := PriceAt(food, price, quantity, unit)
+		if ok && (storeId == 0 || estimate < cost) {
+			storeId, cost = price.StoreID, estimate
+		}
+	}
+	if storeId == 0 && len(food.StoreIDs) > 0 {
+		storeId = food.StoreIDs[0]
+	}
+	return storeId, cost
+}
+
+// UnitTypeOf returns the type a unit belongs to, empty for unknown units
+func UnitTypeOf(unit string) string {
+	for _, unitType := range []string{"mass", "volume", "count"} {
+		if slices.Contains(GetUnitsByType(unitType), unit) {
+			return unitType
+		}
+	}
+	return ""
+}
+
+// SubstituteIngredient scales the substitute lines to the quantity of the recipe line. It reports false when
+// the substitution is for another food or its unit can't be converted to the line's.
+func SubstituteIngredient(ing *models.RecipeItem, sub *models.Substitution) ([]*models.RecipeItem, bool) {
+	if ing.Food == nil || ing.Food.ID != sub.FoodID {
+		return nil, false
+	}
+	quantity, ok := ConvertFoodUnit(ing.Food, ing.Quantity, ing.Unit, sub.Unit)
+	if !ok {
+		return nil, false
+	}
+	factor := quantity / sub.Quantity
+
+	substitutes := make([]*models.RecipeItem, len(sub.Items))
+	for i, item := range sub.Items {
+		substitutes[i] = &models.RecipeItem{
+			FoodID:   item.FoodID,
+			Food:     item.Food,
+			Quantity: item.Quantity * factor,
+			Unit:     item.Unit,
+			IsMain:   ing.IsMain,
+		}
+	}
+	return substitutes, true
+}
+
+// PropagateLabels gives each of foodIDs the labels of everything it's made from. deps maps each recipe to its
+// ingredients and is walked with walkDependencyChain, like ValidateAndFilterDependencies. A food already worked
+// out isn't walked again, so passing the ingredients of a recipe before the recipe saves walking them twice.
+func PropagateLabels(deps map[int][]int, labels map[int][]string, foodIDs []int) map[int][]string {
+	effective := make(map[int][]string)
+	for _, foodID := range foodIDs {
+		if _, ok := effective[foodID]; ok {
+			continue
+		}
+		var collected []string
+		walkDependencyChain(deps, foodID, make(map[int]bool), 1, func(id int) bool {
+			known, ok := effective[id]
+			if !ok {
+				known = labels[id]
+			}
+			for _, label := range known {
+				if !slices.Contains(collected, label) {
+					collected = append(collected, label)
+				}
+			}
+			return !ok
+		})
+		slices.Sort(collected)
+		effective[foodID] = collected
+	}
+	return effective
+}
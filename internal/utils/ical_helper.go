@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"mealplanner/internal/models"
+	"strings"
+	"time"
+)
+
+// Length of a meal event in calendar apps, schedules only have a start time
+const ICalMealDuration = 30 * time.Minute
+
+const icalTimeFormat = "20060102T150405Z"
+
+// BuildICalendar renders schedules as an iCalendar (RFC 5545) document.
+// Times are written in UTC so every client places them correctly regardless of its own zone.
+func BuildICalendar(calendarName string, schedules []*models.Schedule, generatedAt time.Time) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//mealplanner//Meal Plan//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(calendarName))
+	writeICalLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	writeICalLine(&b, "X-PUBLISHED-TTL:PT1H")
+
+	stamp := generatedAt.UTC().Format(icalTimeFormat)
+	for _, schedule := range schedules {
+		start := schedule.ScheduledAt.UTC()
+		description := fmt.Sprintf("Servings: %s", FormatQuantity(schedule.Servings))
+		if schedule.MealSlotName != "" {
+			description = schedule.MealSlotName + "\n" + description
+		}
+
+		writeICalLine(&b, "BEGIN:VEVENT")
+		// UIDs come from the schedule ID so edits update the existing event instead of duplicating it
+		writeICalLine(&b, fmt.Sprintf("UID:schedule-%d@mealplanner", schedule.ID))
+		writeICalLine(&b, "DTSTAMP:"+stamp)
+		writeICalLine(&b, "DTSTART:"+start.Format(icalTimeFormat))
+		writeICalLine(&b, "DTEND:"+start.Add(ICalMealDuration).Format(icalTimeFormat))
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(schedule.FoodName))
+		writeICalLine(&b, "DESCRIPTION:"+escapeICalText(description))
+		writeICalLine(&b, "TRANSP:TRANSPARENT")
+		writeICalLine(&b, "END:VEVENT")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func escapeICalText(text string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(text)
+}
+
+// writeICalLine writes a CRLF terminated content line, folding it at 75 octets without splitting UTF-8 characters
+func writeICalLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isUTF8Start(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space which counts towards the limit
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isUTF8Start(c byte) bool {
+	return c&0xC0 != 0x80
+}
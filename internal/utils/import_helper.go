@@ -0,0 +1,367 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mealplanner/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Supported import formats
+const (
+	ImportFormatICS = "ics"
+	ImportFormatCSV = "csv"
+)
+
+// Minimum similarity (0..1) for a fuzzy title match to be accepted
+const FuzzyMatchThreshold = 0.8
+
+var csvDateFormats = []string{"2006-01-02", "2006/01/02", "2006-01-02 15:04", "2006-01-02T15:04:05"}
+var csvTimeFormats = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3PM", "3 PM"}
+var servingsPattern = regexp.MustCompile(`(?i)servings:\s*([0-9]+(?:\.[0-9]+)?)`)
+
+// DetectImportFormat picks the format from the file name, falling back to sniffing the content
+func DetectImportFormat(filename, content string) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".ics"), strings.HasSuffix(lower, ".ical"):
+		return ImportFormatICS
+	case strings.HasSuffix(lower, ".csv"):
+		return ImportFormatCSV
+	case strings.Contains(content, "BEGIN:VCALENDAR"):
+		return ImportFormatICS
+	default:
+		return ImportFormatCSV
+	}
+}
+
+// ParseICalendarImport reads the VEVENTs of an iCalendar file. Floating times are taken in timeZone,
+// all-day events are placed at defaultTime ("15:04").
+func ParseICalendarImport(content string, timeZone *time.Location, defaultTime string) []*models.ImportRow {
+	rows := []*models.ImportRow{}
+	var row *models.ImportRow
+	for _, line := range unfoldICalLines(content) {
+		name, params, value := splitICalProperty(line.text)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			row = &models.ImportRow{Line: line.number, Servings: 1}
+		case name == "END" && value == "VEVENT" && row != nil:
+			if row.Title == "" {
+				row.Error = "Missing title"
+			} else if row.ScheduledAt.IsZero() && row.Error == "" {
+				row.Error = "Missing start time"
+			}
+			rows = append(rows, row)
+			row = nil
+		case row == nil:
+			continue
+		case name == "SUMMARY":
+			row.Title = strings.TrimSpace(unescapeICalText(value))
+		case name == "DESCRIPTION":
+			if match := servingsPattern.FindStringSubmatch(unescapeICalText(value)); match != nil {
+				if servings, err := strconv.ParseFloat(match[1], 64); err == nil && servings > 0 {
+					row.Servings = servings
+				}
+			}
+		case name == "DTSTART":
+			scheduledAt, err := parseICalDateTime(params, value, timeZone, defaultTime)
+			if err != nil {
+				row.Error = fmt.Sprintf("Invalid start time %q", value)
+			} else {
+				row.ScheduledAt = scheduledAt
+			}
+		case name == "RRULE" || name == "RDATE":
+			row.Warning = "Repeating event, only the first date is imported"
+		}
+	}
+	return rows
+}
+
+// icalLine is a property of an iCalendar file with its continuation lines joined, numbered by the line it starts on
+type icalLine struct {
+	number int
+	text   string
+}
+
+// unfoldICalLines joins lines starting with a space or tab onto the line before them
+func unfoldICalLines(content string) []icalLine {
+	var lines []icalLine
+	for i, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if len(lines) > 0 && (strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t")) {
+			lines[len(lines)-1].text += raw[1:]
+			continue
+		}
+		lines = append(lines, icalLine{number: i + 1, text: raw})
+	}
+	return lines
+}
+
+// ParseCSVImport reads a CSV file with a header row. Recognised columns are date, time, food (or title, meal, name)
+// and servings. Rows without a time are placed at defaultTime ("15:04") in timeZone.
+func ParseCSVImport(content string, timeZone *time.Location, defaultTime string) ([]*models.ImportRow, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))] = i
+	}
+
+	dateColumn, ok := columns["date"]
+	if !ok {
+		return nil, fmt.Errorf("CSV needs a date column")
+	}
+	titleColumn := -1
+	for _, name := range []string{"food", "title", "meal", "name"} {
+		if i, ok := columns[name]; ok {
+			titleColumn = i
+			break
+		}
+	}
+	if titleColumn < 0 {
+		return nil, fmt.Errorf("CSV needs a food, title, meal or name column")
+	}
+	timeColumn, hasTime := columns["time"]
+	servingsColumn, hasServings := columns["servings"]
+
+	rows := []*models.ImportRow{}
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			rows = append(rows, &models.ImportRow{Line: line, Error: err.Error()})
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row := &models.ImportRow{
+			Line:     line,
+			Title:    strings.TrimSpace(csvField(record, titleColumn)),
+			Servings: 1,
+		}
+		rows = append(rows, row)
+		if row.Title == "" {
+			row.Error = "Missing food"
+			continue
+		}
+
+		timeValue := defaultTime
+		if hasTime && strings.TrimSpace(csvField(record, timeColumn)) != "" {
+			timeValue = strings.TrimSpace(csvField(record, timeColumn))
+		}
+		scheduledAt, err := parseCSVDateTime(strings.TrimSpace(csvField(record, dateColumn)), timeValue, timeZone)
+		if err != nil {
+			row.Error = err.Error()
+			continue
+		}
+		row.ScheduledAt = scheduledAt
+
+		if hasServings && strings.TrimSpace(csvField(record, servingsColumn)) != "" {
+			servings, err := strconv.ParseFloat(strings.TrimSpace(csvField(record, servingsColumn)), 64)
+			if err != nil || servings <= 0 {
+				row.Error = "Servings must be a positive number"
+				continue
+			}
+			row.Servings = servings
+		}
+	}
+	return rows, nil
+}
+
+// FoodMatcher maps free-text meal titles to foods by exact name, alias or fuzzy name
+type FoodMatcher struct {
+	foods      []*models.Food
+	byName     map[string]*models.Food
+	byAlias    map[string]*models.Food
+	normalized []string
+}
+
+// NewFoodMatcher builds a matcher from foods and aliases (normalised alias -> food id)
+func NewFoodMatcher(foods []*models.Food, aliases map[string]int) *FoodMatcher {
+	m := &FoodMatcher{
+		foods:      foods,
+		byName:     make(map[string]*models.Food, len(foods)),
+		byAlias:    make(map[string]*models.Food, len(aliases)),
+		normalized: make([]string, len(foods)),
+	}
+	byID := make(map[int]*models.Food, len(foods))
+	for i, food := range foods {
+		m.byName[strings.ToLower(strings.TrimSpace(food.Name))] = food
+		m.normalized[i] = NormalizeFoodName(food.Name)
+		byID[food.ID] = food
+	}
+	for alias, foodId := range aliases {
+		if food, ok := byID[foodId]; ok {
+			m.byAlias[alias] = food
+		}
+	}
+	return m
+}
+
+// Match returns the food for title and how it was matched, or nil and "" when nothing is close enough
+func (m *FoodMatcher) Match(title string) (*models.Food, string) {
+	if food, ok := m.byName[strings.ToLower(strings.TrimSpace(title))]; ok {
+		return food, models.ImportMatchExact
+	}
+	normalizedTitle := NormalizeFoodName(title)
+	if food, ok := m.byAlias[normalizedTitle]; ok {
+		return food, models.ImportMatchAlias
+	}
+
+	var best *models.Food
+	bestScore := 0.0
+	for i, food := range m.foods {
+		score := similarity(normalizedTitle, m.normalized[i])
+		if score > bestScore {
+			best, bestScore = food, score
+		}
+	}
+	if best != nil && bestScore >= FuzzyMatchThreshold {
+		return best, models.ImportMatchFuzzy
+	}
+	return nil, ""
+}
+
+// NormalizeFoodName lower-cases a name and collapses punctuation and whitespace, used for aliases and fuzzy matching
+func NormalizeFoodName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// similarity is 1 minus the Levenshtein distance relative to the longer string
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// splitICalProperty splits "NAME;PARAM=x:value" into its name, parameters and value
+func splitICalProperty(line string) (string, map[string]string, string) {
+	line = strings.TrimRight(line, "\r")
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+func parseICalDateTime(params map[string]string, value string, timeZone *time.Location, defaultTime string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		date, err := time.ParseInLocation("20060102", value, timeZone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return parseCSVDateTime(date.Format("2006-01-02"), defaultTime, timeZone)
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.In(timeZone), nil
+	}
+
+	location := timeZone
+	if tzid := params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(timeZone), nil
+}
+
+func parseCSVDateTime(dateValue, timeValue string, timeZone *time.Location) (time.Time, error) {
+	for _, format := range csvDateFormats {
+		date, err := time.ParseInLocation(format, dateValue, timeZone)
+		if err != nil {
+			continue
+		}
+		// the date column already carries a time
+		if strings.Contains(format, "15") {
+			return date, nil
+		}
+		for _, timeFormat := range csvTimeFormats {
+			if t, err := time.Parse(timeFormat, strings.ToUpper(timeValue)); err == nil {
+				return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, timeZone), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("Invalid time %q", timeValue)
+	}
+	return time.Time{}, fmt.Errorf("Invalid date %q, use YYYY-MM-DD", dateValue)
+}
+
+func unescapeICalText(text string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(text)
+}
+
+func csvField(record []string, index int) string {
+	if index < len(record) {
+		return record[index]
+	}
+	return ""
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"mealplanner/internal/models"
+	"time"
+)
+
+// ReportRange widens from and to out to whole weeks or months, returning the start of the first period and
+// the end, exclusive, of the last
+func ReportRange(period string, from, to time.Time, weekStart time.Weekday) (time.Time, time.Time) {
+	if period == models.ReportMonthly {
+		start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+		end := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, to.Location()).AddDate(0, 1, 0)
+		return start, end
+	}
+	start := StartOfWeek(from, weekStart)
+	end := StartOfWeek(to, weekStart).AddDate(0, 0, 7)
+	return start, end
+}
+
+// NextReportPeriod is the start of the week or month after start
+func NextReportPeriod(period string, start time.Time) time.Time {
+	if period == models.ReportMonthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+// PriceUnit is the unit a food's prices are compared in, kilograms and liters rather than grams and milliliters
+func PriceUnit(food *models.Food) string {
+	switch food.UnitType {
+	case "mass":
+		return "kilograms"
+	case "volume":
+		return "liters"
+	}
+	return food.BaseUnit
+}
+
+// BuildSpendingCSV writes a spending report as CSV, a row per period, then per category and per store
+func BuildSpendingCSV(report *models.SpendingReport) (string, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"breakdown", "name", "start", "end", "spent", "budget", "difference"})
+	for _, period := range report.Periods {
+		budget, difference := "", ""
+		if period.Budget > 0 {
+			budget = formatMoney(period.Budget)
+			difference = formatMoney(period.Total - period.Budget)
+		}
+		w.Write([]string{
+			report.Period,
+			period.Label(report.Period),
+			period.Start.Format(time.DateOnly),
+			period.End.AddDate(0, 0, -1).Format(time.DateOnly),
+			formatMoney(period.Total),
+			budget,
+			difference,
+		})
+	}
+	for _, total := range report.Categories {
+		w.Write([]string{"category", total.Name, "", "", formatMoney(total.Total), "", ""})
+	}
+	for _, total := range report.Stores {
+		w.Write([]string{"store", total.Name, "", "", formatMoney(total.Total), "", ""})
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// BuildPriceTrendCSV writes every price paid for a food as CSV, oldest first
+func BuildPriceTrendCSV(trend *models.PriceTrend) (string, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"date", "store", "quantity", "unit", "price", "price per " + trend.PriceUnit})
+	for _, point := range trend.Points {
+		unitPrice := ""
+		if point.UnitPrice > 0 {
+			unitPrice = formatMoney(point.UnitPrice)
+		}
+		w.Write([]string{
+			point.PurchasedAt.Format(time.DateOnly),
+			point.StoreName,
+			FormatQuantity(point.Quantity),
+			point.Unit,
+			formatMoney(point.Price),
+			unitPrice,
+		})
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+func formatMoney(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
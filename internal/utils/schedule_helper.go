@@ -6,13 +6,19 @@ import (
 )
 
 type ModalProps struct {
-	Date       time.Time
-	TimeChosen time.Time
-	FoodChosen models.Food
-	Foods      []*models.Food
-	Servings   float64
-	Errors     map[string]string
-	IsEdit     bool
-	ScheduleID int
-	Schedule   *models.Schedule
+	Date           time.Time
+	TimeChosen     time.Time
+	FoodChosen     models.Food
+	Foods          []*models.Food
+	Servings       float64
+	ServingsManual bool // false when servings are counted from the attendees
+	Errors         map[string]string
+	Warnings       map[string]string // non-blocking, keyed by field like Errors
+	IsEdit         bool
+	ScheduleID     int
+	Schedule       *models.Schedule
+	MealSlots      []*models.MealSlot
+	MealSlotID     int
+	Members        []*models.HouseholdMember
+	AttendeeIDs    []int
 }
@@ -5,6 +5,8 @@ import (
 )
 
 type ShoppingListFormProps struct {
+	ID     int
+	IsEdit bool
 	Name   string
 	Notes  string
 	Errors map[string]string
@@ -14,5 +16,13 @@ type AddItemsModalProps struct {
 	ListID    int
 	Foods     []*models.Food
 	Schedules []*models.Schedule
+	MealSlots []*models.MealSlot
+	Errors    map[string]string
+}
+
+type CloseShoppingListProps struct {
+	List      *models.ShoppingList
+	CarryOver []*models.ShoppingListItem
+	Targets   []*models.ShoppingList // the other active lists to carry over to
 	Errors    map[string]string
 }
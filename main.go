@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -65,16 +66,40 @@ func main() {
 	// foodService := service.NewFoodService(db)
 	scheduleService := service.NewScheduleService(db)
 	foodService := service.NewFoodService(db)
-	shoppingService := service.NewShoppingService(db, scheduleService, foodService)
+	pantryService := service.NewPantryService(db)
+	settingsService := service.NewSettingsService(db)
+	mealSlotService := service.NewMealSlotService(db)
+	memberService := service.NewMemberService(db)
+	planTemplateService := service.NewPlanTemplateService(db, scheduleService)
+	importService := service.NewImportService(db, foodService)
+	planGeneratorService := service.NewPlanGeneratorService(db, scheduleService, mealSlotService)
+	varietyService := service.NewVarietyService(db, settingsService)
+	dietService := service.NewDietService(db, memberService)
+	substitutionService := service.NewSubstitutionService(db, scheduleService, foodService)
+	storeService := service.NewStoreService(db)
+	shoppingService := service.NewShoppingService(db, scheduleService, foodService, pantryService, substitutionService, storeService)
+	reportService := service.NewReportService(db, settingsService, foodService)
+
+	// Archive finished and forgotten shopping lists and clear out empty ones in the background
+	go shoppingService.RunCleanup(ctx, 6*time.Hour)
 
 	// Handlers
 	// foodHandler := handlers.NewFoodHandler(foodService)
 	// scheduleHandler := handlers.NewScheduleHandler(scheduleService)
-	calendarHandler := handlers.NewCalendarHandler(scheduleService)
+	calendarHandler := handlers.NewCalendarHandler(scheduleService, settingsService, mealSlotService, dietService)
 	pageHandler := handlers.NewPageHandler()
-	schedulesHandler := handlers.NewSchedulesHandler(scheduleService, foodService)
-	foodHandler := handlers.NewFoodHandler(foodService)
-	shoppingListHandler := handlers.NewShoppingListHandler(shoppingService, scheduleService, foodService)
+	schedulesHandler := handlers.NewSchedulesHandler(scheduleService, foodService, mealSlotService, varietyService, memberService, dietService)
+	foodHandler := handlers.NewFoodHandler(foodService, memberService, dietService, storeService)
+	shoppingListHandler := handlers.NewShoppingListHandler(shoppingService, scheduleService, foodService, mealSlotService, storeService)
+	pantryHandler := handlers.NewPantryHandler(pantryService)
+	settingsHandler := handlers.NewSettingsHandler(settingsService, mealSlotService, memberService)
+	planTemplateHandler := handlers.NewPlanTemplateHandler(planTemplateService)
+	calendarFeedHandler := handlers.NewCalendarFeedHandler(scheduleService, settingsService)
+	importHandler := handlers.NewImportHandler(importService)
+	plannerHandler := handlers.NewPlannerHandler(planGeneratorService, mealSlotService)
+	substitutionHandler := handlers.NewSubstitutionHandler(substitutionService, foodService, memberService)
+	storeHandler := handlers.NewStoreHandler(storeService)
+	reportHandler := handlers.NewReportHandler(reportService, foodService)
 	calendarGroup := e.Group("/", utils.SetTimeZone())
 	e.HTTPErrorHandler = utils.CustomErrorHandler
 
@@ -89,6 +114,35 @@ func main() {
 	calendarGroup.GET("schedules/modal", schedulesHandler.HandleScheduleModal)
 	calendarGroup.GET("schedules/:id/edit", schedulesHandler.HandleEditScheduleModal)
 	calendarGroup.PUT("schedules/:id/edit", schedulesHandler.HandleEditScheduleModal)
+	calendarGroup.GET("schedules/bulk/modal", schedulesHandler.HandleBulkScheduleModal)
+	calendarGroup.POST("schedules/bulk/move", schedulesHandler.HandleMoveSchedules)
+	calendarGroup.POST("schedules/bulk/copy", schedulesHandler.HandleCopySchedules)
+	calendarGroup.POST("schedules/bulk/shift", schedulesHandler.HandleShiftSchedules)
+	calendarGroup.POST("schedules/bulk/swap", schedulesHandler.HandleSwapDays)
+	calendarGroup.GET("schedules/:id/cook", substitutionHandler.HandleCookView)
+	calendarGroup.PUT("schedules/:id/substitutions", substitutionHandler.HandleApplySubstitutions)
+	// Week Template Routes
+	calendarGroup.GET("templates", planTemplateHandler.HandleTemplatesPage)
+	calendarGroup.POST("templates", planTemplateHandler.HandleCreateTemplate)
+	calendarGroup.GET("templates/:id/preview", planTemplateHandler.HandlePreviewTemplate)
+	calendarGroup.POST("templates/:id/apply", planTemplateHandler.HandleApplyTemplate)
+	calendarGroup.DELETE("templates/:id", planTemplateHandler.HandleDeleteTemplate)
+	// Plan Generator Routes
+	calendarGroup.GET("planner", plannerHandler.HandlePlannerPage)
+	calendarGroup.POST("planner/generate", plannerHandler.HandleGeneratePlan)
+	calendarGroup.POST("planner/apply", plannerHandler.HandleApplyPlan)
+	// Import Routes
+	calendarGroup.GET("import", importHandler.HandleImportPage)
+	calendarGroup.POST("import/preview", importHandler.HandleImportPreview)
+	calendarGroup.POST("import/commit", importHandler.HandleImportCommit)
+	// Report Routes
+	calendarGroup.GET("reports", reportHandler.HandleReportsPage)
+	calendarGroup.GET("reports/spending/export", reportHandler.HandleExportSpending)
+	e.GET("/reports/prices", reportHandler.HandlePriceTrend)
+	e.GET("/reports/prices/:foodId/export", reportHandler.HandleExportPriceTrend)
+	// Attendance Routes
+	calendarGroup.POST("settings/members/:id/away", settingsHandler.HandleMarkMemberAway)
+	calendarGroup.DELETE("settings/members/:id/away/:awayId", settingsHandler.HandleCancelMemberAway)
 
 	// Food Routes
 	e.GET("/foods", foodHandler.HandleFoodsPage)
@@ -107,23 +161,43 @@ func main() {
 	e.GET("/foods/recipe-fields", foodHandler.GetRecipeFields)
 	e.GET("/foods/new-ingredient-row", foodHandler.GetNewIngredientRow)
 	e.GET("/foods/units", foodHandler.GetFoodUnits)
+	e.POST("/foods/:id/favourite", foodHandler.HandleSetFavourite)
+	e.POST("/foods/:id/ratings", foodHandler.HandleRateRecipe)
+	e.DELETE("/foods/:id/ratings/:memberId", foodHandler.HandleDeleteRating)
+	e.POST("/foods/:id/scale", foodHandler.HandleScaleRecipe)
 
 	// Shopping List Routes
 	e.GET("/shopping-lists", shoppingListHandler.HandleShoppingListsPage)
 	e.GET("/shopping-lists/new", shoppingListHandler.HandleCreateShoppingListModal)
 	e.POST("/shopping-lists/new", shoppingListHandler.HandleCreateShoppingListModal)
 	e.GET("/shopping-lists/:id", shoppingListHandler.HandleViewShoppingList)
+	e.GET("/shopping-lists/:id/progress", shoppingListHandler.HandleShoppingListProgress)
+	e.GET("/shopping-lists/:id/drift", shoppingListHandler.HandleShoppingListDrift)
+	e.POST("/shopping-lists/:id/reconcile", shoppingListHandler.HandleReconcileShoppingList)
 	e.DELETE("/shopping-lists/:id", shoppingListHandler.HandleDeleteShoppingList)
+	e.PUT("/shopping-lists/:id/store", shoppingListHandler.HandleSetStore)
+	e.PUT("/shopping-lists/:id/split", shoppingListHandler.HandleSetSplit)
+	e.GET("/shopping-lists/:id/edit", shoppingListHandler.HandleEditShoppingListModal)
+	e.PUT("/shopping-lists/:id/edit", shoppingListHandler.HandleEditShoppingListModal)
+	e.POST("/shopping-lists/:id/duplicate", shoppingListHandler.HandleDuplicateShoppingList)
+	e.PUT("/shopping-lists/:id/archive", shoppingListHandler.HandleArchiveShoppingList)
+	e.GET("/shopping-lists/:id/close", shoppingListHandler.HandleCloseShoppingList)
+	e.POST("/shopping-lists/:id/close", shoppingListHandler.HandleCloseShoppingList)
 
 	// Add items routes
 	e.GET("/shopping-lists/:id/add-items", shoppingListHandler.HandleAddItemsModal)
 	e.POST("/shopping-lists/:id/items/manual", shoppingListHandler.HandleAddManualItem)
+	e.POST("/shopping-lists/:id/items/free-text", shoppingListHandler.HandleAddFreeTextItem)
 	e.POST("/shopping-lists/:id/items/recipe", shoppingListHandler.HandleAddRecipe)
 	e.POST("/shopping-lists/:id/items/schedules", shoppingListHandler.HandleAddSchedules)
 	e.POST("/shopping-lists/:id/items/date-range", shoppingListHandler.HandleAddDateRange)
+	e.POST("/shopping-lists/:id/items/staples", shoppingListHandler.HandleAddStaples)
 
 	// Item management routes
+	e.GET("/shopping-lists/:id/items/:itemId", shoppingListHandler.HandleShoppingListItem)
 	e.PUT("/shopping-lists/:id/items/:itemId", shoppingListHandler.HandleUpdateItem)
+	e.PUT("/shopping-lists/:id/items/:itemId/sources/:sourceId", shoppingListHandler.HandleSetItemContribution)
+	e.DELETE("/shopping-lists/:id/items/:itemId/sources/:sourceId", shoppingListHandler.HandleDeleteItemContribution)
 	e.POST("/shopping-lists/:id/items/:itemId/purchased", shoppingListHandler.HandleMarkItemPurchased)
 	e.DELETE("/shopping-lists/:id/items/:itemId", shoppingListHandler.HandleDeleteItem)
 	e.DELETE("/shopping-lists/:id/sources/:sourceId", shoppingListHandler.HandleDeleteItemsBySource)
@@ -131,6 +205,35 @@ func main() {
 	// Export
 	e.GET("/shopping-lists/:id/export", shoppingListHandler.HandleExportShoppingList)
 
+	// Pantry Routes
+	e.GET("/pantry", pantryHandler.HandlePantryPage)
+	e.PUT("/pantry/:foodId", pantryHandler.HandleUpdateStock)
+	e.DELETE("/pantry/:foodId", pantryHandler.HandleRemoveStaple)
+
+	// Settings Routes
+	calendarGroup.GET("settings", settingsHandler.HandleSettingsPage)
+	e.PUT("/settings/week-start", settingsHandler.HandleUpdateWeekStart)
+	e.PUT("/settings/variety", settingsHandler.HandleUpdateVarietySettings)
+	e.PUT("/settings/budget", settingsHandler.HandleUpdateBudget)
+	e.POST("/settings/meal-slots", settingsHandler.HandleCreateMealSlot)
+	e.PUT("/settings/meal-slots/:id", settingsHandler.HandleUpdateMealSlot)
+	e.POST("/settings/members", settingsHandler.HandleCreateMember)
+	e.PUT("/settings/members/:id", settingsHandler.HandleUpdateMember)
+	e.DELETE("/settings/members/:id", settingsHandler.HandleDeleteMember)
+	e.DELETE("/settings/meal-slots/:id", settingsHandler.HandleDeleteMealSlot)
+	e.POST("/settings/calendar-feed", settingsHandler.HandleRotateCalendarFeed)
+	e.DELETE("/settings/calendar-feed", settingsHandler.HandleRevokeCalendarFeed)
+	e.GET("/settings/substitutions", substitutionHandler.HandleSubstitutionSettings)
+	e.POST("/settings/substitutions", substitutionHandler.HandleCreateSubstitution)
+	e.DELETE("/settings/substitutions/:id", substitutionHandler.HandleDeleteSubstitution)
+	e.GET("/settings/stores", storeHandler.HandleStoreSettings)
+	e.POST("/settings/stores", storeHandler.HandleCreateStore)
+	e.PUT("/settings/stores/:id", storeHandler.HandleUpdateStore)
+	e.DELETE("/settings/stores/:id", storeHandler.HandleDeleteStore)
+
+	// Calendar feed, authenticated by the secret token in the URL
+	e.GET("/feeds/:token/meals.ics", calendarFeedHandler.HandleCalendarFeed)
+
 	// Create sub-FS for static files
 	staticFS, err := fs.Sub(staticFiles, "static")
 	if err != nil {
@@ -0,0 +1,12 @@
+-- Staples are foods we keep stocked (salt, oil, flour...) rather than buy per recipe
+ALTER TABLE foods ADD COLUMN is_staple BOOLEAN NOT NULL DEFAULT false;
+
+-- Current stock and par level (minimum stock) per food, in the food's base unit
+CREATE TABLE pantry_stock (
+    food_id INTEGER PRIMARY KEY REFERENCES foods (id) ON DELETE CASCADE,
+    quantity NUMERIC NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    par_level NUMERIC NOT NULL DEFAULT 0 CHECK (par_level >= 0),
+    updated_at TIMESTAMPTZ DEFAULT NOW ()
+);
+
+-- shopping_list_sources.source_type gains 'staples' for top-up lines of staples below par
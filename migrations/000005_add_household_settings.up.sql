@@ -0,0 +1,8 @@
+-- Single-row table holding household-wide preferences
+CREATE TABLE household_settings (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    week_start INTEGER NOT NULL DEFAULT 1 CHECK (week_start BETWEEN 0 AND 6), -- 0 = Sunday, 1 = Monday
+    updated_at TIMESTAMPTZ DEFAULT NOW ()
+);
+
+INSERT INTO household_settings (id) VALUES (1);
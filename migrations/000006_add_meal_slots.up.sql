@@ -0,0 +1,20 @@
+-- Configurable meal slots (breakfast, lunch, dinner, snack...) with a default time
+CREATE TABLE meal_slots (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    default_time TIME NOT NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW (),
+    updated_at TIMESTAMPTZ DEFAULT NOW ()
+);
+
+INSERT INTO meal_slots (name, default_time, sort_order)
+VALUES
+    ('Breakfast', '08:00', 1),
+    ('Lunch', '12:30', 2),
+    ('Snack', '15:30', 3),
+    ('Dinner', '18:30', 4);
+
+ALTER TABLE schedules ADD COLUMN meal_slot_id INTEGER REFERENCES meal_slots (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_schedules_meal_slot_id ON schedules (meal_slot_id);
@@ -0,0 +1,20 @@
+-- Reusable week plans: schedules stored relative to the start of the template
+CREATE TABLE plan_templates (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    days INTEGER NOT NULL CHECK (days > 0),
+    created_at TIMESTAMPTZ DEFAULT NOW (),
+    updated_at TIMESTAMPTZ DEFAULT NOW ()
+);
+
+CREATE TABLE plan_template_entries (
+    id SERIAL PRIMARY KEY,
+    template_id INTEGER NOT NULL REFERENCES plan_templates (id) ON DELETE CASCADE,
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    day_offset INTEGER NOT NULL CHECK (day_offset >= 0),
+    time_of_day TIME NOT NULL,
+    servings NUMERIC NOT NULL DEFAULT 1 CHECK (servings > 0),
+    meal_slot_id INTEGER REFERENCES meal_slots (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_plan_template_entries_template_id ON plan_template_entries (template_id);
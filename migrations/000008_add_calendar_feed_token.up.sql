@@ -0,0 +1,2 @@
+-- Secret token for the subscribable .ics feed, NULL means the feed is disabled
+ALTER TABLE household_settings ADD COLUMN calendar_feed_token TEXT UNIQUE;
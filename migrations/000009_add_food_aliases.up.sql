@@ -0,0 +1,9 @@
+-- Alternative names used to match imported meal titles to foods, stored normalised (lower case, trimmed)
+CREATE TABLE food_aliases (
+    id SERIAL PRIMARY KEY,
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    alias TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT NOW ()
+);
+
+CREATE INDEX idx_food_aliases_food_id ON food_aliases (food_id);
@@ -0,0 +1,13 @@
+-- Optional recipe details used by the plan generator
+ALTER TABLE recipes
+ADD COLUMN prep_minutes INTEGER CHECK (prep_minutes >= 0),
+ADD COLUMN cost_per_serving NUMERIC CHECK (cost_per_serving >= 0);
+
+-- Free-form labels such as "vegetarian" or "quick", stored lower case
+CREATE TABLE recipe_tags (
+    recipe_id INTEGER NOT NULL REFERENCES recipes (food_id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (recipe_id, tag)
+);
+
+CREATE INDEX idx_recipe_tags_tag ON recipe_tags (tag);
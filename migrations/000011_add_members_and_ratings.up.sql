@@ -0,0 +1,23 @@
+-- People in the household, ratings are recorded per member
+CREATE TABLE household_members (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT NOW (),
+    updated_at TIMESTAMPTZ DEFAULT NOW ()
+);
+
+CREATE TABLE recipe_ratings (
+    recipe_id INTEGER NOT NULL REFERENCES recipes (food_id) ON DELETE CASCADE,
+    member_id INTEGER NOT NULL REFERENCES household_members (id) ON DELETE CASCADE,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW (),
+    updated_at TIMESTAMPTZ DEFAULT NOW (),
+    PRIMARY KEY (recipe_id, member_id)
+);
+
+ALTER TABLE foods
+ADD COLUMN is_favourite BOOLEAN NOT NULL DEFAULT false;
+
+-- Cook history is derived from past schedules per food
+CREATE INDEX idx_schedules_food_id_scheduled_at ON schedules (food_id, scheduled_at);
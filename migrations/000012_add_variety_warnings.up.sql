@@ -0,0 +1,9 @@
+-- The ingredients a recipe is built around, compared when warning about repetitive meals
+ALTER TABLE recipe_ingredients ADD COLUMN is_main BOOLEAN NOT NULL DEFAULT false;
+
+-- Limits checked when scheduling, 0 turns a check off
+ALTER TABLE household_settings
+ADD COLUMN variety_window_days INTEGER NOT NULL DEFAULT 7 CHECK (variety_window_days >= 0),
+ADD COLUMN variety_tags TEXT[] NOT NULL DEFAULT '{}', -- recipes sharing one of these tags count as repeats
+ADD COLUMN max_meals_per_day INTEGER NOT NULL DEFAULT 0 CHECK (max_meals_per_day >= 0),
+ADD COLUMN max_daily_prep_minutes INTEGER NOT NULL DEFAULT 0 CHECK (max_daily_prep_minutes >= 0);
@@ -0,0 +1,17 @@
+-- How much of a serving each member eats, e.g. 0.5 for a child. Guests only attend meals they're added to
+ALTER TABLE household_members
+ADD COLUMN portion_factor NUMERIC NOT NULL DEFAULT 1 CHECK (portion_factor > 0),
+ADD COLUMN is_guest BOOLEAN NOT NULL DEFAULT false;
+
+-- Who eats each meal
+CREATE TABLE schedule_attendees (
+    schedule_id INTEGER NOT NULL REFERENCES schedules (id) ON DELETE CASCADE,
+    member_id INTEGER NOT NULL REFERENCES household_members (id) ON DELETE CASCADE,
+    PRIMARY KEY (schedule_id, member_id)
+);
+
+CREATE INDEX idx_schedule_attendees_member_id ON schedule_attendees (member_id);
+
+-- Servings follow the attendees' portion factors unless they were typed by hand,
+-- existing schedules were all typed by hand
+ALTER TABLE schedules ADD COLUMN servings_manual BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,11 @@
+-- What a food contains, e.g. "nuts" or "meat". Recipes also carry the labels of everything they're made from
+CREATE TABLE food_labels (
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    label TEXT NOT NULL,
+    PRIMARY KEY (food_id, label)
+);
+
+-- Restrictions checked against the meals a member attends
+ALTER TABLE household_members
+ADD COLUMN diet TEXT NOT NULL DEFAULT '', -- empty for no diet
+ADD COLUMN allergens TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,29 @@
+-- A quantity of one food that can be swapped for others, e.g. 1 cup buttermilk for 1 cup milk and
+-- 1 tablespoon lemon juice. Rules without a member are for everyone
+CREATE TABLE substitutions (
+    id SERIAL PRIMARY KEY,
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    unit TEXT NOT NULL,
+    member_id INTEGER REFERENCES household_members (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_substitutions_food_id ON substitutions (food_id);
+
+-- What the food is replaced with, in the order it was entered
+CREATE TABLE substitution_items (
+    id SERIAL PRIMARY KEY,
+    substitution_id INTEGER NOT NULL REFERENCES substitutions (id) ON DELETE CASCADE,
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    unit TEXT NOT NULL
+);
+
+CREATE INDEX idx_substitution_items_substitution_id ON substitution_items (substitution_id);
+
+-- Substitutions applied when cooking and shopping for a meal
+CREATE TABLE schedule_substitutions (
+    schedule_id INTEGER NOT NULL REFERENCES schedules (id) ON DELETE CASCADE,
+    substitution_id INTEGER NOT NULL REFERENCES substitutions (id) ON DELETE CASCADE,
+    PRIMARY KEY (schedule_id, substitution_id)
+);
@@ -0,0 +1,10 @@
+-- Units only one food is measured in, e.g. 1 can = 400 grams. unit is a standard unit of the food's type
+CREATE TABLE food_units (
+    id SERIAL PRIMARY KEY,
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    unit TEXT NOT NULL,
+    is_purchase_unit BOOLEAN NOT NULL DEFAULT FALSE, -- shopping lists also show the food in this unit
+    UNIQUE (food_id, name)
+);
@@ -0,0 +1,13 @@
+-- Sizes a food is sold in, e.g. 250 gram blocks of butter. Shopping lists round up to whole packages
+CREATE TABLE food_packages (
+    id SERIAL PRIMARY KEY,
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    size NUMERIC NOT NULL CHECK (size > 0),
+    unit TEXT NOT NULL,
+    price NUMERIC, -- NULL when unknown
+    UNIQUE (food_id, size, unit)
+);
+
+-- Bought beyond what the list needed, in the item's unit. Recorded when the item is marked purchased
+ALTER TABLE shopping_list_items
+ADD COLUMN surplus_quantity NUMERIC NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+-- The section of a store a food is found in, e.g. produce or dairy. Empty for uncategorised
+ALTER TABLE foods
+ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE stores (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+-- The order a store's sections are walked in, categories it doesn't list come after them
+CREATE TABLE store_aisles (
+    store_id INTEGER NOT NULL REFERENCES stores (id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (store_id, category)
+);
+
+-- The store whose layout orders the list, NULL to group by category in the default category order
+ALTER TABLE shopping_lists
+ADD COLUMN store_id INTEGER REFERENCES stores (id) ON DELETE SET NULL;
@@ -0,0 +1,31 @@
+-- Stores a food is usually bought from, e.g. meat from the butcher
+CREATE TABLE food_stores (
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    store_id INTEGER NOT NULL REFERENCES stores (id) ON DELETE CASCADE,
+    PRIMARY KEY (food_id, store_id)
+);
+
+-- What was paid for a quantity of a food at a store, recorded from a shopping list item's actual price.
+-- The latest observation per store is used to pick the cheapest store
+CREATE TABLE store_prices (
+    id SERIAL PRIMARY KEY,
+    food_id INTEGER NOT NULL REFERENCES foods (id) ON DELETE CASCADE,
+    store_id INTEGER NOT NULL REFERENCES stores (id) ON DELETE CASCADE,
+    price NUMERIC NOT NULL CHECK (price > 0),
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    unit TEXT NOT NULL,
+    observed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- the item the price was recorded from, kept when the list is deleted
+    shopping_list_item_id INTEGER UNIQUE REFERENCES shopping_list_items (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_store_prices_food_store ON store_prices (food_id, store_id, observed_at DESC);
+
+-- The store an item was bought at, set when it's marked purchased
+ALTER TABLE shopping_list_items
+ADD COLUMN store_id INTEGER REFERENCES stores (id) ON DELETE SET NULL;
+
+-- How a list is split into per-store sections: '' not split, 'preferred' by each food's preferred store,
+-- 'cheapest' by the store with the lowest latest price
+ALTER TABLE shopping_lists
+ADD COLUMN split_by TEXT NOT NULL DEFAULT '';
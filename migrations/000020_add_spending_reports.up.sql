@@ -0,0 +1,15 @@
+-- When an item was marked purchased, spending is reported by this date
+ALTER TABLE shopping_list_items
+ADD COLUMN purchased_at TIMESTAMPTZ;
+
+UPDATE shopping_list_items
+SET purchased_at = updated_at
+WHERE purchased;
+
+CREATE INDEX idx_shopping_list_items_purchased_at ON shopping_list_items (purchased_at)
+WHERE purchased_at IS NOT NULL;
+
+-- Grocery budget targets reported against, 0 for no budget
+ALTER TABLE household_settings
+ADD COLUMN weekly_budget NUMERIC NOT NULL DEFAULT 0 CHECK (weekly_budget >= 0),
+ADD COLUMN monthly_budget NUMERIC NOT NULL DEFAULT 0 CHECK (monthly_budget >= 0);
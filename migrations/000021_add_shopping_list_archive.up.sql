@@ -0,0 +1,9 @@
+-- Archived lists are kept for their history and spending but hidden from the active lists
+ALTER TABLE shopping_lists
+ADD COLUMN archived_at TIMESTAMPTZ;
+
+-- Lists archived by the old notes marker
+UPDATE shopping_lists
+SET archived_at = updated_at,
+    notes = NULLIF(TRIM(REPLACE(notes, '[ARCHIVED]', '')), '')
+WHERE notes LIKE '%[ARCHIVED]%';
@@ -0,0 +1,4 @@
+-- Whether a recipe or schedule source was added with its staples, so it can be worked out again the same way
+-- when the meal or recipe changes
+ALTER TABLE shopping_list_sources
+ADD COLUMN include_staples BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,9 @@
+-- Contributions of a schedule or recipe source changed by hand on an item. They replace what the meal or recipe
+-- works out to, so keeping the list in sync doesn't undo them. A quantity of 0 is a removed contribution.
+CREATE TABLE shopping_list_source_overrides (
+    shopping_list_source_id INTEGER NOT NULL REFERENCES shopping_list_sources(id) ON DELETE CASCADE,
+    food_id INTEGER NOT NULL REFERENCES foods(id) ON DELETE CASCADE,
+    unit TEXT NOT NULL,
+    quantity NUMERIC NOT NULL CHECK (quantity >= 0),
+    PRIMARY KEY (shopping_list_source_id, food_id, unit)
+);
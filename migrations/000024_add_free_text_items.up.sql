@@ -0,0 +1,7 @@
+-- Items without a food, like dish soap or birthday candles, have no food_id and keep their own category.
+-- Items with a food take the food's category.
+ALTER TABLE shopping_list_items
+ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_shopping_list_items_free_text ON shopping_list_items (shopping_list_id, LOWER(food_name))
+WHERE food_id IS NULL;
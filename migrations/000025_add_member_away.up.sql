@@ -0,0 +1,11 @@
+-- Periods a member is away, [start_at, end_at). Meals in them leave the member out of the default attendees
+-- and of the servings.
+CREATE TABLE member_away (
+    id SERIAL PRIMARY KEY,
+    member_id INTEGER NOT NULL REFERENCES household_members (id) ON DELETE CASCADE,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL CHECK (end_at > start_at),
+    created_at TIMESTAMPTZ DEFAULT NOW ()
+);
+
+CREATE INDEX idx_member_away_member_id ON member_away (member_id, start_at, end_at);
@@ -0,0 +1,17 @@
+-- Ratings and cook history per food, shared by the food list, its count and the autocomplete so they filter
+-- and sort on the same numbers. Only meals up to now count as cooked.
+CREATE VIEW food_stats AS
+SELECT
+    f.id,
+    f.name,
+    f.unit_type,
+    f.base_unit,
+    f.is_recipe,
+    f.density,
+    f.is_staple,
+    f.is_favourite,
+    CAST((SELECT AVG(rr.rating) FROM recipe_ratings rr WHERE rr.recipe_id = f.id) AS NUMERIC) as average_rating,
+    CAST((SELECT COUNT(*) FROM recipe_ratings rr WHERE rr.recipe_id = f.id) AS INTEGER) as rating_count,
+    CAST((SELECT MAX(s.scheduled_at) FROM schedules s WHERE s.food_id = f.id AND s.scheduled_at <= NOW()) AS TIMESTAMPTZ) as last_cooked_at,
+    CAST((SELECT COUNT(*) FROM schedules s WHERE s.food_id = f.id AND s.scheduled_at <= NOW()) AS INTEGER) as times_cooked
+FROM foods f;
@@ -0,0 +1,6 @@
+-- The meals an away period took the member off, so cancelling the period puts them back on
+CREATE TABLE member_away_schedules (
+    member_away_id INTEGER NOT NULL REFERENCES member_away (id) ON DELETE CASCADE,
+    schedule_id INTEGER NOT NULL REFERENCES schedules (id) ON DELETE CASCADE,
+    PRIMARY KEY (member_away_id, schedule_id)
+);
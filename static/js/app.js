@@ -3,6 +3,7 @@ document.addEventListener("alpine:init", () => {
     activeTab: "calendar",
     showModal: false,
     currentDate: new Date().toLocaleDateString("en-CA"),
+    currentView: "day",
 
     init() {
       const path = window.location.pathname;
@@ -10,6 +11,16 @@ document.addEventListener("alpine:init", () => {
         this.activeTab = "shoppinglists";
       } else if (path.startsWith("/foods")) {
         this.activeTab = "foods";
+      } else if (path.startsWith("/templates")) {
+        this.activeTab = "templates";
+      } else if (path.startsWith("/planner")) {
+        this.activeTab = "planner";
+      } else if (path.startsWith("/pantry")) {
+        this.activeTab = "pantry";
+      } else if (path.startsWith("/reports")) {
+        this.activeTab = "reports";
+      } else if (path.startsWith("/settings") || path.startsWith("/import")) {
+        this.activeTab = "settings";
       } else {
         // default for '/' and '/calendar'
         this.activeTab = "calendar";
@@ -26,12 +37,30 @@ document.addEventListener("alpine:init", () => {
       this.currentDate = date;
     },
 
+    setCurrentView(view) {
+      this.currentView = view;
+    },
+
     showScheduleModal(date) {
       this.showModal = true;
       // Create modal container if it doesn't exist
       this.ensureModalContainer();
 
-      htmx.ajax("GET", `/schedules/modal?date=${date.date}`, {
+      const slotParam = date.mealSlotId ? `&meal_slot_id=${date.mealSlotId}` : "";
+      const foodParam = date.foodId
+        ? `&food_id=${date.foodId}&servings=${date.servings}`
+        : "";
+      htmx.ajax("GET", `/schedules/modal?date=${date.date}${slotParam}${foodParam}`, {
+        target: "#dynamic-modal-container",
+        swap: "innerHTML",
+      });
+    },
+
+    showBulkScheduleModal(date) {
+      this.showModal = true;
+      this.ensureModalContainer();
+
+      htmx.ajax("GET", `/schedules/bulk/modal?date=${date.date}`, {
         target: "#dynamic-modal-container",
         swap: "innerHTML",
       });
@@ -47,6 +76,16 @@ document.addEventListener("alpine:init", () => {
       });
     },
 
+    showCookModal(schedule) {
+      this.showModal = true;
+      this.ensureModalContainer();
+
+      htmx.ajax("GET", `/schedules/${schedule.id}/cook`, {
+        target: "#dynamic-modal-container",
+        swap: "innerHTML",
+      });
+    },
+
     showEditFoodModal(food) {
       this.showModal = true;
       this.ensureModalContainer();
@@ -123,6 +162,36 @@ document.addEventListener("alpine:init", () => {
       });
     },
 
+    showEditShoppingListModal(listId) {
+      this.showModal = true;
+      this.ensureModalContainer();
+
+      htmx.ajax("GET", `/shopping-lists/${listId}/edit`, {
+        target: "#dynamic-modal-container",
+        swap: "innerHTML",
+      });
+    },
+
+    showCloseShoppingListModal(listId) {
+      this.showModal = true;
+      this.ensureModalContainer();
+
+      htmx.ajax("GET", `/shopping-lists/${listId}/close`, {
+        target: "#dynamic-modal-container",
+        swap: "innerHTML",
+      });
+    },
+
+    showShoppingItemModal(listId, itemId) {
+      this.showModal = true;
+      this.ensureModalContainer();
+
+      htmx.ajax("GET", `/shopping-lists/${listId}/items/${itemId}`, {
+        target: "#dynamic-modal-container",
+        swap: "innerHTML",
+      });
+    },
+
     showAddItemsModal() {
       this.showModal = true;
       this.ensureModalContainer();
@@ -185,6 +185,9 @@ document.addEventListener("alpine:init", () => {
           } else if (hiddenInput.name === "food_id") {
             // Manual shopping list item form
             unitsSelect = document.getElementById("manual-units");
+          } else if (hiddenInput.name === "limit_food_id" && hiddenInput.form) {
+            // Scale to an ingredient, offering the ingredient's own units too
+            unitsSelect = hiddenInput.form.querySelector('select[name="limit_unit"]');
           }
 
           if (unitsSelect) {
@@ -6,20 +6,51 @@ document.addEventListener("alpine:init", () => {
       this.refreshCalendar();
     },
 
-    previousDay() {
-      const date = new Date(this.$store.mealPlanner.currentDate);
-      date.setDate(date.getDate() - 1);
-      this.$store.mealPlanner.setCurrentDate(date.toISOString().split('T')[0]);
+    changeView(view) {
+      this.$store.mealPlanner.setCurrentView(view);
       this.refreshCalendar();
     },
 
-    nextDay() {
-      const date = new Date(this.$store.mealPlanner.currentDate);
-      date.setDate(date.getDate() + 1);
-      this.$store.mealPlanner.setCurrentDate(date.toISOString().split('T')[0]);
+    selectDay(date) {
+      this.$store.mealPlanner.setCurrentDate(date);
+      this.changeView("day");
+    },
+
+    // Step back or forward by the length of the current view
+    shiftDate(direction) {
+      const [year, month, day] = this.$store.mealPlanner.currentDate.split("-").map(Number);
+      const date = new Date(year, month - 1, day);
+      switch (this.$store.mealPlanner.currentView) {
+        case "week":
+          date.setDate(date.getDate() + 7 * direction);
+          break;
+        case "month":
+          date.setDate(1);
+          date.setMonth(date.getMonth() + direction);
+          break;
+        default:
+          date.setDate(date.getDate() + direction);
+      }
+      this.$store.mealPlanner.setCurrentDate(date.toLocaleDateString("en-CA"));
       this.refreshCalendar();
     },
 
+    previous() {
+      this.shiftDate(-1);
+    },
+
+    next() {
+      this.shiftDate(1);
+    },
+
+    previousDay() {
+      this.previous();
+    },
+
+    nextDay() {
+      this.next();
+    },
+
     goToToday() {
       this.$store.mealPlanner.setCurrentDate(this.$store.mealPlanner.getToday());
       this.refreshCalendar();
@@ -27,7 +58,8 @@ document.addEventListener("alpine:init", () => {
 
     refreshCalendar() {
       htmx.trigger("body", "refreshCalendar", {
-        date: this.$store.mealPlanner.currentDate
+        date: this.$store.mealPlanner.currentDate,
+        view: this.$store.mealPlanner.currentView
       });
     },
 
@@ -42,4 +74,4 @@ document.addEventListener("alpine:init", () => {
       });
     },
   }));
-});
\ No newline at end of file
+});
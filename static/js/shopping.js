@@ -25,7 +25,7 @@ document.addEventListener("alpine:init", () => {
       }
     },
 
-    toggleItemPurchased(itemId, purchased) {
+    toggleItemPurchased(itemId, purchased, storeId) {
       // Get current list ID from URL
       const pathParts = window.location.pathname.split('/');
       const listId = pathParts[pathParts.length - 1];
@@ -47,7 +47,8 @@ document.addEventListener("alpine:init", () => {
         values: {
           purchased: purchased,
           actual_quantity: actualQuantity || "0",
-          actual_price: actualPrice || "0"
+          actual_price: actualPrice || "0",
+          store_id: storeId || "0"
         },
         handler: (_, xhr) => {
           if (xhr.xhr.status >= 200 && xhr.xhr.status < 300) {